@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/rowjay/url-shortening-service/internal/bulk"
+	"github.com/rowjay/url-shortening-service/internal/clicks"
+	"github.com/rowjay/url-shortening-service/internal/config"
+	"github.com/rowjay/url-shortening-service/internal/counter"
+	"github.com/rowjay/url-shortening-service/internal/dto"
+	"github.com/rowjay/url-shortening-service/internal/services"
+	"github.com/rs/zerolog/log"
+)
+
+const importUsage = "usage: import [-format csv|jsonl] [-dry-run] [-on-conflict skip|overwrite|fail] [-actor name] FILE|-"
+
+// runImport implements the "import" subcommand. It reads links from FILE, or
+// from standard input for "-", and writes one JSON result per row to
+// standard output.
+func runImport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	formatName := flags.String("format", "", "csv or jsonl; defaults to the file extension")
+	dryRun := flags.Bool("dry-run", false, "validate and report every row without writing")
+	onConflict := flags.String("on-conflict", string(services.ConflictSkip), "what to do with taken custom codes: skip, overwrite or fail")
+	actor := flags.String("actor", os.Getenv("USER"), "who to record in destination history")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errors.New(importUsage)
+	}
+	path := flags.Arg(0)
+
+	var format bulk.Format
+	var err error
+	switch {
+	case *formatName != "":
+		format, err = bulk.ParseFormat(*formatName)
+	case path == "-":
+		err = errors.New("-format is required when reading standard input")
+	default:
+		format, err = bulk.FormatForFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	policy, err := services.ParseConflictPolicy(*onConflict)
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+	rows, err := bulk.NewReader(input, format)
+	if err != nil {
+		return err
+	}
+
+	store, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	// Imports never resolve links, so the click pipeline is not started.
+	// Rows without a custom code are rare enough that they generate their
+	// codes directly instead of reserving a pool of them.
+	accessCounter := counter.NewAggregator(store.urls, cfg.AccessCountFlushInterval)
+	clickQueue := clicks.NewQueue(accessCounter, clicks.Options{})
+	urlService := services.NewURLService(store.urls, store.history, clickQueue, accessCounter, serviceOptions(cfg, store))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *actor == "" {
+		*actor = "cli"
+	}
+	ctx = services.WithActor(ctx, *actor)
+
+	encoder := json.NewEncoder(os.Stdout)
+	opts := services.ImportOptions{DryRun: *dryRun, OnConflict: policy}
+	summary, err := urlService.ImportURLs(ctx, rows, opts, func(result *dto.ImportResult) error {
+		return encoder.Encode(result)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Int("rows", summary.Rows).
+		Int("created", summary.Created).
+		Int("updated", summary.Updated).
+		Int("skipped", summary.Skipped).
+		Int("invalid", summary.Invalid).
+		Int("conflicts", summary.Conflicts).
+		Int("failed", summary.Failed).
+		Bool("dry_run", summary.DryRun).
+		Msg("Import finished")
+	if summary.Aborted {
+		return errors.New("import stopped at the first conflict")
+	}
+	return nil
+}
@@ -1,19 +1,34 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/rowjay/url-shortening-service/internal/cache/redis"
+	"github.com/rowjay/url-shortening-service/internal/clicks"
+	"github.com/rowjay/url-shortening-service/internal/codegen"
 	"github.com/rowjay/url-shortening-service/internal/config"
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rowjay/url-shortening-service/internal/counter"
 	"github.com/rowjay/url-shortening-service/internal/database"
 	"github.com/rowjay/url-shortening-service/internal/dto"
+	"github.com/rowjay/url-shortening-service/internal/expiry"
 	"github.com/rowjay/url-shortening-service/internal/handlers"
+	"github.com/rowjay/url-shortening-service/internal/keypool"
 	"github.com/rowjay/url-shortening-service/internal/middleware"
 	"github.com/rowjay/url-shortening-service/internal/repository"
 	"github.com/rowjay/url-shortening-service/internal/services"
+	"github.com/rowjay/url-shortening-service/internal/trash"
 )
 
 func main() {
@@ -28,38 +43,384 @@ func main() {
 	}
 
 	cfg := config.Load()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Fatal().Err(err).Msg("Migration failed")
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(cfg, os.Args[2:]); err != nil {
+			log.Fatal().Err(err).Msg("Import failed")
+		}
+		return
+	}
 	
-	log.Info().Str("pocketbase_url", cfg.BaseURL).Msg("Starting URL shortening service")
+	log.Info().Str("storage_driver", cfg.StorageDriver).Msg("Starting URL shortening service")
 
-	pb, err := database.Initialize(cfg.BaseURL)
+	store, err := openStorage(cfg)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to initialize PocketBase")
+		log.Fatal().Err(err).Msg("Failed to initialize storage")
 	}
+	urlRepo := store.urls
+
+	var redisRepo *repository.RedisURLRepository
+	if cfg.RedisURL != "" {
+		redisOpts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid redis_url")
+		}
+
+		redisClient := redis.NewClient(redisOpts)
+		defer redisClient.Close()
 
-	if err := pb.CreateCollection(); err != nil {
-		log.Warn().Err(err).Msg("Collection might already exist or there was an issue creating it")
+		pingCtx, cancel := context.WithTimeout(context.Background(), constants.RequestTimeout)
+		if err := redisClient.Ping(pingCtx); err != nil {
+			log.Warn().Err(err).Str("addr", redisOpts.Addr).Msg("Redis is not reachable yet, requests will fall back to storage")
+		}
+		cancel()
+
+		redisRepo = repository.NewRedisURLRepository(urlRepo, redisClient, repository.RedisCacheOptions{
+			KeyPrefix:     cfg.RedisKeyPrefix,
+			TTL:           cfg.RedisTTL,
+			NegativeTTL:   cfg.RedisNegativeTTL,
+			FlushInterval: cfg.RedisFlushInterval,
+		})
+		redisRepo.Start()
+		urlRepo = redisRepo
+		log.Info().Str("addr", redisOpts.Addr).Dur("ttl", cfg.RedisTTL).Msg("Redis shared cache enabled")
+	}
+
+	if cfg.CacheEnabled {
+		cachedRepo := repository.NewCachedURLRepository(urlRepo, repository.CacheOptions{
+			Size:        cfg.CacheSize,
+			TTL:         cfg.CacheTTL,
+			NegativeTTL: cfg.CacheNegativeTTL,
+		})
+		expvar.Publish("url_cache", expvar.Func(func() any { return cachedRepo.Stats() }))
+		urlRepo = cachedRepo
+		log.Info().Int("size", cfg.CacheSize).Dur("ttl", cfg.CacheTTL).Msg("Short URL lookup cache enabled")
+		if redisRepo != nil {
+			log.Warn().Dur("cache_ttl", cfg.CacheTTL).Msg("Writes on other replicas reach this replica's lookup cache only when entries expire; keep cache_ttl short")
+		}
 	}
 
-	urlRepo := repository.NewURLRepository(pb)
-	urlService := services.NewURLService(urlRepo)
+	accessCounter := counter.NewAggregator(urlRepo, cfg.AccessCountFlushInterval)
+	accessCounter.Start()
+
+	clickQueue := clicks.NewQueue(accessCounter, clicks.Options{
+		QueueSize: cfg.ClickQueueSize,
+		Workers:   cfg.ClickWorkers,
+		Overflow:  clicks.OverflowPolicy(cfg.ClickOverflowPolicy),
+	})
+	clickQueue.Start()
+	expvar.Publish("click_queue", expvar.Func(func() any { return clickQueue.Stats() }))
+
+	var sweeper *expiry.Sweeper
+	if cfg.ExpirySweepEnabled {
+		sweepOpts := expiry.Options{Interval: cfg.ExpirySweepInterval, Retention: cfg.ExpiryRetention}
+		if cfg.ExpiryArchivePath != "" {
+			sweepOpts.Archiver = expiry.NewFileArchiver(cfg.ExpiryArchivePath)
+		}
+		sweeper = expiry.NewSweeper(urlRepo, sweepOpts)
+		sweeper.Start()
+		log.Info().Dur("interval", cfg.ExpirySweepInterval).Dur("retention", cfg.ExpiryRetention).Str("archive", cfg.ExpiryArchivePath).Msg("Expired link sweeper enabled")
+	}
+
+	janitor := trash.NewJanitor(urlRepo, trash.Options{Interval: cfg.TrashPurgeInterval, Retention: cfg.TrashRetention})
+	janitor.Start()
+	log.Info().Dur("interval", cfg.TrashPurgeInterval).Dur("retention", cfg.TrashRetention).Msg("Trash janitor enabled")
+
+	if cfg.UnlockTokenSecret == "" {
+		log.Warn().Msg("No unlock_token_secret configured; password unlock cookies are only valid on this instance until it restarts")
+	}
+
+	opts := serviceOptions(cfg, store)
+	var keyPool *keypool.Pool
+	if cfg.KeyPoolEnabled {
+		keyPool = keypool.New(store.keys, opts.CodeGenerator, keypool.Options{Size: cfg.KeyPoolSize, LowWater: cfg.KeyPoolLowWater})
+		keyPool.Start()
+		opts.Keys = keyPool
+		expvar.Publish("key_pool", expvar.Func(func() any { return keyPool.Stats() }))
+		log.Info().Int("size", cfg.KeyPoolSize).Int("low_water", cfg.KeyPoolLowWater).Msg("Short code key pool enabled")
+	}
+
+	urlService := services.NewURLService(urlRepo, store.history, clickQueue, accessCounter, opts)
 	urlHandler := handlers.NewURLHandler(urlService)
+	redirectHandler := handlers.NewRedirectHandler(urlService, cfg.RedirectStatusCode)
 
 	r := gin.Default()
+	// Client IPs key the unlock attempt limit, so X-Forwarded-For is only
+	// believed when it comes from a configured proxy.
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal().Err(err).Msg("Invalid trusted_proxies")
+	}
 	r.Use(middleware.Logger())
 	r.Use(middleware.CORS())
 
 	r.POST("/api/v1/shorten", urlHandler.CreateShortURL)
+	r.GET("/api/v1/shorten", urlHandler.ListURLs)
+	r.POST("/api/v1/shorten/batch", urlHandler.BatchCreateURLs)
+	r.DELETE("/api/v1/shorten/batch", urlHandler.BatchDeleteURLs)
 	r.GET("/api/v1/shorten/:shortCode", urlHandler.GetOriginalURL)
 	r.PUT("/api/v1/shorten/:shortCode", urlHandler.UpdateShortURL)
 	r.DELETE("/api/v1/shorten/:shortCode", urlHandler.DeleteShortURL)
 	r.GET("/api/v1/shorten/:shortCode/stats", urlHandler.GetStatistics)
+	r.GET("/api/v1/shorten/:shortCode/history", urlHandler.GetHistory)
+	r.POST("/api/v1/shorten/:shortCode/rollback/:version", urlHandler.RollbackURL)
+	r.POST("/api/v1/shorten/:shortCode/unlock", urlHandler.UnlockURL)
+	r.POST("/api/v1/shorten/:shortCode/disable", urlHandler.DisableURL)
+	r.POST("/api/v1/shorten/:shortCode/enable", urlHandler.EnableURL)
+	r.POST("/api/v1/shorten/:shortCode/restore", urlHandler.RestoreURL)
+	r.GET("/api/v1/trash", urlHandler.ListTrash)
+	r.POST("/api/v1/import", urlHandler.ImportURLs)
+	r.GET("/api/v1/export", urlHandler.ExportURLs)
 
 	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, dto.HealthResponse{Status: "ok"})
+		c.JSON(200, healthResponse(cfg.StorageDriver, store.breaker))
 	})
 
-	log.Info().Str("port", cfg.Port).Msg("Server starting")
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatal().Err(err).Msg("Failed to start server")
+	r.GET("/:shortCode", redirectHandler.Redirect)
+	r.POST("/:shortCode", redirectHandler.Unlock)
+
+	srv := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: r,
+	}
+
+	// Runtime metrics are served on their own listener, loopback-only by
+	// default, since the public one has no admin authentication.
+	var debugSrv *http.Server
+	if cfg.DebugAddr != "" {
+		debugMux := http.NewServeMux()
+		debugMux.Handle("/debug/vars", expvar.Handler())
+		debugSrv = &http.Server{Addr: cfg.DebugAddr, Handler: debugMux}
+		go func() {
+			log.Info().Str("addr", cfg.DebugAddr).Msg("Debug server starting")
+			if err := debugSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error().Err(err).Msg("Failed to start debug server")
+			}
+		}()
+	}
+
+	go func() {
+		log.Info().Str("port", cfg.Port).Msg("Server starting")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Err(err).Msg("Failed to start server")
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Info().Msg("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Server forced to shut down")
+	}
+	if debugSrv != nil {
+		if err := debugSrv.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Debug server forced to shut down")
+		}
+	}
+
+	if keyPool != nil {
+		if err := keyPool.Stop(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Failed to stop short code key pool")
+		}
+	}
+
+	if sweeper != nil {
+		if err := sweeper.Stop(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Failed to stop expired link sweeper")
+		}
+	}
+
+	if err := janitor.Stop(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to stop trash janitor")
+	}
+
+	if err := clickQueue.Stop(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to drain click queue on shutdown")
+	}
+
+	if err := accessCounter.Stop(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush access counts on shutdown")
+	}
+
+	if redisRepo != nil {
+		if err := redisRepo.Stop(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Failed to flush Redis access counters on shutdown")
+		}
+	}
+
+	log.Info().Msg("Server stopped")
+}
+
+// healthResponse reports "degraded" while the PocketBase circuit breaker is
+// not closed. The status code stays 200 so orchestrators do not restart the
+// service for a storage outage it is already handling.
+func healthResponse(driver string, breaker *database.CircuitBreaker) dto.HealthResponse {
+	if breaker == nil {
+		return dto.HealthResponse{Status: "ok"}
+	}
+
+	status := breaker.Status()
+	resp := dto.HealthResponse{
+		Status: "ok",
+		Storage: &dto.StorageHealth{
+			Driver:              driver,
+			CircuitBreaker:      string(status.State),
+			ConsecutiveFailures: status.ConsecutiveFailures,
+			OpenedAt:            status.OpenedAt,
+		},
+	}
+	if status.State != database.BreakerClosed {
+		resp.Status = "degraded"
+	}
+	return resp
+}
+
+func serviceOptions(cfg *config.Config, store *storage) services.Options {
+	return services.Options{
+		UnlockTokenSecret:   []byte(cfg.UnlockTokenSecret),
+		UnlockTokenTTL:      cfg.UnlockTokenTTL,
+		UnlockMaxAttempts:   cfg.UnlockMaxAttempts,
+		UnlockAttemptWindow: cfg.UnlockAttemptWindow,
+		HideNotYetActive:    cfg.NotYetActive == constants.NotYetActiveNotFound,
+		BatchMaxSize:        cfg.BatchMaxSize,
+		BatchConcurrency:    cfg.BatchConcurrency,
+		CodeGenerator:       codeGenerator(cfg, store.sequence),
+	}
+}
+
+// codeGenerator builds the generator of the configured short_code_strategy.
+// The counter-based strategies count in sequence, so every process sharing
+// the storage continues where the others stopped.
+func codeGenerator(cfg *config.Config, sequence repository.SequenceRepository) services.CodeGenerator {
+	switch codegen.Strategy(cfg.ShortCodeStrategy) {
+	case codegen.StrategyCounter:
+		return codegen.NewCounter(codegen.NewBlockSequence(sequence, constants.SequenceBlockSize), cfg.ShortCodeLength)
+	case codegen.StrategyHashids:
+		if cfg.ShortCodeSalt == "" {
+			log.Warn().Msg("short_code_salt is empty; hashids codes are obfuscated but not secret")
+		}
+		return codegen.NewHashids(codegen.NewBlockSequence(sequence, constants.SequenceBlockSize), cfg.ShortCodeSalt, cfg.ShortCodeLength)
+	case codegen.StrategyULID:
+		return codegen.NewULID(cfg.ShortCodeLength)
+	}
+	return codegen.NewRandom(cfg.ShortCodeLength)
+}
+
+// storage holds the repositories of the configured backend. breaker is only
+// set for PocketBase, the one backend reached over HTTP.
+type storage struct {
+	urls     repository.URLRepository
+	history  repository.HistoryRepository
+	keys     repository.KeyRepository
+	sequence repository.SequenceRepository
+	breaker  *database.CircuitBreaker
+}
+
+// openStorage opens the configured storage backend.
+func openStorage(cfg *config.Config) (*storage, error) {
+	switch cfg.StorageDriver {
+	case constants.StorageDriverMemory:
+		log.Warn().Msg("Using in-memory storage; data will be lost on restart")
+		urls := repository.NewMemoryURLRepository()
+		return &storage{
+			urls:     urls,
+			history:  repository.NewMemoryHistoryRepository(),
+			keys:     repository.NewMemoryKeyRepository(urls),
+			sequence: repository.NewMemorySequenceRepository(),
+		}, nil
+	case constants.StorageDriverSQLite:
+		db, err := database.OpenSQL(cfg.StorageDriver, cfg.SQLDataSource(), cfg.AutoMigrate)
+		if err != nil {
+			return nil, err
+		}
+		return &storage{
+			urls:     repository.NewSQLiteURLRepository(db),
+			history:  repository.NewSQLiteHistoryRepository(db),
+			keys:     repository.NewSQLiteKeyRepository(db),
+			sequence: repository.NewSQLiteSequenceRepository(db),
+		}, nil
+	case constants.StorageDriverPostgres:
+		db, err := database.OpenSQL(cfg.StorageDriver, cfg.SQLDataSource(), cfg.AutoMigrate)
+		if err != nil {
+			return nil, err
+		}
+		return &storage{
+			urls:     repository.NewPostgresURLRepository(db),
+			history:  repository.NewPostgresHistoryRepository(db),
+			keys:     repository.NewPostgresKeyRepository(db),
+			sequence: repository.NewPostgresSequenceRepository(db),
+		}, nil
+	case constants.StorageDriverPocketBase:
+		log.Info().Str("pocketbase_url", cfg.BaseURL).Msg("Using PocketBase storage")
+
+		pb, err := database.Initialize(cfg.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+
+		pb.UseResilience(database.ResilienceOptions{
+			MaxRetries:       cfg.MaxRetries,
+			BaseDelay:        cfg.RetryBaseDelay,
+			MaxDelay:         cfg.RetryMaxDelay,
+			FailureThreshold: cfg.BreakerThreshold,
+			OpenTimeout:      cfg.BreakerOpenTimeout,
+		})
+		expvar.Publish("pocketbase_breaker", expvar.Func(func() any { return pb.Breaker().Status() }))
+
+		ctx, cancel := context.WithTimeout(context.Background(), constants.RequestTimeout)
+		defer cancel()
+
+		superuser := database.Credentials{Identity: cfg.PBSuperuserEmail, Password: cfg.PBSuperuserPassword}
+
+		// Repository calls sign in as the service account, or as the
+		// superuser when no separate account is configured.
+		account := database.Credentials{Collection: cfg.PBAuthCollection, Identity: cfg.PBAuthIdentity, Password: cfg.PBAuthPassword}
+		if account.IsZero() {
+			account = superuser
+		}
+		if !account.IsZero() {
+			pb.UseAuth(account)
+			if err := pb.Authenticate(ctx); err != nil {
+				log.Warn().Err(err).Msg("PocketBase sign-in failed, will retry on the next request")
+			} else {
+				log.Info().Stringer("account", account).Msg("Authenticated with PocketBase")
+			}
+		} else {
+			log.Warn().Msg("No PocketBase credentials configured; requests are sent anonymously")
+		}
+
+		if err := pb.CreateCollection(ctx, superuser); err != nil {
+			log.Warn().Err(err).Msg("Failed to provision the short_urls collection")
+		}
+		if err := pb.CreateHistoryCollection(ctx, superuser); err != nil {
+			log.Warn().Err(err).Msg("Failed to provision the short_url_history collection")
+		}
+		if err := pb.CreateKeysCollection(ctx, superuser); err != nil {
+			log.Warn().Err(err).Msg("Failed to provision the short_code_keys collection")
+		}
+		if err := pb.CreateSequenceCollection(ctx, superuser); err != nil {
+			log.Warn().Err(err).Msg("Failed to provision the short_code_blocks collection")
+		}
+
+		return &storage{
+			urls:     repository.NewURLRepository(pb),
+			history:  repository.NewHistoryRepository(pb),
+			keys:     repository.NewKeyRepository(pb),
+			sequence: repository.NewSequenceRepository(pb),
+			breaker:  pb.Breaker(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
 	}
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/rowjay/url-shortening-service/internal/config"
+	"github.com/rowjay/url-shortening-service/internal/database"
+	"github.com/rs/zerolog/log"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status"
+
+// runMigrate implements the "migrate" subcommand for the SQL storage drivers.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := database.OpenSQL(cfg.StorageDriver, cfg.SQLDataSource(), false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db, cfg.StorageDriver)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info().Int("applied", applied).Msg("Migrations applied")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Info().Int("reverted", reverted).Msg("Migrations reverted")
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package bulk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/dto"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+)
+
+// readAll returns the rows of r and the lines of rows that failed to parse.
+func readAll(t *testing.T, r Reader) ([]*dto.ImportRow, []int) {
+	t.Helper()
+	var rows []*dto.ImportRow
+	var malformed []int
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			return rows, malformed
+		}
+		var serviceErr *serviceErrors.ServiceError
+		switch {
+		case err == nil:
+			rows = append(rows, row)
+		case row != nil && errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeValidation:
+			malformed = append(malformed, row.Line)
+		default:
+			t.Fatalf("Next() error = %v", err)
+		}
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "\ufeffurl,extra,customCode,metadata\n" +
+		"https://example.com/a,x,docs,\"{\"\"team\"\":\"\"growth\"\"}\"\n" +
+		"https://example.com/b,,,\n" +
+		"https://example.com/c,,bad,not json\n" +
+		"\"https://example.com/\nmultiline\",,,\n" +
+		"https://example.com/d,,short\n"
+
+	r, err := NewReader(strings.NewReader(input), CSV)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	rows, malformed := readAll(t, r)
+
+	if len(rows) != 4 {
+		t.Fatalf("read %d rows, want 4: %+v", len(rows), rows)
+	}
+	if rows[0].URL != "https://example.com/a" || rows[0].CustomCode != "docs" || !maps.Equal(rows[0].Metadata, map[string]string{"team": "growth"}) || rows[0].Line != 2 {
+		t.Errorf("row 1 = %+v", rows[0])
+	}
+	if rows[1].CustomCode != "" || rows[1].Metadata != nil {
+		t.Errorf("row 2 = %+v, want no code or metadata", rows[1])
+	}
+	if rows[3].Line != 7 || rows[3].CustomCode != "short" {
+		t.Errorf("row 4 = %+v, want line 7 after the multi-line field", rows[3])
+	}
+	if len(malformed) != 1 || malformed[0] != 4 {
+		t.Errorf("malformed lines = %v, want [4]", malformed)
+	}
+}
+
+func TestCSVReaderRequiresURLColumn(t *testing.T) {
+	for _, input := range []string{"", "customCode,metadata\ndocs,\n"} {
+		if _, err := NewReader(strings.NewReader(input), CSV); err == nil {
+			t.Errorf("NewReader(%q) error = nil, want an error", input)
+		}
+	}
+}
+
+func TestJSONLReader(t *testing.T) {
+	input := `{"url":"https://example.com/a","customCode":"docs","metadata":{"team":"growth"}}
+
+{"url":"https://example.com/b","shortCode":"exported","accessCount":3}
+{"url":
+{"url":"https://example.com/c","metadata":{"count":1}}
+`
+	r, err := NewReader(strings.NewReader(input), JSONL)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	rows, malformed := readAll(t, r)
+
+	if len(rows) != 2 {
+		t.Fatalf("read %d rows, want 2: %+v", len(rows), rows)
+	}
+	if rows[0].CustomCode != "docs" || !maps.Equal(rows[0].Metadata, map[string]string{"team": "growth"}) {
+		t.Errorf("row 1 = %+v", rows[0])
+	}
+	if rows[1].CustomCode != "exported" || rows[1].Line != 3 {
+		t.Errorf("row 2 = %+v, want shortCode accepted on line 3", rows[1])
+	}
+	if len(malformed) != 2 || malformed[0] != 4 || malformed[1] != 5 {
+		t.Errorf("malformed lines = %v, want [4 5]", malformed)
+	}
+}
+
+func TestExportCanBeImported(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	links := []*dto.GetStatsResponse{
+		{ShortCode: "docs", URL: "https://example.com/a", AccessCount: 7, CreatedAt: created, UpdatedAt: created, Enabled: true, Metadata: map[string]string{"team": "growth"}},
+		{ShortCode: "plain", URL: "https://example.com/b,with,commas", CreatedAt: created, UpdatedAt: created},
+	}
+
+	for _, format := range []Format{CSV, JSONL} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, format)
+		if err != nil {
+			t.Fatalf("NewWriter(%s) error = %v", format, err)
+		}
+		for _, link := range links {
+			if err := w.Write(link); err != nil {
+				t.Fatalf("Write(%s) error = %v", format, err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("Flush(%s) error = %v", format, err)
+		}
+
+		r, err := NewReader(&buf, format)
+		if err != nil {
+			t.Fatalf("NewReader(%s) error = %v", format, err)
+		}
+		rows, malformed := readAll(t, r)
+		if len(rows) != len(links) || len(malformed) != 0 {
+			t.Fatalf("%s round trip read %d rows and %d malformed, want %d rows", format, len(rows), len(malformed), len(links))
+		}
+		for i, row := range rows {
+			if row.URL != links[i].URL || row.CustomCode != links[i].ShortCode || !maps.Equal(row.Metadata, links[i].Metadata) {
+				t.Errorf("%s round trip row %d = %+v, want %+v", format, i+1, row, links[i])
+			}
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for value, want := range map[string]Format{
+		"csv":                              CSV,
+		"text/csv; charset=utf-8":          CSV,
+		"JSONL":                            JSONL,
+		"application/x-ndjson":             JSONL,
+		"application/jsonl; charset=utf-8": JSONL,
+	} {
+		if got, err := ParseFormat(value); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("application/json"); err == nil {
+		t.Error("ParseFormat(application/json) error = nil, want unsupported")
+	}
+	if got, err := FormatForFile("links.ndjson"); err != nil || got != JSONL {
+		t.Errorf("FormatForFile(links.ndjson) = %q, %v, want jsonl", got, err)
+	}
+}
@@ -0,0 +1,46 @@
+// Package bulk reads and writes links as CSV or JSON Lines for bulk import
+// and export.
+package bulk
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	CSV   Format = "csv"
+	JSONL Format = "jsonl"
+)
+
+// ParseFormat accepts a format name, such as "csv" or "jsonl", or a media
+// type, such as "text/csv" or "application/x-ndjson".
+func ParseFormat(value string) (Format, error) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	if mediaType, _, err := mime.ParseMediaType(name); err == nil {
+		name = mediaType
+	}
+	switch name {
+	case "csv", "text/csv":
+		return CSV, nil
+	case "jsonl", "ndjson", "application/jsonl", "application/x-jsonlines", "application/x-ndjson":
+		return JSONL, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, want csv or jsonl", value)
+}
+
+// FormatForFile picks the format from the extension of path.
+func FormatForFile(path string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// ContentType is the media type of f.
+func (f Format) ContentType() string {
+	if f == CSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
@@ -0,0 +1,161 @@
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rowjay/url-shortening-service/internal/dto"
+	"github.com/rowjay/url-shortening-service/internal/errors"
+)
+
+// MaxLineSize caps a JSON Lines row, so a file without newlines cannot
+// exhaust memory.
+const MaxLineSize = 1 << 20
+
+// Reader streams import rows. Next returns io.EOF after the last row. A
+// malformed row is returned with whatever could be read of it and a
+// validation error, and reading can continue with the next one. An error
+// without a row ends the import.
+type Reader interface {
+	Next() (*dto.ImportRow, error)
+}
+
+// NewReader reads rows in format from r. CSV input needs a header row with a
+// url column and optional customCode and metadata columns, where metadata
+// is a JSON object. JSON Lines rows are objects with the same keys. Both
+// accept shortCode in place of customCode, so exports can be imported back.
+// Other columns and keys are ignored.
+func NewReader(r io.Reader, format Format) (Reader, error) {
+	switch format {
+	case CSV:
+		return newCSVReader(r)
+	case JSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64<<10), MaxLineSize)
+		return &jsonlReader{scanner: scanner}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type csvReader struct {
+	csv                       *csv.Reader
+	url, customCode, metadata int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.NewValidationError("bulk.NewReader", "CSV input is empty", nil)
+	}
+	if err != nil {
+		return nil, errors.NewValidationError("bulk.NewReader", "failed to read CSV header", err)
+	}
+
+	reader := &csvReader{csv: cr, url: -1, customCode: -1, metadata: -1}
+	for i, column := range header {
+		// Spreadsheets often prefix UTF-8 files with a byte order mark.
+		switch strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")) {
+		case "url":
+			reader.url = i
+		case "customCode":
+			reader.customCode = i
+		case "shortCode":
+			if reader.customCode < 0 {
+				reader.customCode = i
+			}
+		case "metadata":
+			reader.metadata = i
+		}
+	}
+	if reader.url < 0 {
+		return nil, errors.NewValidationError("bulk.NewReader", "CSV header has no url column", nil)
+	}
+	return reader, nil
+}
+
+func (r *csvReader) Next() (*dto.ImportRow, error) {
+	record, err := r.csv.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if stderrors.As(err, &parseErr) {
+		row := &dto.ImportRow{Line: parseErr.StartLine}
+		return row, errors.NewValidationError("bulk.Next", fmt.Sprintf("malformed CSV: %v", parseErr.Err), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := r.csv.FieldPos(0)
+	row := &dto.ImportRow{
+		Line:       line,
+		URL:        strings.TrimSpace(field(record, r.url)),
+		CustomCode: strings.TrimSpace(field(record, r.customCode)),
+	}
+	if metadata := strings.TrimSpace(field(record, r.metadata)); metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &row.Metadata); err != nil {
+			return row, errors.NewValidationError("bulk.Next", "metadata must be a JSON object of strings", err)
+		}
+	}
+	return row, nil
+}
+
+// field returns the value of column i, or "" when the column is absent.
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+type jsonlRow struct {
+	URL        string            `json:"url"`
+	CustomCode string            `json:"customCode"`
+	ShortCode  string            `json:"shortCode"`
+	Metadata   map[string]string `json:"metadata"`
+}
+
+func (r *jsonlReader) Next() (*dto.ImportRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := bytes.TrimSpace(r.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		row := &dto.ImportRow{Line: r.line}
+		var decoded jsonlRow
+		if err := json.Unmarshal(text, &decoded); err != nil {
+			return row, errors.NewValidationError("bulk.Next", "malformed JSON row", err)
+		}
+		row.URL = strings.TrimSpace(decoded.URL)
+		row.CustomCode = strings.TrimSpace(decoded.CustomCode)
+		if row.CustomCode == "" {
+			row.CustomCode = strings.TrimSpace(decoded.ShortCode)
+		}
+		row.Metadata = decoded.Metadata
+		return row, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		if stderrors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("line %d is longer than %d bytes: %w", r.line+1, MaxLineSize, err)
+		}
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,109 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/dto"
+)
+
+// Writer streams exported links. Flush must be called after the last one.
+type Writer interface {
+	Write(link *dto.GetStatsResponse) error
+	Flush() error
+}
+
+// exportColumns are the CSV export columns. shortCode, url and metadata
+// come first and are what NewReader imports.
+var exportColumns = []string{
+	"shortCode", "url", "metadata", "accessCount", "redirectType", "createdAt", "updatedAt",
+	"expiresAt", "maxClicks", "fallbackUrl", "passwordProtected", "activeFrom", "activeUntil",
+	"enabled", "disabledReason",
+}
+
+// NewWriter writes links in format to w. JSON Lines rows have the shape of
+// the stats endpoint; CSV rows have a column per field, with metadata as a
+// JSON object and timestamps in RFC 3339.
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case CSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportColumns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{csv: cw}, nil
+	case JSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type csvWriter struct {
+	csv *csv.Writer
+}
+
+func (w *csvWriter) Write(link *dto.GetStatsResponse) error {
+	var metadata string
+	if len(link.Metadata) > 0 {
+		encoded, err := json.Marshal(link.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = string(encoded)
+	}
+
+	return w.csv.Write([]string{
+		link.ShortCode,
+		link.URL,
+		metadata,
+		strconv.FormatInt(link.AccessCount, 10),
+		formatInt(int64(link.RedirectType)),
+		link.CreatedAt.UTC().Format(time.RFC3339),
+		link.UpdatedAt.UTC().Format(time.RFC3339),
+		formatTime(link.ExpiresAt),
+		formatInt(link.MaxClicks),
+		link.FallbackURL,
+		strconv.FormatBool(link.PasswordProtected),
+		formatTime(link.ActiveFrom),
+		formatTime(link.ActiveUntil),
+		strconv.FormatBool(link.Enabled),
+		link.DisabledReason,
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// formatInt renders 0, which means unset, as an empty cell.
+func formatInt(value int64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Write(link *dto.GetStatsResponse) error {
+	return w.encoder.Encode(link)
+}
+
+// Flush is a no-op; every row is written as soon as it is encoded.
+func (w *jsonlWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a size-bounded, concurrency-safe least-recently-used cache whose
+// entries also expire after a per-entry TTL.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[K]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+type lruEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &LRU[K, V]{
+		capacity: capacity,
+		items:    make(map[K]*list.Element, capacity),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get returns the value for key if present and not expired, marking it as
+// most recently used.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+
+	entry := elem.Value.(*lruEntry[K, V])
+	if !c.now().Before(entry.expiresAt) {
+		c.removeElement(elem)
+		return zero, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores value under key for ttl, evicting the least recently used entry
+// when the cache is full.
+func (c *LRU[K, V]) Set(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry[K, V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Update replaces the value for key in place, keeping its expiry and
+// recency. It does nothing if the key is absent or expired.
+func (c *LRU[K, V]) Update(key K, fn func(V) V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return
+	}
+	entry := elem.Value.(*lruEntry[K, V])
+	if !c.now().Before(entry.expiresAt) {
+		c.removeElement(elem)
+		return
+	}
+	entry.value = fn(entry.value)
+}
+
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry[K, V]).key)
+}
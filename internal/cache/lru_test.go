@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, int](2)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Get(a) missing")
+	}
+	c.Set("c", 3, time.Minute)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Get(b) present, want evicted as least recently used")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v, want 1, true", v, ok)
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Get(c) = %v, %v, want 3, true", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestLRUExpiresEntries(t *testing.T) {
+	now := time.Now()
+	c := NewLRU[string, int](10)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1, time.Second)
+	c.Set("b", 2, time.Minute)
+
+	now = now.Add(2 * time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get(a) present after TTL")
+	}
+	c.Update("a", func(v int) int { return v + 1 })
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want expired entry removed", c.Len())
+	}
+	if v, ok := c.Get("b"); !ok || v != 2 {
+		t.Errorf("Get(b) = %v, %v, want 2, true", v, ok)
+	}
+}
+
+func TestLRUUpdateAndDelete(t *testing.T) {
+	c := NewLRU[string, int](10)
+	c.Set("a", 1, time.Minute)
+
+	c.Update("a", func(v int) int { return v + 41 })
+	if v, _ := c.Get("a"); v != 42 {
+		t.Errorf("Get(a) after Update = %v, want 42", v)
+	}
+	c.Update("missing", func(v int) int { return 1 })
+	if _, ok := c.Get("missing"); ok {
+		t.Error("Update() created a missing key")
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get(a) present after Delete")
+	}
+}
+
+func TestLRUConcurrentAccess(t *testing.T) {
+	c := NewLRU[string, int](64)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := strconv.Itoa((i * j) % 100)
+				c.Set(key, j, time.Minute)
+				c.Get(key)
+				c.Update(key, func(v int) int { return v + 1 })
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if c.Len() > 64 {
+		t.Errorf("Len() = %d, exceeds capacity", c.Len())
+	}
+}
@@ -0,0 +1,296 @@
+// Package redis is a small RESP2 client covering the commands the shortener
+// needs: plain key/value, counters and sets, with pipelining.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNil is returned by helpers when a key does not exist.
+var ErrNil = errors.New("redis: nil")
+
+// Error is an error reply sent by the server.
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+type Options struct {
+	Addr        string
+	Password    string
+	DB          int
+	PoolSize    int
+	DialTimeout time.Duration
+	IOTimeout   time.Duration
+}
+
+// ParseURL reads options from a redis://[:password@]host:port[/db] URL.
+func ParseURL(rawURL string) (Options, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Options{}, fmt.Errorf("parse redis url: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return Options{}, fmt.Errorf("parse redis url: unsupported scheme %q", u.Scheme)
+	}
+
+	opts := Options{Addr: u.Host}
+	if u.Port() == "" {
+		opts.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if password, ok := u.User.Password(); ok {
+		opts.Password = password
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		opts.DB, err = strconv.Atoi(db)
+		if err != nil {
+			return Options{}, fmt.Errorf("parse redis url: invalid database %q", db)
+		}
+	}
+	return opts, nil
+}
+
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+}
+
+// Client is a concurrency-safe Redis client backed by a small pool of
+// connections.
+type Client struct {
+	opts Options
+	idle chan *conn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewClient(opts Options) *Client {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	if opts.IOTimeout <= 0 {
+		opts.IOTimeout = 3 * time.Second
+	}
+	return &Client{opts: opts, idle: make(chan *conn, opts.PoolSize)}
+}
+
+// Do sends a single command and returns its reply. Replies are string,
+// int64, nil, []any or Error.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	replies, err := c.Pipeline(ctx, [][]string{args})
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := replies[0].(Error); ok {
+		return nil, replyErr
+	}
+	return replies[0], nil
+}
+
+// Pipeline sends all commands in one write and reads their replies in
+// order. Error replies are returned in place rather than failing the batch.
+func (c *Client) Pipeline(ctx context.Context, cmds [][]string) ([]any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := c.roundTrip(ctx, cn, cmds)
+	if err != nil {
+		cn.netConn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return replies, nil
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Close closes idle connections; connections in use are closed when
+// returned.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.idle)
+	for cn := range c.idle {
+		cn.netConn.Close()
+	}
+	return nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, cn *conn, cmds [][]string) ([]any, error) {
+	deadline := time.Now().Add(c.opts.IOTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := cn.netConn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	for _, args := range cmds {
+		writeCommand(cn.writer, args)
+	}
+	if err := cn.writer.Flush(); err != nil {
+		return nil, fmt.Errorf("redis write: %w", err)
+	}
+
+	replies := make([]any, len(cmds))
+	for i := range cmds {
+		reply, err := readReply(cn.reader)
+		if err != nil {
+			return nil, fmt.Errorf("redis read: %w", err)
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, errors.New("redis: client closed")
+	}
+
+	select {
+	case cn, ok := <-c.idle:
+		if ok {
+			return cn, nil
+		}
+		return nil, errors.New("redis: client closed")
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.opts.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis dial: %w", err)
+	}
+	cn := &conn{netConn: netConn, reader: bufio.NewReader(netConn), writer: bufio.NewWriter(netConn)}
+
+	var setup [][]string
+	if c.opts.Password != "" {
+		setup = append(setup, []string{"AUTH", c.opts.Password})
+	}
+	if c.opts.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.opts.DB)})
+	}
+	if len(setup) > 0 {
+		replies, err := c.roundTrip(ctx, cn, setup)
+		if err == nil {
+			for _, reply := range replies {
+				if replyErr, ok := reply.(Error); ok {
+					err = replyErr
+					break
+				}
+			}
+		}
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		cn.netConn.Close()
+		return
+	}
+	select {
+	case c.idle <- cn:
+	default:
+		cn.netConn.Close()
+	}
+}
+
+// Get returns the string stored at key, or ErrNil.
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil {
+		return "", err
+	}
+	if reply == nil {
+		return "", ErrNil
+	}
+	return reply.(string), nil
+}
+
+// Set stores value at key, expiring after ttl when it is positive.
+func (c *Client) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	args := []string{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	_, err := c.Do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// AsInt64 converts integer and numeric string replies, treating nil as 0.
+func AsInt64(reply any) (int64, error) {
+	switch v := reply.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case Error:
+		return 0, v
+	default:
+		return 0, fmt.Errorf("redis: unexpected reply %T", reply)
+	}
+}
+
+// AsStrings converts an array reply of bulk strings.
+func AsStrings(reply any) ([]string, error) {
+	switch v := reply.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("redis: unexpected array item %T", item)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	case Error:
+		return nil, v
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %T", reply)
+	}
+}
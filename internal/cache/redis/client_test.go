@@ -0,0 +1,118 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/cache/redis"
+	"github.com/rowjay/url-shortening-service/internal/cache/redis/redistest"
+)
+
+func TestClientKeyValue(t *testing.T) {
+	ctx := context.Background()
+	server := redistest.NewServer(t)
+	client := server.Client(t)
+
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	if _, err := client.Get(ctx, "missing"); !errors.Is(err, redis.ErrNil) {
+		t.Errorf("Get(missing) error = %v, want ErrNil", err)
+	}
+
+	if err := client.Set(ctx, "k", "hello\r\nworld", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, err := client.Get(ctx, "k"); err != nil || got != "hello\r\nworld" {
+		t.Errorf("Get(k) = %q, %v", got, err)
+	}
+
+	server.FastForward(2 * time.Minute)
+	if _, err := client.Get(ctx, "k"); !errors.Is(err, redis.ErrNil) {
+		t.Errorf("Get(k) after TTL error = %v, want ErrNil", err)
+	}
+
+	if _, err := client.Do(ctx, "NOSUCHCOMMAND"); err == nil {
+		t.Error("Do(NOSUCHCOMMAND) expected error reply")
+	}
+}
+
+func TestClientPipeline(t *testing.T) {
+	ctx := context.Background()
+	client := redistest.NewServer(t).Client(t)
+
+	replies, err := client.Pipeline(ctx, [][]string{
+		{"INCRBY", "c", "5"},
+		{"INCRBY", "c", "2"},
+		{"SADD", "s", "a", "b"},
+		{"GETDEL", "c"},
+		{"GET", "c"},
+	})
+	if err != nil {
+		t.Fatalf("Pipeline() error = %v", err)
+	}
+
+	if n, _ := redis.AsInt64(replies[1]); n != 7 {
+		t.Errorf("INCRBY reply = %v, want 7", replies[1])
+	}
+	if n, _ := redis.AsInt64(replies[3]); n != 7 {
+		t.Errorf("GETDEL reply = %v, want 7", replies[3])
+	}
+	if replies[4] != nil {
+		t.Errorf("GET after GETDEL = %v, want nil", replies[4])
+	}
+}
+
+func TestClientConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	client := redistest.NewServer(t).Client(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := client.Do(ctx, "INCRBY", "n", "1"); err != nil {
+					t.Errorf("INCRBY error = %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := client.Get(ctx, "n")
+	if err != nil || got != strconv.Itoa(50*20) {
+		t.Errorf("Get(n) = %q, %v, want 1000", got, err)
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    redis.Options
+		wantErr bool
+	}{
+		{"redis://localhost:6379", redis.Options{Addr: "localhost:6379"}, false},
+		{"redis://cache", redis.Options{Addr: "cache:6379"}, false},
+		{"redis://:secret@cache:6380/2", redis.Options{Addr: "cache:6380", Password: "secret", DB: 2}, false},
+		{"http://cache:6379", redis.Options{}, true},
+		{"redis://cache/abc", redis.Options{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := redis.ParseURL(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseURL(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseURL(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package redistest provides an in-process server speaking enough of the
+// Redis protocol to test code built on the redis package.
+package redistest
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/cache/redis"
+)
+
+type value struct {
+	str       string
+	set       map[string]struct{}
+	expiresAt time.Time
+}
+
+// Server is a single-database, in-memory Redis stand-in.
+type Server struct {
+	listener net.Listener
+
+	mu   sync.Mutex
+	data map[string]*value
+	now  func() time.Time
+}
+
+// NewServer starts a server on a random local port and stops it when the
+// test finishes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("redistest: listen: %v", err)
+	}
+
+	s := &Server{listener: listener, data: make(map[string]*value), now: time.Now}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Client returns a redis.Client connected to the server.
+func (s *Server) Client(t testing.TB) *redis.Client {
+	client := redis.NewClient(redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// FastForward moves the server clock forward, expiring keys.
+func (s *Server) FastForward(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := s.now
+	s.now = func() time.Time { return current().Add(d) }
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	for {
+		args, err := redis.ReadCommand(reader)
+		if err != nil || len(args) == 0 {
+			return
+		}
+		redis.WriteReply(writer, s.exec(args))
+		if reader.Buffered() == 0 {
+			if err := writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) lookup(key string) *value {
+	v, ok := s.data[key]
+	if !ok {
+		return nil
+	}
+	if !v.expiresAt.IsZero() && !s.now().Before(v.expiresAt) {
+		delete(s.data, key)
+		return nil
+	}
+	return v
+}
+
+func (s *Server) exec(args []string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cmd := strings.ToUpper(args[0])
+	args = args[1:]
+
+	switch cmd {
+	case "PING":
+		return redis.Status("PONG")
+	case "AUTH", "SELECT":
+		return redis.Status("OK")
+	case "GET":
+		v := s.lookup(args[0])
+		if v == nil {
+			return nil
+		}
+		return v.str
+	case "SET":
+		v := &value{str: args[1]}
+		for i := 2; i+1 < len(args); i += 2 {
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return redis.Error("ERR value is not an integer or out of range")
+			}
+			switch strings.ToUpper(args[i]) {
+			case "EX":
+				v.expiresAt = s.now().Add(time.Duration(n) * time.Second)
+			case "PX":
+				v.expiresAt = s.now().Add(time.Duration(n) * time.Millisecond)
+			}
+		}
+		s.data[args[0]] = v
+		return redis.Status("OK")
+	case "GETDEL":
+		v := s.lookup(args[0])
+		if v == nil {
+			return nil
+		}
+		delete(s.data, args[0])
+		return v.str
+	case "DEL":
+		removed := 0
+		for _, key := range args {
+			if s.lookup(key) != nil {
+				delete(s.data, key)
+				removed++
+			}
+		}
+		return removed
+	case "EXISTS":
+		found := 0
+		for _, key := range args {
+			if s.lookup(key) != nil {
+				found++
+			}
+		}
+		return found
+	case "INCRBY":
+		delta, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return redis.Error("ERR value is not an integer or out of range")
+		}
+		v := s.lookup(args[0])
+		if v == nil {
+			v = &value{str: "0"}
+			s.data[args[0]] = v
+		}
+		current, err := strconv.ParseInt(v.str, 10, 64)
+		if err != nil {
+			return redis.Error("ERR value is not an integer or out of range")
+		}
+		current += delta
+		v.str = strconv.FormatInt(current, 10)
+		return current
+	case "PEXPIRE":
+		ms, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return redis.Error("ERR value is not an integer or out of range")
+		}
+		v := s.lookup(args[0])
+		if v == nil {
+			return 0
+		}
+		v.expiresAt = s.now().Add(time.Duration(ms) * time.Millisecond)
+		return 1
+	case "SADD":
+		v := s.lookup(args[0])
+		if v == nil {
+			v = &value{set: make(map[string]struct{})}
+			s.data[args[0]] = v
+		}
+		added := 0
+		for _, member := range args[1:] {
+			if _, ok := v.set[member]; !ok {
+				v.set[member] = struct{}{}
+				added++
+			}
+		}
+		return added
+	case "SPOP":
+		v := s.lookup(args[0])
+		count := 1
+		if len(args) > 1 {
+			count, _ = strconv.Atoi(args[1])
+		}
+		members := []string{}
+		if v != nil {
+			for member := range v.set {
+				if len(members) == count {
+					break
+				}
+				members = append(members, member)
+				delete(v.set, member)
+			}
+			if len(v.set) == 0 {
+				delete(s.data, args[0])
+			}
+		}
+		return members
+	case "SMEMBERS":
+		members := []string{}
+		if v := s.lookup(args[0]); v != nil {
+			for member := range v.set {
+				members = append(members, member)
+			}
+		}
+		return members
+	default:
+		return redis.Error("ERR unknown command '" + cmd + "'")
+	}
+}
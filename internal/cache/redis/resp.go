@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// writeCommand encodes args as a RESP array of bulk strings.
+func writeCommand(w *bufio.Writer, args []string) {
+	w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		w.WriteString(arg)
+		w.WriteString("\r\n")
+	}
+}
+
+// ReadCommand decodes a RESP array of bulk strings, as sent by clients.
+func ReadCommand(r *bufio.Reader) ([]string, error) {
+	reply, err := readReply(r)
+	if err != nil {
+		return nil, err
+	}
+	return AsStrings(reply)
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errors.New("redis: malformed line")
+	}
+	return line[:len(line)-2], nil
+}
+
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", line[0])
+	}
+}
+
+// WriteReply encodes a reply value the way a server would: string as bulk
+// string, int64/int as integer, nil as null bulk, []string/[]any as array,
+// Error as error and the Status type as a simple string.
+func WriteReply(w *bufio.Writer, reply any) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case Status:
+		w.WriteString("+" + string(v) + "\r\n")
+	case Error:
+		w.WriteString("-" + string(v) + "\r\n")
+	case int:
+		w.WriteString(":" + strconv.Itoa(v) + "\r\n")
+	case int64:
+		w.WriteString(":" + strconv.FormatInt(v, 10) + "\r\n")
+	case string:
+		w.WriteString("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n")
+	case []string:
+		w.WriteString("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, item := range v {
+			WriteReply(w, item)
+		}
+	case []any:
+		w.WriteString("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, item := range v {
+			WriteReply(w, item)
+		}
+	default:
+		WriteReply(w, Error(fmt.Sprintf("ERR unsupported reply %T", reply)))
+	}
+}
+
+// Status is a simple string reply such as OK or PONG.
+type Status string
@@ -0,0 +1,180 @@
+package clicks
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// OverflowPolicy decides what Record does when the queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowDrop discards the event and counts it as dropped.
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowBlock waits for room until the caller's context is done.
+	OverflowBlock OverflowPolicy = "block"
+)
+
+// Event is a single resolve of a short code.
+type Event struct {
+	ShortCode string
+	At        time.Time
+}
+
+// Sink consumes click events on the worker goroutines.
+type Sink interface {
+	RecordClick(ctx context.Context, event Event) error
+}
+
+type Options struct {
+	QueueSize int
+	Workers   int
+	Overflow  OverflowPolicy
+}
+
+// Stats is a point-in-time snapshot of the queue counters.
+type Stats struct {
+	Enqueued  uint64 `json:"enqueued"`
+	Processed uint64 `json:"processed"`
+	Failed    uint64 `json:"failed"`
+	Dropped   uint64 `json:"dropped"`
+	Depth     int    `json:"depth"`
+	Capacity  int    `json:"capacity"`
+}
+
+// Queue moves click recording off the request path: Record hands the event
+// to a bounded channel and returns, and a fixed pool of workers feeds the
+// Sink.
+type Queue struct {
+	sink    Sink
+	opts    Options
+	events  chan Event
+	wg      sync.WaitGroup
+	dropLog zerolog.Logger
+
+	mu     sync.RWMutex
+	closed bool
+
+	enqueued  atomic.Uint64
+	processed atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func NewQueue(sink Sink, opts Options) *Queue {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.Overflow != OverflowBlock {
+		opts.Overflow = OverflowDrop
+	}
+
+	return &Queue{
+		sink:    sink,
+		opts:    opts,
+		events:  make(chan Event, opts.QueueSize),
+		dropLog: log.Sample(&zerolog.BasicSampler{N: 1000}),
+	}
+}
+
+// Start launches the worker goroutines.
+func (q *Queue) Start() {
+	for i := 0; i < q.opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for event := range q.events {
+		if err := q.sink.RecordClick(context.Background(), event); err != nil {
+			q.failed.Add(1)
+			log.Warn().Err(err).Str("short_code", event.ShortCode).Msg("Failed to record click")
+			continue
+		}
+		q.processed.Add(1)
+	}
+}
+
+// RecordClick enqueues a click on shortCode according to the overflow
+// policy. It never reports an error to the caller; lost events show up in
+// Stats instead.
+func (q *Queue) RecordClick(ctx context.Context, shortCode string) {
+	event := Event{ShortCode: shortCode, At: time.Now().UTC()}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		q.drop(event, "queue stopped")
+		return
+	}
+
+	select {
+	case q.events <- event:
+		q.enqueued.Add(1)
+		return
+	default:
+	}
+
+	if q.opts.Overflow == OverflowDrop {
+		q.drop(event, "queue full")
+		return
+	}
+
+	select {
+	case q.events <- event:
+		q.enqueued.Add(1)
+	case <-ctx.Done():
+		q.drop(event, "context done while queue full")
+	}
+}
+
+func (q *Queue) drop(event Event, reason string) {
+	total := q.dropped.Add(1)
+	q.dropLog.Warn().Str("short_code", event.ShortCode).Str("reason", reason).Uint64("dropped_total", total).Msg("Dropped click event")
+}
+
+// Stop refuses new events and waits for the workers to drain what is
+// already queued.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) Stats() Stats {
+	return Stats{
+		Enqueued:  q.enqueued.Load(),
+		Processed: q.processed.Load(),
+		Failed:    q.failed.Load(),
+		Dropped:   q.dropped.Load(),
+		Depth:     len(q.events),
+		Capacity:  cap(q.events),
+	}
+}
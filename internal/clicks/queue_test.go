@@ -0,0 +1,131 @@
+package clicks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	events  []Event
+	release chan struct{}
+	fail    bool
+}
+
+func (s *recordingSink) RecordClick(ctx context.Context, event Event) error {
+	if s.release != nil {
+		<-s.release
+	}
+	if s.fail {
+		return errors.New("sink failure")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+func TestQueueDrainsOnStop(t *testing.T) {
+	sink := &recordingSink{}
+	q := NewQueue(sink, Options{QueueSize: 100, Workers: 3, Overflow: OverflowBlock})
+	q.Start()
+
+	for i := 0; i < 500; i++ {
+		q.RecordClick(context.Background(), "abc")
+	}
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	if got := sink.count(); got != 500 {
+		t.Errorf("sink received %d events, want 500", got)
+	}
+	if stats := q.Stats(); stats.Enqueued != 500 || stats.Processed != 500 || stats.Dropped != 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestQueueDropPolicyCountsOverflow(t *testing.T) {
+	sink := &recordingSink{release: make(chan struct{})}
+	q := NewQueue(sink, Options{QueueSize: 2, Workers: 1, Overflow: OverflowDrop})
+	q.Start()
+
+	// One event is held by the blocked worker, two fill the buffer and the
+	// rest overflow.
+	q.RecordClick(context.Background(), "abc")
+	waitFor(t, func() bool { return q.Stats().Depth == 0 })
+	for i := 0; i < 5; i++ {
+		q.RecordClick(context.Background(), "abc")
+	}
+
+	if stats := q.Stats(); stats.Dropped != 3 || stats.Enqueued != 3 {
+		t.Errorf("Stats() = %+v, want 3 enqueued and 3 dropped", stats)
+	}
+
+	close(sink.release)
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if got := sink.count(); got != 3 {
+		t.Errorf("sink received %d events, want 3", got)
+	}
+}
+
+func TestQueueBlockPolicyHonoursContext(t *testing.T) {
+	sink := &recordingSink{release: make(chan struct{})}
+	q := NewQueue(sink, Options{QueueSize: 1, Workers: 1, Overflow: OverflowBlock})
+	q.Start()
+
+	q.RecordClick(context.Background(), "abc")
+	waitFor(t, func() bool { return q.Stats().Depth == 0 })
+	q.RecordClick(context.Background(), "abc")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	q.RecordClick(ctx, "abc")
+
+	if stats := q.Stats(); stats.Dropped != 1 {
+		t.Errorf("Stats() = %+v, want the timed out event dropped", stats)
+	}
+
+	close(sink.release)
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+}
+
+func TestQueueCountsSinkFailuresAndLateEvents(t *testing.T) {
+	sink := &recordingSink{fail: true}
+	q := NewQueue(sink, Options{QueueSize: 10, Workers: 1})
+	q.Start()
+
+	q.RecordClick(context.Background(), "abc")
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	q.RecordClick(context.Background(), "abc")
+
+	if stats := q.Stats(); stats.Failed != 1 || stats.Dropped != 1 {
+		t.Errorf("Stats() = %+v, want 1 failed and 1 dropped", stats)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
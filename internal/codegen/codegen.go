@@ -0,0 +1,151 @@
+// Package codegen generates short codes for links created without a custom
+// one. Every generator is safe for concurrent use. Codes are candidates:
+// the caller still has to make sure a code is not taken.
+package codegen
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rowjay/url-shortening-service/internal/utils"
+)
+
+// Strategy names a generator in configuration.
+type Strategy string
+
+const (
+	// StrategyRandom picks every character at random.
+	StrategyRandom Strategy = "random"
+	// StrategyCounter encodes sequential numbers in base62.
+	StrategyCounter Strategy = "counter"
+	// StrategyHashids encodes sequential numbers with a salted alphabet, so
+	// consecutive codes look unrelated.
+	StrategyHashids Strategy = "hashids"
+	// StrategyULID generates ULIDs cut to the code length, so codes sort in
+	// the order they were generated.
+	StrategyULID Strategy = "ulid"
+)
+
+// Strategies lists every supported strategy.
+var Strategies = []Strategy{StrategyRandom, StrategyCounter, StrategyHashids, StrategyULID}
+
+// alphabet holds the base62 digits in ASCII order, so fixed-width codes
+// sort like the numbers they encode.
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Sequence hands out the numbers the counter-based generators encode. Next
+// never returns the same number twice.
+type Sequence interface {
+	Next(ctx context.Context) (uint64, error)
+}
+
+// MemorySequence counts up in process memory. A new process starts again
+// from its start value, so it only guarantees distinct numbers within one
+// process.
+type MemorySequence struct {
+	next atomic.Uint64
+}
+
+func NewMemorySequence(start uint64) *MemorySequence {
+	s := &MemorySequence{}
+	s.next.Store(start)
+	return s
+}
+
+func (s *MemorySequence) Next(ctx context.Context) (uint64, error) {
+	return s.next.Add(1) - 1, nil
+}
+
+// Allocator reserves consecutive numbers in shared storage and returns the
+// first of them. repository.SequenceRepository satisfies it.
+type Allocator interface {
+	Allocate(ctx context.Context, n uint64) (uint64, error)
+}
+
+// BlockSequence hands out numbers from blocks reserved through an
+// Allocator, so every process sharing the storage continues one sequence.
+// Numbers left in a block when the process stops are skipped.
+type BlockSequence struct {
+	alloc Allocator
+	block uint64
+
+	mu   sync.Mutex
+	next uint64
+	end  uint64
+}
+
+func NewBlockSequence(alloc Allocator, block uint64) *BlockSequence {
+	return &BlockSequence{alloc: alloc, block: max(block, 1)}
+}
+
+func (s *BlockSequence) Next(ctx context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next == s.end {
+		first, err := s.alloc.Allocate(ctx, s.block)
+		if err != nil {
+			return 0, err
+		}
+		s.next, s.end = first, first+s.block
+	}
+	n := s.next
+	s.next++
+	return n, nil
+}
+
+// Random generates codes of random base62 characters.
+type Random struct {
+	length int
+}
+
+func NewRandom(length int) *Random {
+	return &Random{length: length}
+}
+
+func (g *Random) Generate(ctx context.Context) (string, error) {
+	return utils.GenerateShortCode(g.length)
+}
+
+// Counter generates the base62 encoding of the next number of a sequence,
+// left-padded with zeros to length. Codes grow past length once the
+// sequence outgrows it.
+type Counter struct {
+	seq    Sequence
+	length int
+}
+
+func NewCounter(seq Sequence, length int) *Counter {
+	return &Counter{seq: seq, length: length}
+}
+
+func (g *Counter) Generate(ctx context.Context) (string, error) {
+	n, err := g.seq.Next(ctx)
+	if err != nil {
+		return "", fmt.Errorf("next sequence number: %w", err)
+	}
+	return encode(n, alphabet, g.length), nil
+}
+
+// encode writes n in the base of digits, left-padded with the zero digit
+// to at least width characters.
+func encode(n uint64, digits string, width int) string {
+	base := uint64(len(digits))
+	buf := make([]byte, 0, max(width, 11))
+	for {
+		buf = append(buf, digits[n%base])
+		n /= base
+		if n == 0 {
+			break
+		}
+	}
+	for len(buf) < width {
+		buf = append(buf, digits[0])
+	}
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+	return string(buf)
+}
@@ -0,0 +1,157 @@
+package codegen
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type generator interface {
+	Generate(ctx context.Context) (string, error)
+}
+
+func generateN(t *testing.T, g generator, n int) []string {
+	t.Helper()
+	codes := make([]string, n)
+	for i := range codes {
+		code, err := g.Generate(context.Background())
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		codes[i] = code
+	}
+	return codes
+}
+
+func TestRandom(t *testing.T) {
+	for _, code := range generateN(t, NewRandom(9), 20) {
+		if len(code) != 9 {
+			t.Errorf("Generate() = %q, want 9 characters", code)
+		}
+	}
+}
+
+func TestCounter(t *testing.T) {
+	codes := generateN(t, NewCounter(NewMemorySequence(60), 4), 4)
+	if want := []string{"000y", "000z", "0010", "0011"}; !slices.Equal(codes, want) {
+		t.Errorf("Generate() = %v, want %v", codes, want)
+	}
+
+	large := generateN(t, NewCounter(NewMemorySequence(62*62*62*62), 4), 1)[0]
+	if large != "10000" {
+		t.Errorf("Generate() past the width = %q, want 10000", large)
+	}
+}
+
+// blockAllocator hands out consecutive blocks and counts the calls.
+type blockAllocator struct {
+	next  uint64
+	calls int
+	err   error
+}
+
+func (a *blockAllocator) Allocate(ctx context.Context, n uint64) (uint64, error) {
+	a.calls++
+	if a.err != nil {
+		return 0, a.err
+	}
+	first := a.next
+	a.next += n
+	return first, nil
+}
+
+func TestBlockSequence(t *testing.T) {
+	alloc := &blockAllocator{next: 100}
+	seq := NewBlockSequence(alloc, 3)
+
+	var got []uint64
+	for range 7 {
+		n, err := seq.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		got = append(got, n)
+	}
+	if want := []uint64{100, 101, 102, 103, 104, 105, 106}; !slices.Equal(got, want) {
+		t.Errorf("Next() = %v, want %v", got, want)
+	}
+	if alloc.calls != 3 {
+		t.Errorf("Allocate called %d times, want once per block of 3", alloc.calls)
+	}
+
+	// Another process took the next block; this one moves past it.
+	alloc.next += 3
+	alloc.err = errors.New("storage down")
+	if _, err := seq.Next(context.Background()); err != nil {
+		t.Fatalf("Next() within the block error = %v", err)
+	}
+	seq.Next(context.Background())
+	if _, err := seq.Next(context.Background()); err == nil {
+		t.Error("Next() error = nil, want the allocator error")
+	}
+	alloc.err = nil
+	if n, err := seq.Next(context.Background()); err != nil || n != 112 {
+		t.Errorf("Next() = %d, %v, want 112 from the next free block", n, err)
+	}
+}
+
+func TestHashids(t *testing.T) {
+	g := NewHashids(NewMemorySequence(0), "pepper", 6)
+	seen := make(map[string]bool)
+	for n := uint64(0); n < 200000; n++ {
+		code := g.Encode(n)
+		if len(code) < 6 || strings.Trim(code, alphabet) != "" {
+			t.Fatalf("Encode(%d) = %q, want at least 6 base62 characters", n, code)
+		}
+		if seen[code] {
+			t.Fatalf("Encode(%d) = %q, which an earlier number already produced", n, code)
+		}
+		seen[code] = true
+	}
+
+	if a, b := g.Encode(1000), g.Encode(1001); a[1:] == b[1:] || a[:len(a)-1] == b[:len(b)-1] {
+		t.Errorf("consecutive codes %q and %q look related", a, b)
+	}
+	if g.Encode(42) == NewHashids(NewMemorySequence(0), "salt", 6).Encode(42) {
+		t.Error("different salts produced the same code")
+	}
+	if got := generateN(t, g, 1)[0]; got != g.Encode(0) {
+		t.Errorf("Generate() = %q, want Encode(0) = %q", got, g.Encode(0))
+	}
+}
+
+func TestULID(t *testing.T) {
+	g := NewULID(16)
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	earlier := generateN(t, g, 1)[0]
+	now = now.Add(time.Millisecond)
+	later := generateN(t, g, 1)[0]
+
+	if len(earlier) != 16 || strings.Trim(earlier, crockford) != "" {
+		t.Errorf("Generate() = %q, want 16 Crockford base32 characters", earlier)
+	}
+	if want := "01JGJFGR48"; earlier[:ulidTimeWidth] != want {
+		t.Errorf("Generate() = %q, want the timestamp %s", earlier, want)
+	}
+	if earlier >= later {
+		t.Errorf("codes %q and %q do not sort by creation time", earlier, later)
+	}
+
+	if code := generateN(t, NewULID(6), 1)[0]; len(code) != ULIDMinLength {
+		t.Errorf("Generate() = %q, want the %d character minimum", code, ULIDMinLength)
+	}
+}
+
+func TestEncodeULID(t *testing.T) {
+	// The timestamp of the specification's example ULID, followed by
+	// arbitrary entropy.
+	entropy := [ulidRandomBytes]byte{0x9c, 0xa6, 0xe3, 0x1f, 0x0e, 0x8d, 0xd3, 0x57, 0xbd, 0x5e}
+	got := encodeULID(1469918176385, entropy)
+	if want := "01ARYZ6S41KJKE67REHQ9NFFAY"; got != want {
+		t.Errorf("encodeULID() = %q, want %q", got, want)
+	}
+}
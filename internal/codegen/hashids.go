@@ -0,0 +1,63 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+)
+
+// Hashids generates obfuscated codes from a sequence, in the style of
+// Hashids: the first character is a "lottery" picked by the number, and
+// the rest is the number in an alphabet shuffled by the lottery and the
+// salt. Consecutive numbers therefore produce unrelated-looking codes,
+// while every number still maps to exactly one code.
+type Hashids struct {
+	seq      Sequence
+	length   int
+	salt     []byte
+	alphabet string
+}
+
+// NewHashids returns a generator whose codes are at least length
+// characters. Different salts give different codes for the same number;
+// keep the salt fixed once codes have been handed out.
+func NewHashids(seq Sequence, salt string, length int) *Hashids {
+	return &Hashids{
+		seq:      seq,
+		length:   length,
+		salt:     []byte(salt),
+		alphabet: shuffle(alphabet, []byte(salt)),
+	}
+}
+
+func (g *Hashids) Generate(ctx context.Context) (string, error) {
+	n, err := g.seq.Next(ctx)
+	if err != nil {
+		return "", fmt.Errorf("next sequence number: %w", err)
+	}
+	return g.Encode(n), nil
+}
+
+// Encode returns the code of n.
+func (g *Hashids) Encode(n uint64) string {
+	lottery := g.alphabet[n%uint64(len(g.alphabet))]
+	key := append([]byte{lottery}, g.salt...)
+	return string(lottery) + encode(n, shuffle(g.alphabet, key), g.length-1)
+}
+
+// shuffle permutes digits deterministically by key, as Hashids' consistent
+// shuffle does. An empty key leaves digits unchanged.
+func shuffle(digits string, key []byte) string {
+	if len(key) == 0 {
+		return digits
+	}
+	result := []byte(digits)
+	for i, v, p := len(result)-1, 0, 0; i > 0; i-- {
+		v %= len(key)
+		c := int(key[v])
+		p += c
+		j := (c + v + p) % i
+		result[i], result[j] = result[j], result[i]
+		v++
+	}
+	return string(result)
+}
@@ -0,0 +1,67 @@
+package codegen
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+)
+
+const (
+	// crockford is the Crockford base32 alphabet ULIDs are written in. It is
+	// in ASCII order, so codes sort like the ULIDs they come from.
+	crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// ulidTimeWidth is the 48-bit millisecond timestamp in base32.
+	ulidTimeWidth = 10
+	// ulidRandomBytes is the 80 bits of randomness of a ULID, which take
+	// 16 base32 characters.
+	ulidRandomBytes = 10
+	// ULIDMinLength keeps four random characters after the timestamp, so
+	// codes made in the same millisecond are unlikely to collide.
+	ULIDMinLength = ulidTimeWidth + 4
+	// ULIDMaxLength is the length of a complete ULID.
+	ULIDMaxLength = ulidTimeWidth + ulidRandomBytes*8/5
+)
+
+// ULID generates codes that are ULIDs cut to length: the creation time in
+// milliseconds and then randomness, in Crockford base32. Shortening drops
+// trailing random characters, so codes made in different milliseconds
+// still sort by creation time.
+type ULID struct {
+	length int
+	now    func() time.Time
+}
+
+// NewULID returns a generator of length character codes. Lengths outside
+// ULIDMinLength and ULIDMaxLength are moved to the nearest bound.
+func NewULID(length int) *ULID {
+	return &ULID{length: min(max(length, ULIDMinLength), ULIDMaxLength), now: time.Now}
+}
+
+func (g *ULID) Generate(ctx context.Context) (string, error) {
+	var entropy [ulidRandomBytes]byte
+	if _, err := rand.Read(entropy[:]); err != nil {
+		return "", fmt.Errorf("read ulid entropy: %w", err)
+	}
+	return encodeULID(uint64(g.now().UnixMilli()), entropy)[:g.length], nil
+}
+
+// encodeULID writes the canonical 26 character form of the ULID made of
+// the millisecond timestamp ms and entropy.
+func encodeULID(ms uint64, entropy [ulidRandomBytes]byte) string {
+	buf := make([]byte, 0, ULIDMaxLength)
+	buf = append(buf, encode(ms&(1<<48-1), crockford, ulidTimeWidth)...)
+
+	// Read the entropy five bits at a time, most significant first.
+	var acc uint64
+	bits := 0
+	for _, b := range entropy {
+		acc = acc<<8 | uint64(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			buf = append(buf, crockford[acc>>bits&31])
+		}
+	}
+	return string(buf)
+}
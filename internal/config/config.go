@@ -2,19 +2,77 @@ package config
 
 import (
 	"log"
+	"slices"
+	"time"
 
+	"github.com/rowjay/url-shortening-service/internal/codegen"
 	"github.com/rowjay/url-shortening-service/internal/constants"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	BaseURL            string
-	JWTSecret          string
-	Environment        string
-	CORSAllowedOrigins []string
-	Port               string
-	ShortCodeLength    int
-	MaxRetries         int
+	StorageDriver       string
+	BaseURL             string
+	PBSuperuserEmail    string
+	PBSuperuserPassword string
+	PBAuthCollection    string
+	PBAuthIdentity      string
+	PBAuthPassword      string
+	SQLitePath          string
+	DatabaseURL         string
+	AutoMigrate         bool
+	JWTSecret           string
+	Environment         string
+	CORSAllowedOrigins  []string
+	TrustedProxies      []string
+	Port                string
+	DebugAddr           string
+	ShortCodeLength     int
+	ShortCodeStrategy   string
+	ShortCodeSalt       string
+	MaxRetries          int
+	RetryBaseDelay      time.Duration
+	RetryMaxDelay       time.Duration
+	BreakerThreshold    int
+	BreakerOpenTimeout  time.Duration
+	RedirectStatusCode  int
+	NotYetActive        string
+
+	AccessCountFlushInterval time.Duration
+	ClickQueueSize           int
+	ClickWorkers             int
+	ClickOverflowPolicy      string
+
+	CacheEnabled     bool
+	CacheSize        int
+	CacheTTL         time.Duration
+	CacheNegativeTTL time.Duration
+
+	RedisURL           string
+	RedisKeyPrefix     string
+	RedisTTL           time.Duration
+	RedisNegativeTTL   time.Duration
+	RedisFlushInterval time.Duration
+
+	ExpirySweepEnabled  bool
+	ExpirySweepInterval time.Duration
+	ExpiryRetention     time.Duration
+	ExpiryArchivePath   string
+
+	TrashPurgeInterval time.Duration
+	TrashRetention     time.Duration
+
+	UnlockTokenSecret   string
+	UnlockTokenTTL      time.Duration
+	UnlockMaxAttempts   int
+	UnlockAttemptWindow time.Duration
+
+	BatchMaxSize     int
+	BatchConcurrency int
+
+	KeyPoolEnabled  bool
+	KeyPoolSize     int
+	KeyPoolLowWater int
 }
 
 func Load() *Config {
@@ -23,9 +81,45 @@ func Load() *Config {
 	viper.AddConfigPath("./config")
 	viper.AddConfigPath(".")
 	viper.AutomaticEnv()
+	viper.SetDefault("storage_driver", constants.StorageDriverPocketBase)
+	viper.SetDefault("sqlite_path", constants.DefaultSQLitePath)
+	viper.SetDefault("auto_migrate", true)
 	viper.SetDefault("cors_allowed_origins", []string{"*"})
+	viper.SetDefault("debug_addr", constants.DefaultDebugAddr)
 	viper.SetDefault("short_code_length", constants.DefaultShortCodeLength)
+	viper.SetDefault("short_code_strategy", string(codegen.StrategyRandom))
 	viper.SetDefault("max_retries", constants.MaxRetries)
+	viper.SetDefault("retry_base_delay", constants.DefaultRetryBaseDelay)
+	viper.SetDefault("retry_max_delay", constants.DefaultRetryMaxDelay)
+	viper.SetDefault("breaker_failure_threshold", constants.DefaultBreakerFailureThreshold)
+	viper.SetDefault("breaker_open_timeout", constants.DefaultBreakerOpenTimeout)
+	viper.SetDefault("redirect_status_code", constants.DefaultRedirectStatus)
+	viper.SetDefault("not_yet_active_response", constants.NotYetActivePage)
+	viper.SetDefault("access_count_flush_interval", constants.DefaultFlushInterval)
+	viper.SetDefault("click_queue_size", constants.DefaultClickQueueSize)
+	viper.SetDefault("click_workers", constants.DefaultClickWorkers)
+	viper.SetDefault("click_overflow_policy", "drop")
+	viper.SetDefault("cache_enabled", true)
+	viper.SetDefault("cache_size", constants.DefaultCacheSize)
+	viper.SetDefault("cache_ttl", constants.DefaultCacheTTL)
+	viper.SetDefault("cache_negative_ttl", constants.DefaultNegativeTTL)
+	viper.SetDefault("redis_key_prefix", constants.DefaultRedisKeyPrefix)
+	viper.SetDefault("redis_ttl", constants.DefaultRedisTTL)
+	viper.SetDefault("redis_negative_ttl", constants.DefaultNegativeTTL)
+	viper.SetDefault("redis_flush_interval", constants.DefaultFlushInterval)
+	viper.SetDefault("expiry_sweep_enabled", true)
+	viper.SetDefault("expiry_sweep_interval", constants.DefaultExpirySweepInterval)
+	viper.SetDefault("expiry_retention", constants.DefaultExpiryRetention)
+	viper.SetDefault("trash_purge_interval", constants.DefaultTrashPurgeInterval)
+	viper.SetDefault("trash_retention", constants.DefaultTrashRetention)
+	viper.SetDefault("unlock_token_ttl", constants.DefaultUnlockTokenTTL)
+	viper.SetDefault("unlock_max_attempts", constants.DefaultUnlockMaxAttempts)
+	viper.SetDefault("unlock_attempt_window", constants.DefaultUnlockWindow)
+	viper.SetDefault("batch_max_size", constants.DefaultBatchMaxSize)
+	viper.SetDefault("batch_concurrency", constants.DefaultBatchConcurrency)
+	viper.SetDefault("key_pool_enabled", true)
+	viper.SetDefault("key_pool_size", constants.DefaultKeyPoolSize)
+	viper.SetDefault("key_pool_low_water", constants.DefaultKeyPoolLowWater)
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Printf("Error reading config file, using defaults: %v", err)
@@ -36,13 +130,165 @@ func Load() *Config {
 		corsAllowedOrigins = []string{"*"}
 	}
 
+	clickOverflowPolicy := viper.GetString("click_overflow_policy")
+	if clickOverflowPolicy != "drop" && clickOverflowPolicy != "block" {
+		log.Printf("Unsupported click_overflow_policy %q, using drop", clickOverflowPolicy)
+		clickOverflowPolicy = "drop"
+	}
+
+	redirectStatusCode := viper.GetInt("redirect_status_code")
+	if !slices.Contains(constants.RedirectStatusCodes, redirectStatusCode) {
+		log.Printf("Unsupported redirect_status_code %d, using %d", redirectStatusCode, constants.DefaultRedirectStatus)
+		redirectStatusCode = constants.DefaultRedirectStatus
+	}
+
+	unlockMaxAttempts := viper.GetInt("unlock_max_attempts")
+	if unlockMaxAttempts <= 0 {
+		log.Printf("Invalid unlock_max_attempts %d, using %d", unlockMaxAttempts, constants.DefaultUnlockMaxAttempts)
+		unlockMaxAttempts = constants.DefaultUnlockMaxAttempts
+	}
+
+	notYetActive := viper.GetString("not_yet_active_response")
+	if notYetActive != constants.NotYetActivePage && notYetActive != constants.NotYetActiveNotFound {
+		log.Printf("Unsupported not_yet_active_response %q, using %s", notYetActive, constants.NotYetActivePage)
+		notYetActive = constants.NotYetActivePage
+	}
+
+	expiryRetention := viper.GetDuration("expiry_retention")
+	if expiryRetention < 0 {
+		log.Printf("Invalid expiry_retention %q, purging expired links immediately", viper.GetString("expiry_retention"))
+		expiryRetention = 0
+	}
+
+	trashRetention := viper.GetDuration("trash_retention")
+	if trashRetention < 0 {
+		log.Printf("Invalid trash_retention %q, purging deleted links immediately", viper.GetString("trash_retention"))
+		trashRetention = 0
+	}
+
+	shortCodeLength := viper.GetInt("short_code_length")
+	if shortCodeLength < constants.MinShortCodeLength || shortCodeLength > constants.MaxShortCodeLength {
+		log.Printf("Invalid short_code_length %d, using %d", shortCodeLength, constants.DefaultShortCodeLength)
+		shortCodeLength = constants.DefaultShortCodeLength
+	}
+
+	shortCodeStrategy := viper.GetString("short_code_strategy")
+	if !slices.Contains(codegen.Strategies, codegen.Strategy(shortCodeStrategy)) {
+		log.Printf("Unsupported short_code_strategy %q, using %s", shortCodeStrategy, codegen.StrategyRandom)
+		shortCodeStrategy = string(codegen.StrategyRandom)
+	}
+	if shortCodeStrategy == string(codegen.StrategyULID) && shortCodeLength < codegen.ULIDMinLength {
+		log.Printf("short_code_length %d is too short for the ulid strategy, using %d", shortCodeLength, codegen.ULIDMinLength)
+		shortCodeLength = codegen.ULIDMinLength
+	}
+
+	batchMaxSize := viper.GetInt("batch_max_size")
+	if batchMaxSize <= 0 {
+		log.Printf("Invalid batch_max_size %d, using %d", batchMaxSize, constants.DefaultBatchMaxSize)
+		batchMaxSize = constants.DefaultBatchMaxSize
+	}
+
+	batchConcurrency := viper.GetInt("batch_concurrency")
+	if batchConcurrency <= 0 {
+		log.Printf("Invalid batch_concurrency %d, using %d", batchConcurrency, constants.DefaultBatchConcurrency)
+		batchConcurrency = constants.DefaultBatchConcurrency
+	}
+
+	keyPoolSize := viper.GetInt("key_pool_size")
+	if keyPoolSize <= 0 || keyPoolSize > constants.MaxKeyPoolSize {
+		log.Printf("Invalid key_pool_size %d, using %d", keyPoolSize, constants.DefaultKeyPoolSize)
+		keyPoolSize = constants.DefaultKeyPoolSize
+	}
+
+	keyPoolLowWater := viper.GetInt("key_pool_low_water")
+	if keyPoolLowWater < 0 || keyPoolLowWater >= keyPoolSize {
+		lowWater := min(constants.DefaultKeyPoolLowWater, keyPoolSize/5)
+		log.Printf("Invalid key_pool_low_water %d, using %d", keyPoolLowWater, lowWater)
+		keyPoolLowWater = lowWater
+	}
+
 	return &Config{
-		BaseURL:            viper.GetString("pocket_base_url"),
-		JWTSecret:          viper.GetString("jwt_secret"),
-		Environment:        viper.GetString("app_env"),
-		CORSAllowedOrigins: corsAllowedOrigins,
-		Port:               viper.GetString("port"),
-		ShortCodeLength:    viper.GetInt("short_code_length"),
-		MaxRetries:         viper.GetInt("max_retries"),
+		StorageDriver:       viper.GetString("storage_driver"),
+		BaseURL:             viper.GetString("pocket_base_url"),
+		PBSuperuserEmail:    viper.GetString("pocketbase_superuser_email"),
+		PBSuperuserPassword: viper.GetString("pocketbase_superuser_password"),
+		PBAuthCollection:    viper.GetString("pocketbase_auth_collection"),
+		PBAuthIdentity:      viper.GetString("pocketbase_auth_identity"),
+		PBAuthPassword:      viper.GetString("pocketbase_auth_password"),
+		SQLitePath:          viper.GetString("sqlite_path"),
+		DatabaseURL:         viper.GetString("database_url"),
+		AutoMigrate:         viper.GetBool("auto_migrate"),
+		JWTSecret:           viper.GetString("jwt_secret"),
+		Environment:         viper.GetString("app_env"),
+		CORSAllowedOrigins:  corsAllowedOrigins,
+		TrustedProxies:      viper.GetStringSlice("trusted_proxies"),
+		Port:                viper.GetString("port"),
+		DebugAddr:           viper.GetString("debug_addr"),
+		ShortCodeLength:     shortCodeLength,
+		ShortCodeStrategy:   shortCodeStrategy,
+		ShortCodeSalt:       viper.GetString("short_code_salt"),
+		MaxRetries:          viper.GetInt("max_retries"),
+		RetryBaseDelay:      positiveDuration("retry_base_delay", constants.DefaultRetryBaseDelay),
+		RetryMaxDelay:       positiveDuration("retry_max_delay", constants.DefaultRetryMaxDelay),
+		BreakerThreshold:    viper.GetInt("breaker_failure_threshold"),
+		BreakerOpenTimeout:  positiveDuration("breaker_open_timeout", constants.DefaultBreakerOpenTimeout),
+		RedirectStatusCode:  redirectStatusCode,
+		NotYetActive:        notYetActive,
+
+		AccessCountFlushInterval: positiveDuration("access_count_flush_interval", constants.DefaultFlushInterval),
+		ClickQueueSize:           viper.GetInt("click_queue_size"),
+		ClickWorkers:             viper.GetInt("click_workers"),
+		ClickOverflowPolicy:      clickOverflowPolicy,
+
+		CacheEnabled:     viper.GetBool("cache_enabled"),
+		CacheSize:        viper.GetInt("cache_size"),
+		CacheTTL:         positiveDuration("cache_ttl", constants.DefaultCacheTTL),
+		CacheNegativeTTL: viper.GetDuration("cache_negative_ttl"),
+
+		RedisURL:           viper.GetString("redis_url"),
+		RedisKeyPrefix:     viper.GetString("redis_key_prefix"),
+		RedisTTL:           positiveDuration("redis_ttl", constants.DefaultRedisTTL),
+		RedisNegativeTTL:   viper.GetDuration("redis_negative_ttl"),
+		RedisFlushInterval: positiveDuration("redis_flush_interval", constants.DefaultFlushInterval),
+
+		ExpirySweepEnabled:  viper.GetBool("expiry_sweep_enabled"),
+		ExpirySweepInterval: positiveDuration("expiry_sweep_interval", constants.DefaultExpirySweepInterval),
+		ExpiryRetention:     expiryRetention,
+		ExpiryArchivePath:   viper.GetString("expiry_archive_path"),
+
+		TrashPurgeInterval: positiveDuration("trash_purge_interval", constants.DefaultTrashPurgeInterval),
+		TrashRetention:     trashRetention,
+
+		UnlockTokenSecret:   viper.GetString("unlock_token_secret"),
+		UnlockTokenTTL:      positiveDuration("unlock_token_ttl", constants.DefaultUnlockTokenTTL),
+		UnlockMaxAttempts:   unlockMaxAttempts,
+		UnlockAttemptWindow: positiveDuration("unlock_attempt_window", constants.DefaultUnlockWindow),
+
+		BatchMaxSize:     batchMaxSize,
+		BatchConcurrency: batchConcurrency,
+
+		KeyPoolEnabled:  viper.GetBool("key_pool_enabled"),
+		KeyPoolSize:     keyPoolSize,
+		KeyPoolLowWater: keyPoolLowWater,
+	}
+}
+
+// positiveDuration reads a duration setting, falling back to def when it is
+// missing or not positive.
+func positiveDuration(key string, def time.Duration) time.Duration {
+	d := viper.GetDuration(key)
+	if d <= 0 {
+		log.Printf("Invalid %s %q, using %s", key, viper.GetString(key), def)
+		return def
+	}
+	return d
+}
+
+// SQLDataSource returns the data source for the configured SQL driver: the
+// file path for SQLite and the connection URL for PostgreSQL.
+func (c *Config) SQLDataSource() string {
+	if c.StorageDriver == constants.StorageDriverSQLite {
+		return c.SQLitePath
 	}
+	return c.DatabaseURL
 }
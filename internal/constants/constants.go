@@ -4,13 +4,110 @@ import "time"
 
 const (
 	ShortURLsCollection    = "short_urls"
+	HistoryCollection      = "short_url_history"
+	KeysCollection         = "short_code_keys"
+	SequenceCollection     = "short_code_blocks"
 	DefaultPageSize        = 30
+	MaxPageSize            = 200
+	MaxTTLSeconds          = 10 * 365 * 24 * 60 * 60
 	DefaultShortCodeLength = 6
 	MaxRetries             = 5
 	RequestTimeout         = 30 * time.Second
 	MaxURLLength           = 2048
+	DefaultRedirectStatus  = 302
+	DefaultFlushInterval   = 5 * time.Second
+	ShutdownTimeout        = 15 * time.Second
+	DefaultClickQueueSize  = 10000
+	DefaultClickWorkers    = 4
+	DefaultCacheSize       = 10000
+	DefaultCacheTTL        = 5 * time.Minute
+	DefaultNegativeTTL     = 30 * time.Second
+	DefaultRedisTTL        = 10 * time.Minute
+	DefaultRedisKeyPrefix  = "shortener:"
 )
 
+const (
+	DefaultExpirySweepInterval = time.Minute
+	DefaultExpiryRetention     = 24 * time.Hour
+)
+
+const (
+	DefaultTrashPurgeInterval = time.Hour
+	DefaultTrashRetention     = 30 * 24 * time.Hour
+)
+
+const MaxDisabledReasonLength = 500
+
+// Key pool defaults. MaxKeyPoolSize bounds how many codes one refill
+// reserves in a single storage round trip.
+const (
+	DefaultKeyPoolSize     = 500
+	DefaultKeyPoolLowWater = 100
+	MaxKeyPoolSize         = 5000
+)
+
+// SequenceBlockSize is how many numbers the counter-based code generators
+// reserve in storage at a time.
+const SequenceBlockSize = 100
+
+// Bounds on short codes, custom or generated.
+const (
+	MinShortCodeLength = 4
+	MaxShortCodeLength = 20
+)
+
+// ReservedShortCodes are the top-level paths of the server's own routes. A
+// link with one of these codes could never be followed.
+var ReservedShortCodes = []string{"api", "health"}
+
+// DefaultDebugAddr keeps runtime metrics off the public listener.
+const DefaultDebugAddr = "127.0.0.1:6060"
+
+const (
+	DefaultBatchMaxSize     = 100
+	DefaultBatchConcurrency = 8
+)
+
+// Limits on the free-form metadata of a link. MaxMetadataSize bounds the
+// encoded JSON and comfortably fits the other limits.
+const (
+	MaxMetadataEntries     = 32
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 512
+	MaxMetadataSize        = 32 << 10
+)
+
+const (
+	MinPasswordLength        = 4
+	MaxPasswordLength        = 72 // bcrypt ignores anything longer
+	DefaultUnlockTokenTTL    = 30 * time.Minute
+	DefaultUnlockMaxAttempts = 5
+	DefaultUnlockWindow      = 15 * time.Minute
+)
+
+const (
+	DefaultRetryBaseDelay          = 100 * time.Millisecond
+	DefaultRetryMaxDelay           = 2 * time.Second
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerOpenTimeout      = 30 * time.Second
+)
+
+const (
+	StorageDriverPocketBase = "pocketbase"
+	StorageDriverMemory     = "memory"
+	StorageDriverSQLite     = "sqlite"
+	StorageDriverPostgres   = "postgres"
+	DefaultSQLitePath       = "data/shortener.db"
+)
+
+// Responses to links whose activation window has not started.
+const (
+	NotYetActivePage     = "page"
+	NotYetActiveNotFound = "not_found"
+)
+
+var RedirectStatusCodes = []int{301, 302, 307, 308}
+
 var BlockedDomains = []string{
 	"malware.com",
 	"phishing.com",
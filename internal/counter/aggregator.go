@@ -0,0 +1,135 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/clicks"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Store persists accumulated access count deltas. repository.URLRepository
+// satisfies it.
+type Store interface {
+	IncrementAccessCount(ctx context.Context, shortCode string, delta int64) error
+}
+
+// Aggregator batches access count increments per short code in memory and
+// flushes them to the Store as deltas, on an interval and on Stop. Because
+// each flush only ever adds to the stored value, concurrent resolves never
+// overwrite each other's clicks.
+type Aggregator struct {
+	store    Store
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]int64
+
+	flushMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewAggregator(store Store, interval time.Duration) *Aggregator {
+	return &Aggregator{
+		store:    store,
+		interval: interval,
+		pending:  make(map[string]int64),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Increment records one access to shortCode. It never blocks on storage.
+func (a *Aggregator) Increment(shortCode string) {
+	a.mu.Lock()
+	a.pending[shortCode]++
+	a.mu.Unlock()
+}
+
+// RecordClick lets the Aggregator act as the sink of a clicks.Queue.
+func (a *Aggregator) RecordClick(ctx context.Context, event clicks.Event) error {
+	a.Increment(event.ShortCode)
+	return nil
+}
+
+// Pending returns the number of accesses to shortCode not yet flushed.
+func (a *Aggregator) Pending(shortCode string) int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.pending[shortCode]
+}
+
+// Start runs the periodic flush loop until Stop is called.
+func (a *Aggregator) Start() {
+	go func() {
+		defer close(a.done)
+
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := a.Flush(context.Background()); err != nil {
+					log.Warn().Err(err).Msg("Failed to flush access counts, will retry")
+				}
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the flush loop and writes out everything still pending.
+func (a *Aggregator) Stop(ctx context.Context) error {
+	close(a.stop)
+	select {
+	case <-a.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return a.Flush(ctx)
+}
+
+// Flush writes all pending deltas to the store. Deltas that fail to persist
+// are put back and retried on the next flush, except for codes that no longer
+// exist.
+func (a *Aggregator) Flush(ctx context.Context) error {
+	a.flushMu.Lock()
+	defer a.flushMu.Unlock()
+
+	a.mu.Lock()
+	batch := a.pending
+	a.pending = make(map[string]int64, len(batch))
+	a.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for shortCode, delta := range batch {
+		err := a.store.IncrementAccessCount(ctx, shortCode, delta)
+		if err == nil {
+			continue
+		}
+
+		var serviceErr *serviceErrors.ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeNotFound {
+			log.Debug().Str("short_code", shortCode).Int64("delta", delta).Msg("Dropping access count for missing short URL")
+			continue
+		}
+
+		a.mu.Lock()
+		a.pending[shortCode] += delta
+		a.mu.Unlock()
+		errs = append(errs, err)
+	}
+
+	log.Debug().Int("codes", len(batch)).Int("failed", len(errs)).Msg("Flushed access counts")
+	return errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+)
+
+type fakeStore struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	fail   error
+	calls  int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{counts: make(map[string]int64)}
+}
+
+func (s *fakeStore) IncrementAccessCount(ctx context.Context, shortCode string, delta int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if shortCode == "gone" {
+		return serviceErrors.NewNotFoundError("fakeStore", "short URL not found")
+	}
+	if s.fail != nil {
+		return s.fail
+	}
+	s.counts[shortCode] += delta
+	return nil
+}
+
+func (s *fakeStore) count(shortCode string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[shortCode]
+}
+
+func TestAggregatorFlushSendsDeltas(t *testing.T) {
+	store := newFakeStore()
+	a := NewAggregator(store, time.Hour)
+
+	for i := 0; i < 5; i++ {
+		a.Increment("abc")
+	}
+	a.Increment("xyz")
+
+	if got := a.Pending("abc"); got != 5 {
+		t.Errorf("Pending() = %d, want 5", got)
+	}
+
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if store.count("abc") != 5 || store.count("xyz") != 1 {
+		t.Errorf("store counts = %v, want abc=5 xyz=1", store.counts)
+	}
+	if store.calls != 2 {
+		t.Errorf("store calls = %d, want one per code", store.calls)
+	}
+	if got := a.Pending("abc"); got != 0 {
+		t.Errorf("Pending() after flush = %d, want 0", got)
+	}
+}
+
+func TestAggregatorRequeuesFailedDeltas(t *testing.T) {
+	store := newFakeStore()
+	store.fail = errors.New("pocketbase unavailable")
+	a := NewAggregator(store, time.Hour)
+
+	a.Increment("abc")
+	a.Increment("abc")
+	a.Increment("gone")
+
+	if err := a.Flush(context.Background()); err == nil {
+		t.Fatal("Flush() expected error")
+	}
+	if got := a.Pending("abc"); got != 2 {
+		t.Errorf("Pending() after failed flush = %d, want 2", got)
+	}
+	if got := a.Pending("gone"); got != 0 {
+		t.Errorf("Pending() for missing code = %d, want dropped", got)
+	}
+
+	store.fail = nil
+	a.Increment("abc")
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := store.count("abc"); got != 3 {
+		t.Errorf("store count = %d, want 3", got)
+	}
+}
+
+func TestAggregatorStopFlushesPending(t *testing.T) {
+	store := newFakeStore()
+	a := NewAggregator(store, time.Hour)
+	a.Start()
+
+	a.Increment("abc")
+	if err := a.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if got := store.count("abc"); got != 1 {
+		t.Errorf("store count after Stop() = %d, want 1", got)
+	}
+}
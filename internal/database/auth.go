@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// SuperusersCollection is the PocketBase auth collection for superusers.
+	SuperusersCollection = "_superusers"
+
+	// tokenRefreshLeeway renews tokens this long before they expire so that
+	// in-flight requests do not race the expiry.
+	tokenRefreshLeeway = time.Minute
+)
+
+// Credentials sign in to a PocketBase auth collection with a password. The
+// collection defaults to superusers; any other auth collection acts as a
+// service account.
+type Credentials struct {
+	Collection string
+	Identity   string
+	Password   string
+}
+
+func (c Credentials) IsZero() bool {
+	return c.Identity == "" || c.Password == ""
+}
+
+func (c Credentials) collection() string {
+	if c.Collection == "" {
+		return SuperusersCollection
+	}
+	return c.Collection
+}
+
+// String identifies the account without the password so credentials are
+// safe to log or format.
+func (c Credentials) String() string {
+	return c.collection() + "/" + c.Identity
+}
+
+func (c Credentials) GoString() string {
+	return fmt.Sprintf("database.Credentials{Collection:%q, Identity:%q, Password:\"***\"}", c.collection(), c.Identity)
+}
+
+// AuthWithPassword exchanges credentials for an auth token and returns it
+// with its expiry, which is zero when the token does not carry one.
+func (pb *PBClient) AuthWithPassword(ctx context.Context, creds Credentials) (string, time.Time, error) {
+	var resp struct {
+		Token string `json:"token"`
+	}
+	body := map[string]string{"identity": creds.Identity, "password": creds.Password}
+	path := "/api/collections/" + creds.collection() + "/auth-with-password"
+
+	// Signing in must not go through the token transport itself.
+	client := pb
+	if pb.auth != nil {
+		client = pb.auth.authClient
+	}
+	if err := client.doJSON(ctx, http.MethodPost, path, "", body, &resp); err != nil {
+		return "", time.Time{}, fmt.Errorf("authenticate %s: %w", creds, err)
+	}
+	if resp.Token == "" {
+		return "", time.Time{}, fmt.Errorf("authenticate %s: empty token", creds)
+	}
+	return resp.Token, tokenExpiry(resp.Token), nil
+}
+
+// UseAuth makes every request sent through pb.HTTPClient carry a token for
+// creds. The token is obtained lazily, renewed shortly before it expires,
+// and renewed once more if PocketBase answers 401.
+func (pb *PBClient) UseAuth(creds Credentials) {
+	client := *pb.HTTPClient
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	pb.auth = &authTransport{
+		base:       base,
+		creds:      creds,
+		authClient: &PBClient{BaseURL: pb.BaseURL, HTTPClient: &http.Client{Transport: base, Timeout: client.Timeout}},
+		now:        time.Now,
+	}
+	client.Transport = pb.auth
+	pb.HTTPClient = &client
+}
+
+// Authenticate signs in immediately so bad credentials surface at startup
+// instead of on the first request. It is a no-op without UseAuth.
+func (pb *PBClient) Authenticate(ctx context.Context) error {
+	if pb.auth == nil {
+		return nil
+	}
+	_, err := pb.auth.token(ctx, "")
+	return err
+}
+
+// accessRule is the API rule for collections the service provisions: open
+// for anonymous clients, superuser-only (nil) when signed in as a superuser,
+// and limited to the service account's collection otherwise.
+func (pb *PBClient) accessRule() *string {
+	if pb.auth == nil {
+		rule := ""
+		return &rule
+	}
+	if pb.auth.creds.collection() == SuperusersCollection {
+		return nil
+	}
+	rule := fmt.Sprintf("@request.auth.collectionName = %q", pb.auth.creds.collection())
+	return &rule
+}
+
+type authTransport struct {
+	base       http.RoundTripper
+	creds      Credentials
+	authClient *PBClient
+	now        func() time.Time
+
+	mu        sync.Mutex
+	current   string
+	expiresAt time.Time
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests that carry their own token, such as provisioning calls made
+	// with a superuser token, are passed through untouched.
+	if req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	token, err := t.token(req.Context(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(withToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	// The token was rejected before its expiry, e.g. after a password change
+	// or server restart. Sign in again and retry once.
+	fresh, err := t.token(req.Context(), token)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	retry := withToken(req, fresh)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		retry.Body = body
+	}
+	resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
+
+// token returns a valid token, signing in again when there is none, when it
+// is about to expire, or when it is the stale token a request just had
+// rejected.
+func (t *authTransport) token(ctx context.Context, stale string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current != "" && t.current != stale &&
+		(t.expiresAt.IsZero() || t.now().Add(tokenRefreshLeeway).Before(t.expiresAt)) {
+		return t.current, nil
+	}
+
+	token, expiresAt, err := t.authClient.AuthWithPassword(ctx, t.creds)
+	if err != nil {
+		t.current = ""
+		return "", err
+	}
+
+	t.current, t.expiresAt = token, expiresAt
+	log.Debug().Stringer("account", t.creds).Time("expires_at", expiresAt).Msg("Obtained PocketBase auth token")
+	return token, nil
+}
+
+func withToken(req *http.Request, token string) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Authorization", token)
+	return clone
+}
+
+// tokenExpiry reads the exp claim of a PocketBase JWT without verifying it.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
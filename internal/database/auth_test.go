@@ -0,0 +1,221 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	fakeServiceIdentity = "shortener@example.com"
+	fakeServicePassword = "service-account-password"
+)
+
+// fakeAuthAPI issues JWT-shaped tokens and accepts only the latest one on
+// its records endpoint.
+type fakeAuthAPI struct {
+	mu        sync.Mutex
+	issued    int
+	valid     string
+	expiresAt time.Time
+	bodies    []string
+}
+
+func newFakeAuthAPI(t *testing.T) (*fakeAuthAPI, *PBClient) {
+	t.Helper()
+
+	api := &fakeAuthAPI{expiresAt: time.Now().Add(time.Hour)}
+	server := httptest.NewServer(http.HandlerFunc(api.serveHTTP))
+	t.Cleanup(server.Close)
+
+	return api, &PBClient{BaseURL: server.URL, HTTPClient: server.Client()}
+}
+
+func (f *fakeAuthAPI) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Method == http.MethodPost && r.URL.Path == "/api/collections/service_accounts/auth-with-password" {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["identity"] != fakeServiceIdentity || body["password"] != fakeServicePassword {
+			writeFakeAPIError(w, http.StatusBadRequest, "Failed to authenticate.")
+			return
+		}
+		f.issued++
+		f.valid = fakeJWT(f.issued, f.expiresAt)
+		writeFakeAPIJSON(w, map[string]string{"token": f.valid})
+		return
+	}
+
+	if r.Header.Get("Authorization") != f.valid {
+		writeFakeAPIError(w, http.StatusUnauthorized, "The request requires valid record authorization token.")
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.bodies = append(f.bodies, string(body))
+	writeFakeAPIJSON(w, map[string]any{"items": []any{}})
+}
+
+// revoke invalidates the current token, as a PocketBase restart with a new
+// token secret would.
+func (f *fakeAuthAPI) revoke() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.valid = "revoked"
+}
+
+func fakeJWT(n int, exp time.Time) string {
+	enc := base64.RawURLEncoding
+	payload := fmt.Sprintf(`{"id":"svc","type":"auth","exp":%d,"n":%d}`, exp.Unix(), n)
+	return enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc.EncodeToString([]byte(payload)) + ".sig"
+}
+
+var serviceCredentials = Credentials{Collection: "service_accounts", Identity: fakeServiceIdentity, Password: fakeServicePassword}
+
+func sendRecordsRequest(t *testing.T, pb *PBClient, body string) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	method := http.MethodGet
+	if body != "" {
+		method, reader = http.MethodPost, strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, pb.BaseURL+"/api/collections/short_urls/records", reader)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	resp, err := pb.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestUseAuthAttachesToken(t *testing.T) {
+	api, pb := newFakeAuthAPI(t)
+	pb.UseAuth(serviceCredentials)
+
+	for i := 0; i < 5; i++ {
+		if resp := sendRecordsRequest(t, pb, ""); resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want 200", resp.StatusCode)
+		}
+	}
+	if api.issued != 1 {
+		t.Errorf("tokens issued = %d, want 1", api.issued)
+	}
+}
+
+func TestUseAuthRefreshesBeforeExpiry(t *testing.T) {
+	api, pb := newFakeAuthAPI(t)
+	pb.UseAuth(serviceCredentials)
+
+	sendRecordsRequest(t, pb, "")
+
+	// Move the clock to within the refresh leeway of the token's expiry.
+	pb.auth.now = func() time.Time { return api.expiresAt.Add(-tokenRefreshLeeway / 2) }
+	if resp := sendRecordsRequest(t, pb, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if api.issued != 2 {
+		t.Errorf("tokens issued = %d, want 2", api.issued)
+	}
+}
+
+func TestUseAuthRetriesOnceAfterUnauthorized(t *testing.T) {
+	api, pb := newFakeAuthAPI(t)
+	pb.UseAuth(serviceCredentials)
+
+	sendRecordsRequest(t, pb, "")
+	api.revoke()
+
+	if resp := sendRecordsRequest(t, pb, `{"url":"https://example.com"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if api.issued != 2 {
+		t.Errorf("tokens issued = %d, want 2", api.issued)
+	}
+	if last := api.bodies[len(api.bodies)-1]; last != `{"url":"https://example.com"}` {
+		t.Errorf("retried body = %q, want original body replayed", last)
+	}
+}
+
+func TestUseAuthConcurrentRequestsShareToken(t *testing.T) {
+	api, pb := newFakeAuthAPI(t)
+	pb.UseAuth(serviceCredentials)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, pb.BaseURL+"/api/collections/short_urls/records", nil)
+			resp, err := pb.HTTPClient.Do(req)
+			if err != nil {
+				t.Errorf("Do() error = %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if api.issued != 1 {
+		t.Errorf("tokens issued = %d, want 1", api.issued)
+	}
+}
+
+func TestAuthenticateNeverLogsSecrets(t *testing.T) {
+	var logs bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&logs).Level(zerolog.DebugLevel)
+	t.Cleanup(func() { log.Logger = previous })
+
+	api, pb := newFakeAuthAPI(t)
+	pb.UseAuth(serviceCredentials)
+	if err := pb.Authenticate(context.Background()); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	wrong := serviceCredentials
+	wrong.Password = "not-the-password"
+	_, bad := newFakeAuthAPI(t)
+	bad.UseAuth(wrong)
+	err := bad.Authenticate(context.Background())
+	if err == nil {
+		t.Fatal("Authenticate() with a wrong password error = nil")
+	}
+
+	formatted := fmt.Sprintf("%v %+v %#v %s", wrong, wrong, wrong, err)
+	for _, secret := range []string{fakeServicePassword, "not-the-password", api.valid} {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("logs contain secret %q: %s", secret, logs.String())
+		}
+		if strings.Contains(formatted, secret) {
+			t.Errorf("formatted credentials contain secret %q: %s", secret, formatted)
+		}
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	exp := time.Unix(1_900_000_000, 0)
+	if got := tokenExpiry(fakeJWT(1, exp)); !got.Equal(exp) {
+		t.Errorf("tokenExpiry() = %v, want %v", got, exp)
+	}
+	if got := tokenExpiry("opaque-token"); !got.IsZero() {
+		t.Errorf("tokenExpiry(opaque) = %v, want zero", got)
+	}
+}
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rs/zerolog/log"
+)
+
+// CollectionField is a field definition in the PocketBase collections API.
+type CollectionField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+	Max      int    `json:"max,omitempty"`
+	MaxSize  int    `json:"maxSize,omitempty"`
+	OnlyInt  bool   `json:"onlyInt,omitempty"`
+	OnCreate bool   `json:"onCreate,omitempty"`
+	OnUpdate bool   `json:"onUpdate,omitempty"`
+}
+
+// ShortURLFields are the fields the repository reads and writes on the
+// short_urls collection.
+var ShortURLFields = []CollectionField{
+	{Name: "url", Type: "text", Required: true, Max: constants.MaxURLLength},
+	{Name: "short_code", Type: "text", Required: true, Max: 64},
+	{Name: "access_count", Type: "number", OnlyInt: true},
+	{Name: "redirect_type", Type: "number", OnlyInt: true},
+	{Name: "expires_at", Type: "date"},
+	{Name: "max_clicks", Type: "number", OnlyInt: true},
+	{Name: "fallback_url", Type: "text", Max: constants.MaxURLLength},
+	{Name: "password_hash", Type: "text", Max: 255},
+	{Name: "active_from", Type: "date"},
+	{Name: "active_until", Type: "date"},
+	{Name: "disabled", Type: "bool"},
+	{Name: "disabled_reason", Type: "text", Max: 500},
+	{Name: "disabled_legal", Type: "bool"},
+	{Name: "deleted_at", Type: "date"},
+	{Name: "metadata", Type: "json", MaxSize: constants.MaxMetadataSize},
+	{Name: "created", Type: "autodate", OnCreate: true},
+	{Name: "updated", Type: "autodate", OnCreate: true, OnUpdate: true},
+}
+
+// HistoryFields are the fields of the short_url_history collection, which
+// keeps every destination of a short URL.
+var HistoryFields = []CollectionField{
+	{Name: "url_id", Type: "text", Required: true, Max: 64},
+	{Name: "short_code", Type: "text", Required: true, Max: 64},
+	{Name: "version", Type: "number", Required: true, OnlyInt: true},
+	{Name: "old_url", Type: "text", Max: constants.MaxURLLength},
+	{Name: "new_url", Type: "text", Required: true, Max: constants.MaxURLLength},
+	{Name: "actor", Type: "text", Max: 255},
+	{Name: "changed_at", Type: "date", Required: true},
+}
+
+// KeyFields are the fields of the short_code_keys collection, which records
+// every code the key pool has reserved.
+var KeyFields = []CollectionField{
+	{Name: "short_code", Type: "text", Required: true, Max: 64},
+	{Name: "created", Type: "autodate", OnCreate: true},
+}
+
+// SequenceFields are the fields of the short_code_blocks collection, which
+// records every block of numbers the counter-based code generators took.
+// first is not required because PocketBase rejects a required 0.
+var SequenceFields = []CollectionField{
+	{Name: "first", Type: "number", OnlyInt: true},
+	{Name: "next", Type: "number", Required: true, OnlyInt: true},
+	{Name: "created", Type: "autodate", OnCreate: true},
+}
+
+var shortCodeIndex = fmt.Sprintf("CREATE UNIQUE INDEX idx_short_urls_short_code ON %s (short_code)", constants.ShortURLsCollection)
+
+var historyVersionIndex = fmt.Sprintf("CREATE UNIQUE INDEX idx_short_url_history_version ON %s (url_id, version)", constants.HistoryCollection)
+
+var keyIndex = fmt.Sprintf("CREATE UNIQUE INDEX idx_short_code_keys_short_code ON %s (short_code)", constants.KeysCollection)
+
+var sequenceIndex = fmt.Sprintf("CREATE UNIQUE INDEX idx_short_code_blocks_first ON %s (first)", constants.SequenceCollection)
+
+// collectionSpec describes a collection the repositories depend on and the
+// unique index they rely on.
+type collectionSpec struct {
+	name        string
+	fields      []CollectionField
+	index       string
+	indexLabel  string
+	hasIndex    func(indexes []string) bool
+	description string
+}
+
+var shortURLsSpec = collectionSpec{
+	name:        constants.ShortURLsCollection,
+	fields:      ShortURLFields,
+	index:       shortCodeIndex,
+	indexLabel:  "unique index on short_code",
+	hasIndex:    hasUniqueShortCodeIndex,
+	description: "url (text, required), short_code (text, required, unique), access_count (number, default: 0), redirect_type (number), expires_at (date), max_clicks (number), fallback_url (text), password_hash (text), active_from (date), active_until (date), disabled (bool), disabled_reason (text), disabled_legal (bool), deleted_at (date), metadata (json)",
+}
+
+var historySpec = collectionSpec{
+	name:        constants.HistoryCollection,
+	fields:      HistoryFields,
+	index:       historyVersionIndex,
+	indexLabel:  "unique index on (url_id, version)",
+	hasIndex:    func(indexes []string) bool { return hasUniqueIndex(indexes, "(URL_ID, VERSION)") },
+	description: "url_id (text, required), short_code (text, required), version (number, required), old_url (text), new_url (text, required), actor (text), changed_at (date, required), unique on (url_id, version)",
+}
+
+var keysSpec = collectionSpec{
+	name:        constants.KeysCollection,
+	fields:      KeyFields,
+	index:       keyIndex,
+	indexLabel:  "unique index on short_code",
+	hasIndex:    hasUniqueShortCodeIndex,
+	description: "short_code (text, required, unique)",
+}
+
+var sequenceSpec = collectionSpec{
+	name:        constants.SequenceCollection,
+	fields:      SequenceFields,
+	index:       sequenceIndex,
+	indexLabel:  "unique index on first",
+	hasIndex:    func(indexes []string) bool { return hasUniqueIndex(indexes, "(FIRST)") },
+	description: "first (number, unique), next (number, required)",
+}
+
+type collection struct {
+	ID      string            `json:"id,omitempty"`
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	Fields  []json.RawMessage `json:"fields"`
+	Indexes []string          `json:"indexes"`
+
+	ListRule   *string `json:"listRule"`
+	ViewRule   *string `json:"viewRule"`
+	CreateRule *string `json:"createRule"`
+	UpdateRule *string `json:"updateRule"`
+	DeleteRule *string `json:"deleteRule"`
+}
+
+// CreateCollection provisions the short_urls collection as a superuser. A
+// missing collection is created with ShortURLFields and a unique index on
+// short_code; an existing one gains whichever of them it lacks. Without
+// credentials it only logs how to create the collection by hand.
+func (pb *PBClient) CreateCollection(ctx context.Context, creds Credentials) error {
+	return pb.provision(ctx, creds, &shortURLsSpec)
+}
+
+// CreateHistoryCollection provisions the short_url_history collection the
+// same way, with HistoryFields and a unique index on (url_id, version).
+func (pb *PBClient) CreateHistoryCollection(ctx context.Context, creds Credentials) error {
+	return pb.provision(ctx, creds, &historySpec)
+}
+
+// CreateKeysCollection provisions the short_code_keys collection the same
+// way, with KeyFields and a unique index on short_code.
+func (pb *PBClient) CreateKeysCollection(ctx context.Context, creds Credentials) error {
+	return pb.provision(ctx, creds, &keysSpec)
+}
+
+// CreateSequenceCollection provisions the short_code_blocks collection the
+// same way, with SequenceFields and a unique index on first.
+func (pb *PBClient) CreateSequenceCollection(ctx context.Context, creds Credentials) error {
+	return pb.provision(ctx, creds, &sequenceSpec)
+}
+
+func (pb *PBClient) provision(ctx context.Context, creds Credentials, spec *collectionSpec) error {
+	if creds.IsZero() {
+		log.Info().Msg("No PocketBase superuser configured; create the collection through the admin UI at " + pb.BaseURL + "/_/")
+		log.Info().Msgf("Create a '%s' collection with fields: %s", spec.name, spec.description)
+		return nil
+	}
+
+	creds.Collection = SuperusersCollection
+	token, _, err := pb.AuthWithPassword(ctx, creds)
+	if err != nil {
+		return err
+	}
+
+	var existing collection
+	err = pb.doJSON(ctx, http.MethodGet, "/api/collections/"+spec.name, token, nil, &existing)
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound:
+		return pb.createCollection(ctx, token, spec)
+	case err != nil:
+		return fmt.Errorf("get collection %s: %w", spec.name, err)
+	}
+
+	return pb.reconcileCollection(ctx, token, spec, &existing)
+}
+
+func (pb *PBClient) createCollection(ctx context.Context, token string, spec *collectionSpec) error {
+	fields := make([]json.RawMessage, 0, len(spec.fields))
+	for _, field := range spec.fields {
+		encoded, err := json.Marshal(field)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, encoded)
+	}
+
+	rule := pb.accessRule()
+	c := collection{
+		Name:       spec.name,
+		Type:       "base",
+		Fields:     fields,
+		Indexes:    []string{spec.index},
+		ListRule:   rule,
+		ViewRule:   rule,
+		CreateRule: rule,
+		UpdateRule: rule,
+		DeleteRule: rule,
+	}
+	if err := pb.doJSON(ctx, http.MethodPost, "/api/collections", token, c, nil); err != nil {
+		return fmt.Errorf("create collection %s: %w", spec.name, err)
+	}
+
+	log.Info().Str("collection", spec.name).Msg("Created PocketBase collection")
+	return nil
+}
+
+func (pb *PBClient) reconcileCollection(ctx context.Context, token string, spec *collectionSpec, existing *collection) error {
+	present := make(map[string]string, len(existing.Fields))
+	for _, raw := range existing.Fields {
+		var field CollectionField
+		if err := json.Unmarshal(raw, &field); err == nil {
+			present[field.Name] = field.Type
+		}
+	}
+
+	// Existing field definitions are sent back untouched so PocketBase keeps
+	// their ids and options.
+	fields := existing.Fields
+	var added []string
+	for _, field := range spec.fields {
+		fieldType, ok := present[field.Name]
+		if !ok {
+			encoded, err := json.Marshal(field)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, encoded)
+			added = append(added, field.Name)
+			continue
+		}
+		if fieldType != field.Type {
+			log.Warn().Str("field", field.Name).Str("type", fieldType).Str("want", field.Type).Msg("PocketBase field has an unexpected type; leaving it unchanged")
+		}
+	}
+
+	indexes := existing.Indexes
+	if !spec.hasIndex(indexes) {
+		indexes = append(indexes, spec.index)
+		added = append(added, spec.indexLabel)
+	}
+
+	if pb.auth != nil && existing.isPublic() {
+		log.Warn().Str("collection", existing.Name).Msg("PocketBase collection is open to anonymous clients; restrict its API rules in the admin UI")
+	}
+
+	if len(added) == 0 {
+		log.Info().Str("collection", existing.Name).Msg("PocketBase collection is up to date")
+		return nil
+	}
+
+	update := map[string]any{"fields": fields, "indexes": indexes}
+	if err := pb.doJSON(ctx, http.MethodPatch, "/api/collections/"+existing.ID, token, update, nil); err != nil {
+		return fmt.Errorf("update collection %s: %w", existing.Name, err)
+	}
+
+	log.Info().Str("collection", existing.Name).Strs("added", added).Msg("Updated PocketBase collection")
+	return nil
+}
+
+func (c *collection) isPublic() bool {
+	for _, rule := range []*string{c.ListRule, c.ViewRule, c.CreateRule, c.UpdateRule, c.DeleteRule} {
+		if rule != nil && *rule == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasUniqueShortCodeIndex(indexes []string) bool {
+	return hasUniqueIndex(indexes, "(SHORT_CODE)")
+}
+
+// hasUniqueIndex reports whether one of indexes is a unique index on
+// columns, given upper case as they appear in the statement.
+func hasUniqueIndex(indexes []string, columns string) bool {
+	for _, index := range indexes {
+		normalized := strings.NewReplacer("`", "", `"`, "", "'", "").Replace(index)
+		normalized = strings.ToUpper(strings.Join(strings.Fields(normalized), " "))
+		if strings.HasPrefix(normalized, "CREATE UNIQUE INDEX") && strings.Contains(normalized, columns) {
+			return true
+		}
+	}
+	return false
+}
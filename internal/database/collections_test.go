@@ -0,0 +1,302 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	fakeSuperuserEmail    = "admin@example.com"
+	fakeSuperuserPassword = "s3cret-pass"
+	fakeSuperuserToken    = "superuser-token"
+)
+
+// fakeCollectionsAPI implements the parts of the PocketBase collections API
+// used for provisioning.
+type fakeCollectionsAPI struct {
+	mu         sync.Mutex
+	collection map[string]any
+	creates    int
+	patches    int
+}
+
+func newFakeCollectionsAPI(t *testing.T, existing map[string]any) (*fakeCollectionsAPI, *PBClient) {
+	t.Helper()
+
+	api := &fakeCollectionsAPI{collection: existing}
+	server := httptest.NewServer(http.HandlerFunc(api.serveHTTP))
+	t.Cleanup(server.Close)
+
+	return api, &PBClient{BaseURL: server.URL, HTTPClient: server.Client()}
+}
+
+func (f *fakeCollectionsAPI) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Method == http.MethodPost && r.URL.Path == "/api/collections/_superusers/auth-with-password" {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["identity"] != fakeSuperuserEmail || body["password"] != fakeSuperuserPassword {
+			writeFakeAPIError(w, http.StatusBadRequest, "Failed to authenticate.")
+			return
+		}
+		writeFakeAPIJSON(w, map[string]string{"token": fakeSuperuserToken})
+		return
+	}
+
+	if r.Header.Get("Authorization") != fakeSuperuserToken {
+		writeFakeAPIError(w, http.StatusUnauthorized, "The request requires valid record authorization token.")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/collections/short_urls":
+		if f.collection == nil {
+			writeFakeAPIError(w, http.StatusNotFound, "Missing collection context.")
+			return
+		}
+		writeFakeAPIJSON(w, f.collection)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/collections":
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		body["id"] = "pbc_1"
+		f.collection = body
+		f.creates++
+		writeFakeAPIJSON(w, body)
+	case r.Method == http.MethodPatch && f.collection != nil && r.URL.Path == "/api/collections/"+f.collection["id"].(string):
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		for key, value := range body {
+			f.collection[key] = value
+		}
+		f.patches++
+		writeFakeAPIJSON(w, f.collection)
+	default:
+		writeFakeAPIError(w, http.StatusNotFound, "The requested resource wasn't found.")
+	}
+}
+
+func (f *fakeCollectionsAPI) fieldNames() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var names []string
+	for _, field := range f.collection["fields"].([]any) {
+		names = append(names, field.(map[string]any)["name"].(string))
+	}
+	return names
+}
+
+func writeFakeAPIJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeFakeAPIError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": status, "message": message, "data": map[string]any{}})
+}
+
+var testCredentials = Credentials{Identity: fakeSuperuserEmail, Password: fakeSuperuserPassword}
+
+func TestCreateCollectionCreatesMissingCollection(t *testing.T) {
+	api, pb := newFakeCollectionsAPI(t, nil)
+
+	if err := pb.CreateCollection(context.Background(), testCredentials); err != nil {
+		t.Fatalf("CreateCollection() error = %v", err)
+	}
+
+	if api.creates != 1 {
+		t.Errorf("creates = %d, want 1", api.creates)
+	}
+	if got := strings.Join(api.fieldNames(), ","); got != "url,short_code,access_count,redirect_type,expires_at,max_clicks,fallback_url,password_hash,active_from,active_until,disabled,disabled_reason,disabled_legal,deleted_at,metadata,created,updated" {
+		t.Errorf("fields = %s", got)
+	}
+	if !hasUniqueShortCodeIndex(toStrings(api.collection["indexes"])) {
+		t.Errorf("indexes = %v, want unique short_code index", api.collection["indexes"])
+	}
+}
+
+func TestCreateCollectionReconcilesMissingFields(t *testing.T) {
+	api, pb := newFakeCollectionsAPI(t, map[string]any{
+		"id":   "pbc_42",
+		"name": "short_urls",
+		"type": "base",
+		"fields": []any{
+			map[string]any{"id": "text1", "name": "url", "type": "text", "required": true},
+			map[string]any{"id": "text2", "name": "short_code", "type": "text", "required": true},
+			map[string]any{"id": "number3", "name": "access_count", "type": "number"},
+		},
+		"indexes": []any{},
+	})
+
+	if err := pb.CreateCollection(context.Background(), testCredentials); err != nil {
+		t.Fatalf("CreateCollection() error = %v", err)
+	}
+
+	if api.creates != 0 || api.patches != 1 {
+		t.Fatalf("creates = %d, patches = %d, want 0 and 1", api.creates, api.patches)
+	}
+	if got := strings.Join(api.fieldNames(), ","); got != "url,short_code,access_count,redirect_type,expires_at,max_clicks,fallback_url,password_hash,active_from,active_until,disabled,disabled_reason,disabled_legal,deleted_at,metadata,created,updated" {
+		t.Errorf("fields = %s", got)
+	}
+	// Existing definitions keep their ids.
+	if first := api.collection["fields"].([]any)[0].(map[string]any); first["id"] != "text1" {
+		t.Errorf("first field = %v, want id text1 preserved", first)
+	}
+	if !hasUniqueShortCodeIndex(toStrings(api.collection["indexes"])) {
+		t.Errorf("indexes = %v, want unique short_code index", api.collection["indexes"])
+	}
+
+	// A second run finds nothing to change.
+	if err := pb.CreateCollection(context.Background(), testCredentials); err != nil {
+		t.Fatalf("CreateCollection() second run error = %v", err)
+	}
+	if api.patches != 1 {
+		t.Errorf("patches after second run = %d, want 1", api.patches)
+	}
+}
+
+func TestCreateHistoryCollection(t *testing.T) {
+	api, pb := newFakeCollectionsAPI(t, nil)
+
+	if err := pb.CreateHistoryCollection(context.Background(), testCredentials); err != nil {
+		t.Fatalf("CreateHistoryCollection() error = %v", err)
+	}
+
+	if api.creates != 1 || api.collection["name"] != "short_url_history" {
+		t.Fatalf("creates = %d, name = %v, want the history collection created", api.creates, api.collection["name"])
+	}
+	if got := strings.Join(api.fieldNames(), ","); got != "url_id,short_code,version,old_url,new_url,actor,changed_at" {
+		t.Errorf("fields = %s", got)
+	}
+	if !hasUniqueIndex(toStrings(api.collection["indexes"]), "(URL_ID, VERSION)") {
+		t.Errorf("indexes = %v, want unique (url_id, version) index", api.collection["indexes"])
+	}
+}
+
+func TestCreateKeysCollection(t *testing.T) {
+	api, pb := newFakeCollectionsAPI(t, nil)
+
+	if err := pb.CreateKeysCollection(context.Background(), testCredentials); err != nil {
+		t.Fatalf("CreateKeysCollection() error = %v", err)
+	}
+
+	if api.creates != 1 || api.collection["name"] != "short_code_keys" {
+		t.Fatalf("creates = %d, name = %v, want the keys collection created", api.creates, api.collection["name"])
+	}
+	if got := strings.Join(api.fieldNames(), ","); got != "short_code,created" {
+		t.Errorf("fields = %s", got)
+	}
+	if !hasUniqueShortCodeIndex(toStrings(api.collection["indexes"])) {
+		t.Errorf("indexes = %v, want unique short_code index", api.collection["indexes"])
+	}
+}
+
+func TestCreateSequenceCollection(t *testing.T) {
+	api, pb := newFakeCollectionsAPI(t, nil)
+
+	if err := pb.CreateSequenceCollection(context.Background(), testCredentials); err != nil {
+		t.Fatalf("CreateSequenceCollection() error = %v", err)
+	}
+
+	if api.creates != 1 || api.collection["name"] != "short_code_blocks" {
+		t.Fatalf("creates = %d, name = %v, want the sequence collection created", api.creates, api.collection["name"])
+	}
+	if got := strings.Join(api.fieldNames(), ","); got != "first,next,created" {
+		t.Errorf("fields = %s", got)
+	}
+	if !hasUniqueIndex(toStrings(api.collection["indexes"]), "(FIRST)") {
+		t.Errorf("indexes = %v, want unique first index", api.collection["indexes"])
+	}
+}
+
+func TestCreateCollectionRejectsBadCredentials(t *testing.T) {
+	api, pb := newFakeCollectionsAPI(t, nil)
+
+	err := pb.CreateCollection(context.Background(), Credentials{Identity: fakeSuperuserEmail, Password: "wrong-password"})
+	if err == nil {
+		t.Fatal("CreateCollection() error = nil, want authentication error")
+	}
+	if strings.Contains(err.Error(), "wrong-password") {
+		t.Errorf("error %q leaks the password", err)
+	}
+	if api.creates != 0 {
+		t.Errorf("creates = %d, want 0", api.creates)
+	}
+}
+
+func TestCreateCollectionWithoutCredentialsIsNoop(t *testing.T) {
+	api, pb := newFakeCollectionsAPI(t, nil)
+
+	if err := pb.CreateCollection(context.Background(), Credentials{}); err != nil {
+		t.Fatalf("CreateCollection() error = %v", err)
+	}
+	if api.creates != 0 {
+		t.Errorf("creates = %d, want 0", api.creates)
+	}
+}
+
+func TestHasUniqueShortCodeIndex(t *testing.T) {
+	tests := []struct {
+		index string
+		want  bool
+	}{
+		{"CREATE UNIQUE INDEX idx_short_urls_short_code ON short_urls (short_code)", true},
+		{"CREATE UNIQUE INDEX `idx_abc` ON `short_urls` (`short_code`)", true},
+		{"create unique index idx on short_urls (short_code)", true},
+		{"CREATE INDEX idx_short_code ON short_urls (short_code)", false},
+		{"CREATE UNIQUE INDEX idx_url ON short_urls (url)", false},
+	}
+
+	for _, tt := range tests {
+		if got := hasUniqueShortCodeIndex([]string{tt.index}); got != tt.want {
+			t.Errorf("hasUniqueShortCodeIndex(%q) = %v, want %v", tt.index, got, tt.want)
+		}
+	}
+}
+
+func toStrings(values any) []string {
+	var out []string
+	for _, v := range values.([]any) {
+		out = append(out, v.(string))
+	}
+	return out
+}
+
+func TestCreateCollectionLocksRulesWhenAuthenticated(t *testing.T) {
+	tests := []struct {
+		name  string
+		creds *Credentials
+		want  any
+	}{
+		{"anonymous client", nil, ""},
+		{"superuser client", &Credentials{Identity: fakeSuperuserEmail, Password: fakeSuperuserPassword}, nil},
+		{"service account", &Credentials{Collection: "service_accounts", Identity: "svc", Password: "pw"}, `@request.auth.collectionName = "service_accounts"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, pb := newFakeCollectionsAPI(t, nil)
+			if tt.creds != nil {
+				pb.UseAuth(*tt.creds)
+			}
+
+			if err := pb.CreateCollection(context.Background(), testCredentials); err != nil {
+				t.Fatalf("CreateCollection() error = %v", err)
+			}
+			for _, rule := range []string{"listRule", "viewRule", "createRule", "updateRule", "deleteRule"} {
+				if got := api.collection[rule]; got != tt.want {
+					t.Errorf("%s = %#v, want %#v", rule, got, tt.want)
+				}
+			}
+		})
+	}
+}
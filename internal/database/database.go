@@ -8,6 +8,9 @@ import (
 type PBClient struct {
 	BaseURL    string
 	HTTPClient *http.Client
+
+	auth    *authTransport
+	breaker *CircuitBreaker
 }
 
 func Initialize(pocketbaseURL string) (*PBClient, error) {
@@ -36,9 +39,3 @@ func (pb *PBClient) StartServer() error {
 	log.Info().Msg("PocketBase should be started separately with: ./pocketbase serve")
 	return nil
 }
-
-func (pb *PBClient) CreateCollection() error {
-	log.Info().Msg("Collection should be created through PocketBase admin UI at http://localhost:8090/_/")
-	log.Info().Msg("Create a 'short_urls' collection with fields: url (text, required), short_code (text, required, unique), access_count (number, default: 0)")
-	return nil
-}
@@ -0,0 +1,289 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+// migrationLockKey is the PostgreSQL advisory lock that serialises
+// migrations. The value is arbitrary but must never change.
+const migrationLockKey = 4907031155
+
+// Migration is one versioned schema change. Files are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a known migration has been applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// LoadMigrations reads all migrations in dir, ordered by version.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		direction := path.Ext(base)
+		if direction != ".up" && direction != ".down" {
+			return nil, fmt.Errorf("migration %s: missing .up or .down suffix", entry.Name())
+		}
+		base = strings.TrimSuffix(base, direction)
+
+		versionPart, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name>", entry.Name())
+		}
+		version, err := strconv.Atoi(versionPart)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", entry.Name(), versionPart)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", entry.Name(), err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d used by both %q and %q", version, m.Name, name)
+		}
+
+		if direction == ".up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies the embedded migrations for one SQL dialect and records
+// progress in the schema_migrations table.
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for dialect, which must match a directory
+// under migrations/ ("sqlite" or "postgres").
+func NewMigrator(db *sql.DB, dialect string) (*Migrator, error) {
+	migrations, err := LoadMigrations(migrationsFS, path.Join("migrations", dialect))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// lock keeps other processes from migrating the same database until the
+// returned function is called. On PostgreSQL it holds an advisory lock on a
+// dedicated connection, so replicas starting with auto_migrate wait for
+// each other instead of applying the same migration twice. SQLite is only
+// opened by a single process and needs no lock.
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	if m.dialect != constants.StorageDriverPostgres {
+		return func() {}, nil
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lock migrations: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("lock migrations: %w", err)
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			log.Error().Err(err).Msg("Failed to release the migration lock")
+		}
+		conn.Close()
+	}, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt any
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		applied[version] = parseAppliedAt(appliedAt)
+	}
+	return applied, rows.Err()
+}
+
+// Up applies every pending migration in order and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		log.Info().Int("version", migration.Version).Str("name", migration.Name).Msg("Applying migration")
+		record := fmt.Sprintf("INSERT INTO schema_migrations (version) VALUES (%d)", migration.Version)
+		if err := m.run(ctx, migration.Up, record); err != nil {
+			return count, fmt.Errorf("migration %04d_%s up: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down reverts the most recently applied migrations, at most steps of them.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return count, fmt.Errorf("migration %04d_%s has no down script", migration.Version, migration.Name)
+		}
+
+		log.Info().Int("version", migration.Version).Str("name", migration.Name).Msg("Reverting migration")
+		record := fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %d", migration.Version)
+		if err := m.run(ctx, migration.Down, record); err != nil {
+			return count, fmt.Errorf("migration %04d_%s down: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Status lists all known migrations along with when they were applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) run(ctx context.Context, script, record string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// parseAppliedAt normalises applied_at, which SQLite hands back as text.
+func parseAppliedAt(value any) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case string:
+		t, _ := time.Parse(time.DateTime, v)
+		return t
+	case []byte:
+		t, _ := time.Parse(time.DateTime, string(v))
+		return t
+	default:
+		return time.Time{}
+	}
+}
@@ -0,0 +1,55 @@
+//go:build postgres
+
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+)
+
+// TestMigratorLockPostgres runs against the database in TEST_POSTGRES_DSN,
+// e.g. the one started by "task db:up".
+func TestMigratorLockPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	ctx := context.Background()
+
+	db, err := OpenSQL(constants.StorageDriverPostgres, dsn, false)
+	if err != nil {
+		t.Fatalf("OpenSQL() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	first, err := NewMigrator(db, constants.StorageDriverPostgres)
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+	second, err := NewMigrator(db, constants.StorageDriverPostgres)
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+
+	unlock, err := first.lock(ctx)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+
+	// Another migrator waits while the lock is held.
+	waitCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if _, err := second.Up(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Up() while locked error = %v, want deadline exceeded", err)
+	}
+
+	unlock()
+	if _, err := second.Up(ctx); err != nil {
+		t.Errorf("Up() after unlock error = %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigratorRoundTripSQLite(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := NewMigrator(db, "sqlite")
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil || applied != len(migrator.migrations) {
+		t.Fatalf("Up() = %d, %v, want %d applied", applied, err, len(migrator.migrations))
+	}
+	if applied, err := migrator.Up(ctx); err != nil || applied != 0 {
+		t.Errorf("second Up() = %d, %v, want no-op", applied, err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("migration %d not reported as applied", status.Version)
+		}
+	}
+
+	reverted, err := migrator.Down(ctx, len(migrator.migrations))
+	if err != nil || reverted != len(migrator.migrations) {
+		t.Fatalf("Down() = %d, %v, want %d reverted", reverted, err, len(migrator.migrations))
+	}
+	if _, err := db.ExecContext(ctx, "SELECT 1 FROM short_urls"); err == nil {
+		t.Errorf("short_urls still exists after reverting all migrations")
+	}
+}
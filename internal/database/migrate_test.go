@@ -0,0 +1,77 @@
+package database
+
+import (
+	"path"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_add_index.up.sql":      {Data: []byte("CREATE INDEX i ON t (c);")},
+		"m/0002_add_index.down.sql":    {Data: []byte("DROP INDEX i;")},
+		"m/0001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (c TEXT);")},
+		"m/0001_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		"m/README.md":                  {Data: []byte("ignored")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("LoadMigrations() error = %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("LoadMigrations() returned %d migrations, want 2", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_table" || migrations[1].Version != 2 {
+		t.Errorf("LoadMigrations() order = %+v", migrations)
+	}
+	if migrations[1].Down != "DROP INDEX i;" {
+		t.Errorf("LoadMigrations() down script = %q", migrations[1].Down)
+	}
+}
+
+func TestLoadMigrationsRejectsMalformedFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"Missing direction", fstest.MapFS{"m/0001_create.sql": {Data: []byte("x")}}},
+		{"Missing version", fstest.MapFS{"m/create.up.sql": {Data: []byte("x")}}},
+		{"Non numeric version", fstest.MapFS{"m/abc_create.up.sql": {Data: []byte("x")}}},
+		{"Down without up", fstest.MapFS{"m/0001_create.down.sql": {Data: []byte("x")}}},
+		{"Conflicting names", fstest.MapFS{
+			"m/0001_create.up.sql": {Data: []byte("x")},
+			"m/0001_other.up.sql":  {Data: []byte("y")},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadMigrations(tt.fsys, "m"); err == nil {
+				t.Errorf("LoadMigrations() expected error")
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrationsAreReversible(t *testing.T) {
+	for _, dialect := range []string{"sqlite", "postgres"} {
+		t.Run(dialect, func(t *testing.T) {
+			migrations, err := LoadMigrations(migrationsFS, path.Join("migrations", dialect))
+			if err != nil {
+				t.Fatalf("LoadMigrations() error = %v", err)
+			}
+			if len(migrations) == 0 {
+				t.Fatalf("no migrations embedded for %s", dialect)
+			}
+			for i, m := range migrations {
+				if m.Version != i+1 {
+					t.Errorf("migration %s has version %d, want %d", m.Name, m.Version, i+1)
+				}
+				if m.Down == "" {
+					t.Errorf("migration %04d_%s has no down script", m.Version, m.Name)
+				}
+			}
+		})
+	}
+}
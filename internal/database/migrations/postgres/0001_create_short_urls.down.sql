@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_short_urls_short_code;
+DROP TABLE IF EXISTS short_urls;
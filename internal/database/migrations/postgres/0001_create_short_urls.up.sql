@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS short_urls (
+    id            UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    url           TEXT        NOT NULL,
+    short_code    VARCHAR(64) NOT NULL,
+    access_count  BIGINT      NOT NULL DEFAULT 0,
+    redirect_type INTEGER     NOT NULL DEFAULT 0,
+    created       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_short_urls_short_code ON short_urls (short_code);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_short_urls_expires_at;
+ALTER TABLE short_urls DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,3 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_short_urls_expires_at ON short_urls (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE short_urls DROP COLUMN IF EXISTS fallback_url;
+ALTER TABLE short_urls DROP COLUMN IF EXISTS max_clicks;
@@ -0,0 +1,2 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS max_clicks BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS fallback_url TEXT NOT NULL DEFAULT '';
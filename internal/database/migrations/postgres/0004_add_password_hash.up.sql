@@ -0,0 +1 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE short_urls DROP COLUMN IF EXISTS active_until;
+ALTER TABLE short_urls DROP COLUMN IF EXISTS active_from;
@@ -0,0 +1,2 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS active_from TIMESTAMPTZ;
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS active_until TIMESTAMPTZ;
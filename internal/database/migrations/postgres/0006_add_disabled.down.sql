@@ -0,0 +1,3 @@
+ALTER TABLE short_urls DROP COLUMN IF EXISTS disabled_legal;
+ALTER TABLE short_urls DROP COLUMN IF EXISTS disabled_reason;
+ALTER TABLE short_urls DROP COLUMN IF EXISTS disabled;
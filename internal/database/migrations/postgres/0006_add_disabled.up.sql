@@ -0,0 +1,3 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS disabled_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS disabled_legal BOOLEAN NOT NULL DEFAULT FALSE;
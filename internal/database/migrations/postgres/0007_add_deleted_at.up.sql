@@ -0,0 +1,3 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_short_urls_deleted_at ON short_urls (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE short_urls ADD COLUMN IF NOT EXISTS metadata TEXT NOT NULL DEFAULT '';
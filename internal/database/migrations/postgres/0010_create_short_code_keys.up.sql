@@ -0,0 +1,6 @@
+-- Codes reserved by the key pool. Rows are never deleted, so a code is
+-- handed out at most once.
+CREATE TABLE IF NOT EXISTS short_code_keys (
+    short_code  VARCHAR(64) PRIMARY KEY,
+    reserved_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,6 @@
+-- Counters of the counter-based code generators. next_value is the first
+-- number no process has taken yet.
+CREATE TABLE IF NOT EXISTS short_code_sequence (
+    name       VARCHAR(64) PRIMARY KEY,
+    next_value BIGINT      NOT NULL
+);
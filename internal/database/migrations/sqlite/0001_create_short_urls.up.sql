@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS short_urls (
+    id            INTEGER  PRIMARY KEY AUTOINCREMENT,
+    url           TEXT     NOT NULL,
+    short_code    TEXT     NOT NULL,
+    access_count  INTEGER  NOT NULL DEFAULT 0,
+    redirect_type INTEGER  NOT NULL DEFAULT 0,
+    created       DATETIME NOT NULL,
+    updated       DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_short_urls_short_code ON short_urls (short_code);
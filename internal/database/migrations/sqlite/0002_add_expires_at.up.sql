@@ -0,0 +1,3 @@
+ALTER TABLE short_urls ADD COLUMN expires_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_short_urls_expires_at ON short_urls (expires_at);
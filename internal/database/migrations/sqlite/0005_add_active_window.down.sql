@@ -0,0 +1,2 @@
+ALTER TABLE short_urls DROP COLUMN active_until;
+ALTER TABLE short_urls DROP COLUMN active_from;
@@ -0,0 +1,2 @@
+ALTER TABLE short_urls ADD COLUMN active_from DATETIME;
+ALTER TABLE short_urls ADD COLUMN active_until DATETIME;
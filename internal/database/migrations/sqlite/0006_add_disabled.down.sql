@@ -0,0 +1,3 @@
+ALTER TABLE short_urls DROP COLUMN disabled_legal;
+ALTER TABLE short_urls DROP COLUMN disabled_reason;
+ALTER TABLE short_urls DROP COLUMN disabled;
@@ -0,0 +1,3 @@
+ALTER TABLE short_urls ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE short_urls ADD COLUMN disabled_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE short_urls ADD COLUMN disabled_legal BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE short_urls ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_short_urls_deleted_at ON short_urls (deleted_at);
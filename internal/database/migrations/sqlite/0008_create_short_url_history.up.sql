@@ -0,0 +1,11 @@
+-- No foreign key to short_urls: the history outlives purged links.
+CREATE TABLE IF NOT EXISTS short_url_history (
+    url_id     TEXT     NOT NULL,
+    version    INTEGER  NOT NULL,
+    short_code TEXT     NOT NULL,
+    old_url    TEXT     NOT NULL DEFAULT '',
+    new_url    TEXT     NOT NULL,
+    actor      TEXT     NOT NULL DEFAULT '',
+    changed_at DATETIME NOT NULL,
+    PRIMARY KEY (url_id, version)
+);
@@ -0,0 +1 @@
+ALTER TABLE short_urls ADD COLUMN metadata TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// APIError is the error body PocketBase returns for failed API calls.
+type APIError struct {
+	Status  int                      `json:"status"`
+	Message string                   `json:"message"`
+	Data    map[string]APIFieldError `json:"data"`
+}
+
+// APIFieldError describes why PocketBase rejected a single field.
+type APIFieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("pocketbase: status %d", e.Status)
+	}
+	return fmt.Sprintf("pocketbase: status %d: %s", e.Status, e.Message)
+}
+
+// ParseAPIError builds an APIError from a failed response, keeping the HTTP
+// status when the body is missing or not JSON.
+func ParseAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	_ = json.Unmarshal(body, apiErr)
+	apiErr.Status = resp.StatusCode
+	return apiErr
+}
+
+// doJSON sends a JSON request to the PocketBase API and decodes the JSON
+// response into out when it is non-nil. Non-2xx responses return *APIError.
+func (pb *PBClient) doJSON(ctx context.Context, method, path, token string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, pb.BaseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := pb.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return ParseAPIError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
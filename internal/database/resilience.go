@@ -0,0 +1,271 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrCircuitOpen is returned without contacting PocketBase while the circuit
+// breaker considers it down.
+var ErrCircuitOpen = errors.New("pocketbase circuit breaker is open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerStatus is a snapshot of a CircuitBreaker for logs and health checks.
+type BreakerStatus struct {
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	OpenedAt            *time.Time   `json:"openedAt,omitempty"`
+}
+
+// CircuitBreaker opens after a run of consecutive failures and rejects calls
+// until openTimeout has passed. It then lets a single probe through: success
+// closes the circuit, failure opens it again.
+type CircuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(threshold int, openTimeout time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, openTimeout: openTimeout, now: time.Now, state: BreakerClosed}
+}
+
+// Allow reports whether a call may proceed. Every allowed call must be
+// followed by Success, Failure or Cancel.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		log.Info().Msg("PocketBase circuit breaker half-open, probing")
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BreakerClosed {
+		log.Info().Msg("PocketBase circuit breaker closed, requests resumed")
+	}
+	b.state, b.failures, b.probing = BreakerClosed, 0, false
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.threshold) {
+		b.state, b.openedAt = BreakerOpen, b.now()
+		log.Warn().Int("consecutive_failures", b.failures).Dur("open_for", b.openTimeout).Msg("PocketBase circuit breaker opened, failing fast")
+	}
+}
+
+// Cancel releases an allowed call that ended without a verdict, such as a
+// request whose context was canceled.
+func (b *CircuitBreaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{State: b.state, ConsecutiveFailures: b.failures}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// ResilienceOptions configure retries and the circuit breaker for PocketBase
+// calls.
+type ResilienceOptions struct {
+	MaxRetries       int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+// UseResilience retries idempotent requests that fail with a network error,
+// 429 or a 502/503/504 using exponential backoff with jitter, and guards
+// every request with a circuit breaker. Call it before UseAuth so sign-in
+// requests are covered too.
+func (pb *PBClient) UseResilience(opts ResilienceOptions) {
+	client := *pb.HTTPClient
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	pb.breaker = NewCircuitBreaker(opts.FailureThreshold, opts.OpenTimeout)
+	client.Transport = &resilientTransport{
+		base:    base,
+		opts:    opts,
+		breaker: pb.breaker,
+		sleep:   sleepContext,
+	}
+	pb.HTTPClient = &client
+}
+
+// Breaker returns the circuit breaker installed by UseResilience, or nil.
+func (pb *PBClient) Breaker() *CircuitBreaker {
+	return pb.breaker
+}
+
+type resilientTransport struct {
+	base    http.RoundTripper
+	opts    ResilienceOptions
+	breaker *CircuitBreaker
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	retryable := isIdempotent(req.Method) && replayable
+
+	for attempt := 0; ; attempt++ {
+		if err := t.breaker.Allow(); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				t.breaker.Cancel()
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			t.breaker.Cancel()
+			return nil, err
+		case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+			t.breaker.Failure()
+		default:
+			t.breaker.Success()
+		}
+
+		if !retryable || attempt >= t.opts.MaxRetries || !shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		event := log.Warn().Str("method", req.Method).Str("path", req.URL.Path).Int("attempt", attempt+1).Dur("retry_in", delay)
+		if err != nil {
+			event = event.Err(err)
+		} else {
+			event = event.Int("status", resp.StatusCode)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+		event.Msg("PocketBase request failed, retrying")
+
+		if err := t.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the wait before retry number attempt+1: a random delay
+// between half and all of BaseDelay*2^attempt, capped at MaxDelay. A
+// Retry-After header takes precedence when present.
+func (t *resilientTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, t.opts.MaxDelay)
+		}
+	}
+
+	delay := t.opts.MaxDelay
+	if attempt < 32 {
+		delay = min(t.opts.BaseDelay<<attempt, t.opts.MaxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isIdempotent reports whether repeating the method cannot apply it twice.
+// PATCH is excluded because access counts use PocketBase's additive "+"
+// modifier.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
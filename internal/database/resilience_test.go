@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyServer fails the first failures requests with status, then succeeds.
+type flakyServer struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	header   http.Header
+	requests int
+	bodies   []string
+}
+
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests++
+	body, _ := io.ReadAll(r.Body)
+	f.bodies = append(f.bodies, string(body))
+
+	if f.failures > 0 {
+		f.failures--
+		for key, values := range f.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(f.status)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *flakyServer) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+var testResilience = ResilienceOptions{
+	MaxRetries:       3,
+	BaseDelay:        10 * time.Millisecond,
+	MaxDelay:         100 * time.Millisecond,
+	FailureThreshold: 100,
+	OpenTimeout:      time.Minute,
+}
+
+func newResilientClient(t *testing.T, handler http.Handler, opts ResilienceOptions) (*PBClient, *[]time.Duration) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	pb := &PBClient{BaseURL: server.URL, HTTPClient: server.Client()}
+	pb.UseResilience(opts)
+
+	var delays []time.Duration
+	pb.HTTPClient.Transport.(*resilientTransport).sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return pb, &delays
+}
+
+func send(pb *PBClient, method, body string) (*http.Response, error) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, pb.BaseURL+"/api/collections/short_urls/records", reader)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := pb.HTTPClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestResilientTransportRetriesIdempotentRequests(t *testing.T) {
+	flaky := &flakyServer{failures: 2, status: http.StatusServiceUnavailable}
+	pb, delays := newResilientClient(t, flaky, testResilience)
+
+	resp, err := send(pb, http.MethodPut, `{"url":"https://example.com"}`)
+	if err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if flaky.requests != 3 {
+		t.Errorf("requests = %d, want 3", flaky.requests)
+	}
+	for i, body := range flaky.bodies {
+		if body != `{"url":"https://example.com"}` {
+			t.Errorf("attempt %d body = %q, want replayed body", i+1, body)
+		}
+	}
+	if len(*delays) != 2 {
+		t.Fatalf("delays = %v, want 2 backoffs", *delays)
+	}
+	for i, d := range *delays {
+		if ceiling := testResilience.BaseDelay << i; d < ceiling/2 || d > ceiling {
+			t.Errorf("delay %d = %s, want within [%s, %s]", i, d, ceiling/2, ceiling)
+		}
+	}
+}
+
+func TestResilientTransportDoesNotRetryUnsafeMethods(t *testing.T) {
+	for _, method := range []string{http.MethodPost, http.MethodPatch} {
+		t.Run(method, func(t *testing.T) {
+			flaky := &flakyServer{failures: 1, status: http.StatusServiceUnavailable}
+			pb, _ := newResilientClient(t, flaky, testResilience)
+
+			resp, err := send(pb, method, `{"access_count+":1}`)
+			if err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+			if resp.StatusCode != http.StatusServiceUnavailable || flaky.requests != 1 {
+				t.Errorf("status = %d after %d requests, want one 503", resp.StatusCode, flaky.requests)
+			}
+		})
+	}
+}
+
+func TestResilientTransportGivesUpAfterMaxRetries(t *testing.T) {
+	flaky := &flakyServer{failures: 10, status: http.StatusBadGateway}
+	pb, _ := newResilientClient(t, flaky, testResilience)
+
+	resp, err := send(pb, http.MethodGet, "")
+	if err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", resp.StatusCode)
+	}
+	if flaky.requests != testResilience.MaxRetries+1 {
+		t.Errorf("requests = %d, want %d", flaky.requests, testResilience.MaxRetries+1)
+	}
+}
+
+func TestResilientTransportHonoursRetryAfter(t *testing.T) {
+	flaky := &flakyServer{failures: 1, status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"0"}}}
+	pb, delays := newResilientClient(t, flaky, testResilience)
+
+	if _, err := send(pb, http.MethodGet, ""); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if len(*delays) != 1 || (*delays)[0] != 0 {
+		t.Errorf("delays = %v, want [0s]", *delays)
+	}
+}
+
+func TestResilientTransportRetriesNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	pb := &PBClient{BaseURL: server.URL, HTTPClient: &http.Client{}}
+	pb.UseResilience(testResilience)
+	attempts := 0
+	pb.HTTPClient.Transport.(*resilientTransport).sleep = func(context.Context, time.Duration) error {
+		attempts++
+		return nil
+	}
+
+	if _, err := send(pb, http.MethodGet, ""); err == nil {
+		t.Fatal("send() error = nil, want connection error")
+	}
+	if attempts != testResilience.MaxRetries {
+		t.Errorf("retries = %d, want %d", attempts, testResilience.MaxRetries)
+	}
+}
+
+func TestResilientTransportStopsOnContextCancel(t *testing.T) {
+	flaky := &flakyServer{failures: 10, status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(flaky)
+	t.Cleanup(server.Close)
+
+	pb := &PBClient{BaseURL: server.URL, HTTPClient: server.Client()}
+	pb.UseResilience(ResilienceOptions{MaxRetries: 10, BaseDelay: time.Hour, MaxDelay: time.Hour, FailureThreshold: 100, OpenTimeout: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+	start := time.Now()
+	if _, err := pb.HTTPClient.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Do() took %s, want to stop waiting on cancel", elapsed)
+	}
+}
+
+func TestCircuitBreakerFailsFastAndRecovers(t *testing.T) {
+	flaky := &flakyServer{failures: 3, status: http.StatusServiceUnavailable}
+	opts := testResilience
+	opts.MaxRetries = 0
+	opts.FailureThreshold = 3
+	pb, _ := newResilientClient(t, flaky, opts)
+
+	now := time.Now()
+	pb.Breaker().now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := send(pb, http.MethodGet, ""); err != nil {
+			t.Fatalf("send() error = %v", err)
+		}
+	}
+	if status := pb.Breaker().Status(); status.State != BreakerOpen || status.OpenedAt == nil {
+		t.Fatalf("Status() = %+v, want open", status)
+	}
+
+	// While open, requests fail without reaching PocketBase.
+	if _, err := send(pb, http.MethodGet, ""); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("send() error = %v, want ErrCircuitOpen", err)
+	}
+	if got := flaky.requestCount(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+
+	// After the open timeout a probe goes through and closes the circuit.
+	now = now.Add(opts.OpenTimeout)
+	if resp, err := send(pb, http.MethodGet, ""); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("probe = %v, %v, want 200", resp, err)
+	}
+	if status := pb.Breaker().Status(); status.State != BreakerClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("Status() = %+v, want closed", status)
+	}
+}
+
+func TestCircuitBreakerReopensWhenProbeFails(t *testing.T) {
+	breaker := NewCircuitBreaker(1, time.Minute)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	breaker.Allow()
+	breaker.Failure()
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow() error = %v, want ErrCircuitOpen", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow() probe error = %v", err)
+	}
+	// Only one probe at a time.
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second Allow() while probing error = %v, want ErrCircuitOpen", err)
+	}
+
+	breaker.Failure()
+	if status := breaker.Status(); status.State != BreakerOpen || !status.OpenedAt.Equal(now) {
+		t.Errorf("Status() = %+v, want reopened at %s", status, now)
+	}
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	sqliteDriverName   = "sqlite"
+	postgresDriverName = "pgx"
+)
+
+// OpenSQLite opens the SQLite database at path, creating the file if it does
+// not exist yet.
+func OpenSQLite(path string) (*sql.DB, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create database directory: %w", err)
+		}
+	}
+
+	log.Info().Str("path", path).Msg("Opening SQLite database")
+
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := sql.Open(sqliteDriverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+	return db, nil
+}
+
+// OpenPostgres connects to the PostgreSQL database at dsn. The driver is
+// linked in with the "postgres" build tag.
+func OpenPostgres(dsn string) (*sql.DB, error) {
+	if !slices.Contains(sql.Drivers(), postgresDriverName) {
+		return nil, fmt.Errorf("postgres driver not compiled in, rebuild with -tags postgres")
+	}
+
+	log.Info().Msg("Connecting to PostgreSQL")
+
+	db, err := sql.Open(postgresDriverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.RequestTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping postgres database: %w", err)
+	}
+	return db, nil
+}
+
+// OpenSQL opens the database for driver and, when migrate is set, brings its
+// schema up to date.
+func OpenSQL(driver, dsn string, migrate bool) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+	switch driver {
+	case constants.StorageDriverSQLite:
+		db, err = OpenSQLite(dsn)
+	case constants.StorageDriverPostgres:
+		db, err = OpenPostgres(dsn)
+	default:
+		return nil, fmt.Errorf("storage driver %q is not SQL backed", driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !migrate {
+		return db, nil
+	}
+
+	migrator, err := NewMigrator(db, driver)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.RequestTimeout)
+	defer cancel()
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if applied > 0 {
+		log.Info().Int("applied", applied).Str("driver", driver).Msg("Database migrations applied")
+	}
+	return db, nil
+}
@@ -0,0 +1,5 @@
+package database
+
+// modernc.org/sqlite is pure Go, so the SQLite driver needs no cgo and is
+// linked into every build.
+import _ "modernc.org/sqlite"
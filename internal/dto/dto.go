@@ -3,51 +3,281 @@ package dto
 import "time"
 
 type CreateURLRequest struct {
-	URL        string  `json:"url" validate:"required,url,max=2048"`
-	CustomCode *string `json:"customCode,omitempty" validate:"omitempty,min=4,max=20,alphanum"`
+	URL          string  `json:"url" validate:"required,url,max=2048"`
+	CustomCode   *string `json:"customCode,omitempty" validate:"omitempty,min=4,max=20,alphanum"`
+	RedirectType *int    `json:"redirectType,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	// ExpiresAt and TTLSeconds are alternative ways to set an expiry.
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	TTLSeconds *int64     `json:"ttlSeconds,omitempty"`
+	// MaxClicks limits how often the link resolves; FallbackURL is where it
+	// redirects afterwards instead of answering 410 Gone.
+	MaxClicks   *int64  `json:"maxClicks,omitempty"`
+	FallbackURL *string `json:"fallbackUrl,omitempty"`
+	// Password protects the link; only its hash is stored.
+	Password *string `json:"password,omitempty"`
+	// ActiveFrom and ActiveUntil schedule when the link resolves.
+	ActiveFrom  *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil *time.Time `json:"activeUntil,omitempty"`
+	// Metadata holds free-form labels kept with the link.
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type CreateURLResponse struct {
-	ID        string    `json:"id"`
-	URL       string    `json:"url"`
-	ShortCode string    `json:"shortCode"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID                string     `json:"id"`
+	URL               string     `json:"url"`
+	ShortCode         string     `json:"shortCode"`
+	RedirectType      int        `json:"redirectType,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"`
+	MaxClicks         int64      `json:"maxClicks,omitempty"`
+	FallbackURL       string     `json:"fallbackUrl,omitempty"`
+	PasswordProtected bool       `json:"passwordProtected,omitempty"`
+	ActiveFrom        *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil       *time.Time `json:"activeUntil,omitempty"`
+	// Metadata holds the link's free-form labels.
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type GetURLResponse struct {
-	ID          string    `json:"id"`
-	URL         string    `json:"url"`
-	ShortCode   string    `json:"shortCode"`
-	AccessCount int64     `json:"accessCount,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID                string     `json:"id"`
+	URL               string     `json:"url"`
+	ShortCode         string     `json:"shortCode"`
+	AccessCount       int64      `json:"accessCount,omitempty"`
+	RedirectType      int        `json:"redirectType,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"`
+	MaxClicks         int64      `json:"maxClicks,omitempty"`
+	FallbackURL       string     `json:"fallbackUrl,omitempty"`
+	PasswordProtected bool       `json:"passwordProtected,omitempty"`
+	ActiveFrom        *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil       *time.Time `json:"activeUntil,omitempty"`
 }
 
 type UpdateURLRequest struct {
-	URL string `json:"url" validate:"required,url,max=2048"`
+	URL          string     `json:"url" validate:"required,url,max=2048"`
+	RedirectType *int       `json:"redirectType,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	TTLSeconds   *int64     `json:"ttlSeconds,omitempty"`
+	// ClearExpiry makes the link permanent again.
+	ClearExpiry bool `json:"clearExpiry,omitempty"`
+	// MaxClicks of 0 removes the limit; an empty FallbackURL removes the
+	// fallback.
+	MaxClicks   *int64  `json:"maxClicks,omitempty"`
+	FallbackURL *string `json:"fallbackUrl,omitempty"`
+	// Password replaces the link's password; an empty one removes it.
+	Password *string `json:"password,omitempty"`
+	// ClearActiveFrom and ClearActiveUntil open that side of the window.
+	ActiveFrom       *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil      *time.Time `json:"activeUntil,omitempty"`
+	ClearActiveFrom  bool       `json:"clearActiveFrom,omitempty"`
+	ClearActiveUntil bool       `json:"clearActiveUntil,omitempty"`
+	// Metadata replaces the link's metadata; an empty object removes it.
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type UpdateURLResponse struct {
-	ID          string    `json:"id"`
-	URL         string    `json:"url"`
-	ShortCode   string    `json:"shortCode"`
-	AccessCount int64     `json:"accessCount"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID                string     `json:"id"`
+	URL               string     `json:"url"`
+	ShortCode         string     `json:"shortCode"`
+	AccessCount       int64      `json:"accessCount"`
+	RedirectType      int        `json:"redirectType,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"`
+	MaxClicks         int64      `json:"maxClicks,omitempty"`
+	FallbackURL       string     `json:"fallbackUrl,omitempty"`
+	PasswordProtected bool       `json:"passwordProtected,omitempty"`
+	ActiveFrom        *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil       *time.Time `json:"activeUntil,omitempty"`
+	// Metadata holds the link's free-form labels.
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type GetStatsResponse struct {
-	ID          string    `json:"id"`
-	URL         string    `json:"url"`
-	ShortCode   string    `json:"shortCode"`
-	AccessCount int64     `json:"accessCount"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID                string     `json:"id"`
+	URL               string     `json:"url"`
+	ShortCode         string     `json:"shortCode"`
+	AccessCount       int64      `json:"accessCount"`
+	RedirectType      int        `json:"redirectType,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"`
+	MaxClicks         int64      `json:"maxClicks,omitempty"`
+	FallbackURL       string     `json:"fallbackUrl,omitempty"`
+	PasswordProtected bool       `json:"passwordProtected,omitempty"`
+	ActiveFrom        *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil       *time.Time `json:"activeUntil,omitempty"`
+	Enabled           bool       `json:"enabled"`
+	DisabledReason    string     `json:"disabledReason,omitempty"`
+	DisabledLegal     bool       `json:"disabledLegal,omitempty"`
+	DeletedAt         *time.Time `json:"deletedAt,omitempty"`
+	// Metadata holds the link's free-form labels.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// DisableURLRequest is the body of POST .../disable. Legal makes the link
+// answer 451 Unavailable For Legal Reasons instead of 404.
+type DisableURLRequest struct {
+	Reason string `json:"reason"`
+	Legal  bool   `json:"legal"`
+}
+
+// HistoryResponse lists the destinations of a short URL, oldest first.
+type HistoryResponse struct {
+	ShortCode string               `json:"shortCode"`
+	Versions  []DestinationVersion `json:"versions"`
+}
+
+// DestinationVersion is one destination change. OldURL is empty for the
+// version a link was created with.
+type DestinationVersion struct {
+	Version   int       `json:"version"`
+	OldURL    string    `json:"oldUrl,omitempty"`
+	NewURL    string    `json:"newUrl"`
+	Actor     string    `json:"actor,omitempty"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// ImportRequest holds the query parameters of POST /api/v1/import. Format
+// falls back to the Content-Type of the body.
+type ImportRequest struct {
+	Format     string `form:"format"`
+	DryRun     bool   `form:"dryRun"`
+	OnConflict string `form:"onConflict"`
+}
+
+// ImportRow is one link read from an import file.
+type ImportRow struct {
+	// Line is where the row starts in the file, for reporting.
+	Line       int
+	URL        string
+	CustomCode string
+	Metadata   map[string]string
+}
+
+// ImportResult is the outcome of one import row. ShortCode is empty for
+// generated codes in a dry run.
+type ImportResult struct {
+	Line      int    `json:"line"`
+	Status    string `json:"status"`
+	ShortCode string `json:"shortCode,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ImportSummary counts import results by status. Aborted is set when the
+// fail conflict policy stopped the import early.
+type ImportSummary struct {
+	Rows      int  `json:"rows"`
+	Created   int  `json:"created"`
+	Updated   int  `json:"updated"`
+	Skipped   int  `json:"skipped"`
+	Invalid   int  `json:"invalid"`
+	Conflicts int  `json:"conflicts"`
+	Failed    int  `json:"failed"`
+	DryRun    bool `json:"dryRun,omitempty"`
+	Aborted   bool `json:"aborted,omitempty"`
+}
+
+// ExportRequest holds the query parameters of GET /api/v1/export.
+type ExportRequest struct {
+	Format string `form:"format"`
+}
+
+// BatchCreateRequest is the body of POST /api/v1/shorten/batch. Each item
+// is created on its own, so one bad item does not fail the others.
+type BatchCreateRequest struct {
+	Items []CreateURLRequest `json:"items"`
+}
+
+// BatchCreateResult is the outcome of one batch item. Index is the item's
+// position in the request and Link is set when it was created.
+type BatchCreateResult struct {
+	Index  int                `json:"index"`
+	Status string             `json:"status"`
+	Link   *CreateURLResponse `json:"link,omitempty"`
+	Error  string             `json:"error,omitempty"`
+}
+
+// BatchCreateResponse lists the results in request order and counts them
+// by status.
+type BatchCreateResponse struct {
+	Items      []BatchCreateResult `json:"items"`
+	Created    int                 `json:"created"`
+	Duplicates int                 `json:"duplicates"`
+	Invalid    int                 `json:"invalid"`
+	Failed     int                 `json:"failed"`
+}
+
+// BatchDeleteRequest is the body of DELETE /api/v1/shorten/batch.
+type BatchDeleteRequest struct {
+	ShortCodes []string `json:"shortCodes"`
+}
+
+type BatchDeleteResult struct {
+	Index     int    `json:"index"`
+	ShortCode string `json:"shortCode"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+type BatchDeleteResponse struct {
+	Items      []BatchDeleteResult `json:"items"`
+	Deleted    int                 `json:"deleted"`
+	NotFound   int                 `json:"notFound"`
+	Duplicates int                 `json:"duplicates"`
+	Invalid    int                 `json:"invalid"`
+	Failed     int                 `json:"failed"`
+}
+
+type UnlockURLRequest struct {
+	Password string `json:"password" form:"password" binding:"required"`
+}
+
+// UnlockURLResponse resolves a password-protected link. AccessToken lets the
+// caller resolve it again without the password until AccessTokenExpiresAt.
+type UnlockURLResponse struct {
+	GetURLResponse
+	AccessToken          string     `json:"accessToken,omitempty"`
+	AccessTokenExpiresAt *time.Time `json:"accessTokenExpiresAt,omitempty"`
+}
+
+// ListURLsRequest holds the query parameters of GET /api/v1/shorten. Sort
+// is a field name, prefixed with "-" for descending order. Cursor replaces
+// Page once set.
+type ListURLsRequest struct {
+	Page           int    `form:"page"`
+	PerPage        int    `form:"perPage"`
+	Cursor         string `form:"cursor"`
+	Sort           string `form:"sort"`
+	CreatedAfter   string `form:"createdAfter"`
+	CreatedBefore  string `form:"createdBefore"`
+	URLContains    string `form:"urlContains"`
+	MinAccessCount int64  `form:"minAccessCount"`
+}
+
+type ListURLsResponse struct {
+	Items      []GetStatsResponse `json:"items"`
+	Page       int                `json:"page,omitempty"`
+	PerPage    int                `json:"perPage"`
+	TotalItems int                `json:"totalItems"`
+	TotalPages int                `json:"totalPages"`
+	NextCursor string             `json:"nextCursor,omitempty"`
 }
 
 type HealthResponse struct {
-	Status string `json:"status"`
+	Status  string         `json:"status"`
+	Storage *StorageHealth `json:"storage,omitempty"`
+}
+
+// StorageHealth reports the state of the PocketBase circuit breaker.
+type StorageHealth struct {
+	Driver              string     `json:"driver"`
+	CircuitBreaker      string     `json:"circuitBreaker"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -10,6 +10,12 @@ const (
 	ErrorCodeValidation
 	ErrorCodeInternal
 	ErrorCodeBadRequest
+	ErrorCodeUnavailable
+	ErrorCodeGone
+	ErrorCodeUnauthorized
+	ErrorCodeTooManyRequests
+	ErrorCodeNotYetActive
+	ErrorCodeUnavailableForLegalReasons
 )
 
 type ServiceError struct {
@@ -63,3 +69,52 @@ func NewInternalError(op, message string, err error) *ServiceError {
 		Err:     err,
 	}
 }
+
+func NewUnavailableError(op, message string, err error) *ServiceError {
+	return &ServiceError{
+		Op:      op,
+		Code:    ErrorCodeUnavailable,
+		Message: message,
+		Err:     err,
+	}
+}
+
+func NewGoneError(op, message string) *ServiceError {
+	return &ServiceError{
+		Op:      op,
+		Code:    ErrorCodeGone,
+		Message: message,
+	}
+}
+
+func NewUnauthorizedError(op, message string) *ServiceError {
+	return &ServiceError{
+		Op:      op,
+		Code:    ErrorCodeUnauthorized,
+		Message: message,
+	}
+}
+
+func NewTooManyRequestsError(op, message string) *ServiceError {
+	return &ServiceError{
+		Op:      op,
+		Code:    ErrorCodeTooManyRequests,
+		Message: message,
+	}
+}
+
+func NewNotYetActiveError(op, message string) *ServiceError {
+	return &ServiceError{
+		Op:      op,
+		Code:    ErrorCodeNotYetActive,
+		Message: message,
+	}
+}
+
+func NewUnavailableForLegalReasonsError(op, message string) *ServiceError {
+	return &ServiceError{
+		Op:      op,
+		Code:    ErrorCodeUnavailableForLegalReasons,
+		Message: message,
+	}
+}
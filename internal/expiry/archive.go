@@ -0,0 +1,51 @@
+package expiry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/rowjay/url-shortening-service/internal/models"
+)
+
+// FileArchiver appends expired short URLs to a file as JSON lines.
+type FileArchiver struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileArchiver(path string) *FileArchiver {
+	return &FileArchiver{path: path}
+}
+
+// Archive writes one line per short URL and syncs the file before
+// returning, so a batch is on disk before the sweeper deletes it.
+func (a *FileArchiver) Archive(ctx context.Context, shortURLs []*models.ShortURL) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, shortURL := range shortURLs {
+		if err := encoder.Encode(shortURL); err != nil {
+			return fmt.Errorf("encode %s: %w", shortURL.ShortCode, err)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("write archive: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("sync archive: %w", err)
+	}
+	return file.Close()
+}
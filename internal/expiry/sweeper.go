@@ -0,0 +1,148 @@
+// Package expiry removes short URLs whose expiry has passed.
+package expiry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rowjay/url-shortening-service/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// Store lists and deletes short URLs. repository.URLRepository satisfies it.
+type Store interface {
+	List(ctx context.Context, opts *models.ListOptions) (*models.ListResult, error)
+	Delete(ctx context.Context, shortCode string) error
+}
+
+// Archiver keeps a copy of expired short URLs before they are deleted.
+type Archiver interface {
+	Archive(ctx context.Context, shortURLs []*models.ShortURL) error
+}
+
+type Options struct {
+	// Interval is the time between sweeps.
+	Interval time.Duration
+	// Retention is how long an expired link keeps answering 410 Gone
+	// before it is purged.
+	Retention time.Duration
+	// BatchSize caps how many links are listed and deleted at a time.
+	BatchSize int
+	// Archiver, when set, receives every batch before it is deleted.
+	Archiver Archiver
+}
+
+// Sweeper periodically purges short URLs that expired more than
+// Options.Retention ago, optionally archiving them first.
+type Sweeper struct {
+	store Store
+	opts  Options
+	now   func() time.Time
+
+	sweepMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewSweeper(store Store, opts Options) *Sweeper {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	return &Sweeper{
+		store: store,
+		opts:  opts,
+		now:   time.Now,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Start runs the sweep loop until Stop is called.
+func (s *Sweeper) Start() {
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.Sweep(context.Background()); err != nil {
+					log.Warn().Err(err).Msg("Failed to sweep expired short URLs, will retry")
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the sweep loop, waiting for a sweep in progress to finish.
+func (s *Sweeper) Stop(ctx context.Context) error {
+	close(s.stop)
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sweep purges every short URL past its retention and returns how many were
+// deleted. A batch that cannot be archived is left in place, so nothing is
+// deleted without its archive copy.
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	s.sweepMu.Lock()
+	defer s.sweepMu.Unlock()
+
+	opts := &models.ListOptions{
+		Filter:  models.ListFilter{ExpiredBefore: s.now().Add(-s.opts.Retention)},
+		SortBy:  models.SortByShortCode,
+		PerPage: s.opts.BatchSize,
+	}
+
+	purged := 0
+	var errs []error
+	for {
+		result, err := s.store.List(ctx, opts)
+		if err != nil {
+			return purged, errors.Join(append(errs, err)...)
+		}
+		if len(result.Items) == 0 {
+			break
+		}
+
+		if s.opts.Archiver != nil {
+			if err := s.opts.Archiver.Archive(ctx, result.Items); err != nil {
+				return purged, errors.Join(append(errs, err)...)
+			}
+		}
+
+		for _, shortURL := range result.Items {
+			err := s.store.Delete(ctx, shortURL.ShortCode)
+			var serviceErr *serviceErrors.ServiceError
+			switch {
+			case err == nil:
+				purged++
+			case errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeNotFound:
+			default:
+				errs = append(errs, err)
+			}
+		}
+
+		if len(result.Items) < opts.PerPage {
+			break
+		}
+		// Page by cursor so links that failed to delete are not listed again.
+		opts.After = models.CursorFor(result.Items[len(result.Items)-1])
+	}
+
+	if purged > 0 {
+		log.Info().Int("purged", purged).Msg("Purged expired short URLs")
+	}
+	return purged, errors.Join(errs...)
+}
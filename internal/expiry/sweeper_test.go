@@ -0,0 +1,144 @@
+package expiry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rowjay/url-shortening-service/internal/models"
+	"github.com/rowjay/url-shortening-service/internal/repository"
+)
+
+func seedExpiring(t *testing.T, repo repository.URLRepository, now time.Time, expiries map[string]time.Duration) {
+	t.Helper()
+	for code, offset := range expiries {
+		shortURL := &models.ShortURL{URL: "https://example.com/" + code, ShortCode: code}
+		if offset != 0 {
+			expiresAt := now.Add(offset)
+			shortURL.ExpiresAt = &expiresAt
+		}
+		if err := repo.Create(context.Background(), shortURL); err != nil {
+			t.Fatalf("Create(%s) error = %v", code, err)
+		}
+	}
+}
+
+func remainingCodes(t *testing.T, repo repository.URLRepository) []string {
+	t.Helper()
+	result, err := repo.List(context.Background(), &models.ListOptions{SortBy: models.SortByShortCode, PerPage: 100})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var codes []string
+	for _, item := range result.Items {
+		codes = append(codes, item.ShortCode)
+	}
+	return codes
+}
+
+func TestSweepPurgesLinksPastRetention(t *testing.T) {
+	now := time.Now()
+	repo := repository.NewMemoryURLRepository()
+	seedExpiring(t, repo, now, map[string]time.Duration{
+		"forever": 0,
+		"future":  time.Hour,
+		"recent":  -time.Minute,
+		"old1":    -2 * time.Hour,
+		"old2":    -3 * time.Hour,
+		"old3":    -4 * time.Hour,
+	})
+
+	archivePath := filepath.Join(t.TempDir(), "expired.jsonl")
+	sweeper := NewSweeper(repo, Options{Retention: time.Hour, BatchSize: 2, Archiver: NewFileArchiver(archivePath)})
+	sweeper.now = func() time.Time { return now }
+
+	purged, err := sweeper.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	if purged != 3 {
+		t.Errorf("Sweep() purged %d, want 3", purged)
+	}
+	if got, want := remainingCodes(t, repo), []string{"forever", "future", "recent"}; !slices.Equal(got, want) {
+		t.Errorf("remaining codes = %v, want %v", got, want)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer file.Close()
+	var archived []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var shortURL models.ShortURL
+		if err := json.Unmarshal(scanner.Bytes(), &shortURL); err != nil {
+			t.Fatalf("archive line %q: %v", scanner.Text(), err)
+		}
+		if shortURL.ExpiresAt == nil {
+			t.Errorf("archived %s without its expiry", shortURL.ShortCode)
+		}
+		archived = append(archived, shortURL.ShortCode)
+	}
+	if want := []string{"old1", "old2", "old3"}; !slices.Equal(archived, want) {
+		t.Errorf("archived = %v, want %v", archived, want)
+	}
+}
+
+type failingArchiver struct{}
+
+func (failingArchiver) Archive(ctx context.Context, shortURLs []*models.ShortURL) error {
+	return errors.New("disk full")
+}
+
+func TestSweepKeepsLinksThatCannotBeArchived(t *testing.T) {
+	now := time.Now()
+	repo := repository.NewMemoryURLRepository()
+	seedExpiring(t, repo, now, map[string]time.Duration{"old": -time.Hour})
+
+	sweeper := NewSweeper(repo, Options{Archiver: failingArchiver{}})
+	if _, err := sweeper.Sweep(context.Background()); err == nil {
+		t.Fatal("Sweep() error = nil, want archive failure")
+	}
+	if got := remainingCodes(t, repo); !slices.Equal(got, []string{"old"}) {
+		t.Errorf("remaining codes = %v, want [old]", got)
+	}
+}
+
+// stubbornStore refuses to delete one code.
+type stubbornStore struct {
+	repository.URLRepository
+	keep string
+}
+
+func (s *stubbornStore) Delete(ctx context.Context, shortCode string) error {
+	if shortCode == s.keep {
+		return serviceErrors.NewUnavailableError("repository.Delete", "storage unavailable", nil)
+	}
+	return s.URLRepository.Delete(ctx, shortCode)
+}
+
+func TestSweepContinuesPastFailedDeletes(t *testing.T) {
+	now := time.Now()
+	repo := repository.NewMemoryURLRepository()
+	seedExpiring(t, repo, now, map[string]time.Duration{"a": -time.Hour, "b": -time.Hour, "c": -time.Hour})
+
+	sweeper := NewSweeper(&stubbornStore{URLRepository: repo, keep: "a"}, Options{BatchSize: 1})
+	purged, err := sweeper.Sweep(context.Background())
+	if err == nil {
+		t.Error("Sweep() error = nil, want the failed delete reported")
+	}
+	if purged != 2 {
+		t.Errorf("Sweep() purged %d, want 2", purged)
+	}
+	if got := remainingCodes(t, repo); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("remaining codes = %v, want [a]", got)
+	}
+}
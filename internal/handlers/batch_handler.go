@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rowjay/url-shortening-service/internal/dto"
+	"github.com/rs/zerolog/log"
+)
+
+// BatchCreateURLs creates every item of the batch. Items that fail are
+// reported in the response, which is 200 as long as the batch itself is
+// well-formed.
+func (h *URLHandler) BatchCreateURLs(c *gin.Context) {
+	var req dto.BatchCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Invalid request payload",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.service.BatchCreateURLs(actorContext(c), &req)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	log.Info().
+		Int("items", len(resp.Items)).
+		Int("created", resp.Created).
+		Int("duplicates", resp.Duplicates).
+		Int("invalid", resp.Invalid).
+		Int("failed", resp.Failed).
+		Msg("Batch create finished")
+	c.JSON(http.StatusOK, resp)
+}
+
+// BatchDeleteURLs moves every listed short URL to the trash, reporting the
+// outcome per code.
+func (h *URLHandler) BatchDeleteURLs(c *gin.Context) {
+	var req dto.BatchDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Invalid request payload",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.service.BatchDeleteURLs(c.Request.Context(), &req)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	log.Info().
+		Int("items", len(resp.Items)).
+		Int("deleted", resp.Deleted).
+		Int("not_found", resp.NotFound).
+		Int("invalid", resp.Invalid).
+		Int("failed", resp.Failed).
+		Msg("Batch delete finished")
+	c.JSON(http.StatusOK, resp)
+}
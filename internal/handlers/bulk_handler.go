@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rowjay/url-shortening-service/internal/bulk"
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rowjay/url-shortening-service/internal/dto"
+	"github.com/rowjay/url-shortening-service/internal/services"
+	"github.com/rs/zerolog/log"
+)
+
+// ImportURLs streams the rows of a CSV or JSON Lines body into links. The
+// response is JSON Lines: one result per row as it is processed, then a
+// final {"summary": ...} line, which also carries "error" when the import
+// stopped on one.
+func (h *URLHandler) ImportURLs(c *gin.Context) {
+	var req dto.ImportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid query parameters")
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Invalid query parameters",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	formatName := req.Format
+	if formatName == "" {
+		formatName = c.ContentType()
+	}
+	format, err := bulk.ParseFormat(formatName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Unsupported format",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+	policy, err := services.ParseConflictPolicy(req.OnConflict)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+	rows, err := bulk.NewReader(c.Request.Body, format)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", bulk.JSONL.ContentType())
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	opts := services.ImportOptions{DryRun: req.DryRun, OnConflict: policy}
+	summary, err := h.service.ImportURLs(actorContext(c), rows, opts, func(result *dto.ImportResult) error {
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+
+	trailer := gin.H{"summary": summary}
+	if err != nil {
+		log.Error().Err(err).Int("rows", summary.Rows).Msg("Import stopped")
+		trailer["error"] = err.Error()
+	} else {
+		log.Info().Int("rows", summary.Rows).Int("created", summary.Created).Int("updated", summary.Updated).Bool("dry_run", summary.DryRun).Msg("Import finished")
+	}
+	_ = encoder.Encode(trailer)
+}
+
+// ExportURLs streams every link outside the trash, with its statistics, as
+// JSON Lines or, with format=csv, as CSV.
+func (h *URLHandler) ExportURLs(c *gin.Context) {
+	var req dto.ExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid query parameters")
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Invalid query parameters",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	format := bulk.JSONL
+	if req.Format != "" {
+		var err error
+		if format, err = bulk.ParseFormat(req.Format); err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "Unsupported format",
+				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	writer, err := bulk.NewWriter(c.Writer, format)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="links.`+string(format)+`"`)
+
+	exported := 0
+	err = h.service.ExportURLs(c.Request.Context(), func(link *dto.GetStatsResponse) error {
+		if err := writer.Write(link); err != nil {
+			return err
+		}
+		exported++
+		if exported%constants.MaxPageSize == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		log.Error().Err(err).Int("exported", exported).Msg("Export stopped")
+		// Until something is sent the failure can still be reported;
+		// afterwards the client sees a truncated body.
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			h.handleServiceError(c, err)
+		}
+		return
+	}
+	log.Info().Int("exported", exported).Str("format", string(format)).Msg("Export finished")
+}
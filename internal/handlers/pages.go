@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var pages = loadPages("not_found", "gone", "error", "password", "not_yet_active", "unavailable")
+
+type pageData struct {
+	Title     string
+	ShortCode string
+	Message   string
+}
+
+func loadPages(names ...string) map[string]*template.Template {
+	loaded := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		loaded[name] = template.Must(template.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html"))
+	}
+	return loaded
+}
+
+func renderPage(c *gin.Context, status int, name string, data pageData) {
+	var buf bytes.Buffer
+	if err := pages[name].ExecuteTemplate(&buf, "layout", data); err != nil {
+		log.Error().Err(err).Str("page", name).Msg("Failed to render page")
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
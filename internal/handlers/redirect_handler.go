@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rowjay/url-shortening-service/internal/dto"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rowjay/url-shortening-service/internal/services"
+	"github.com/rowjay/url-shortening-service/internal/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// RedirectHandler serves the browser-facing short links. Unlike URLHandler it
+// answers with redirects and HTML pages rather than JSON.
+type RedirectHandler struct {
+	service       services.URLService
+	defaultStatus int
+}
+
+func NewRedirectHandler(service services.URLService, defaultStatus int) *RedirectHandler {
+	return &RedirectHandler{
+		service:       service,
+		defaultStatus: defaultStatus,
+	}
+}
+
+func (h *RedirectHandler) Redirect(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if !utils.IsValidShortCode(shortCode) {
+		h.renderNotFound(c, shortCode)
+		return
+	}
+
+	// A cookie from an earlier unlock lets protected links through without
+	// prompting again.
+	var resp *dto.GetURLResponse
+	var err error
+	if token, cookieErr := c.Cookie(unlockCookieName(shortCode)); cookieErr == nil {
+		resp, err = h.service.GetUnlockedURL(c.Request.Context(), shortCode, token)
+	} else {
+		resp, err = h.service.GetOriginalURL(c.Request.Context(), shortCode)
+	}
+	if err != nil {
+		h.handleResolveError(c, shortCode, err)
+		return
+	}
+
+	status := h.defaultStatus
+	if resp.RedirectType != 0 {
+		status = resp.RedirectType
+	}
+
+	log.Debug().Str("short_code", shortCode).Int("status", status).Msg("Redirecting short URL")
+	c.Redirect(status, resp.URL)
+}
+
+// Unlock checks the password submitted from the prompt page. On success it
+// sets a cookie scoped to the link and redirects to the destination.
+func (h *RedirectHandler) Unlock(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if !utils.IsValidShortCode(shortCode) {
+		h.renderNotFound(c, shortCode)
+		return
+	}
+
+	req := dto.UnlockURLRequest{Password: c.PostForm("password")}
+	resp, err := h.service.UnlockURL(c.Request.Context(), shortCode, c.ClientIP(), &req)
+	if err != nil {
+		var serviceErr *serviceErrors.ServiceError
+		if errors.As(err, &serviceErr) {
+			switch serviceErr.Code {
+			case serviceErrors.ErrorCodeUnauthorized:
+				h.renderPasswordPrompt(c, http.StatusUnauthorized, shortCode, "Incorrect password. Please try again.")
+				return
+			case serviceErrors.ErrorCodeTooManyRequests:
+				h.renderPasswordPrompt(c, http.StatusTooManyRequests, shortCode, "Too many incorrect attempts. Please wait a few minutes and try again.")
+				return
+			}
+		}
+		h.handleResolveError(c, shortCode, err)
+		return
+	}
+
+	if resp.AccessToken != "" {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     unlockCookieName(shortCode),
+			Value:    resp.AccessToken,
+			Path:     "/" + shortCode,
+			Expires:  *resp.AccessTokenExpiresAt,
+			MaxAge:   int(time.Until(*resp.AccessTokenExpiresAt).Seconds()),
+			Secure:   c.Request.TLS != nil,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	log.Debug().Str("short_code", shortCode).Msg("Redirecting unlocked short URL")
+	c.Redirect(http.StatusSeeOther, resp.URL)
+}
+
+// unlockCookieName is the cookie holding the access token for shortCode.
+// Short codes are base62, so the name needs no escaping.
+func unlockCookieName(shortCode string) string {
+	return "unlock_" + shortCode
+}
+
+func (h *RedirectHandler) handleResolveError(c *gin.Context, shortCode string, err error) {
+	var serviceErr *serviceErrors.ServiceError
+	if errors.As(err, &serviceErr) {
+		switch serviceErr.Code {
+		case serviceErrors.ErrorCodeNotFound, serviceErrors.ErrorCodeValidation:
+			h.renderNotFound(c, shortCode)
+			return
+		case serviceErrors.ErrorCodeUnauthorized:
+			h.renderPasswordPrompt(c, http.StatusUnauthorized, shortCode, "")
+			return
+		case serviceErrors.ErrorCodeNotYetActive:
+			renderPage(c, http.StatusNotFound, "not_yet_active", pageData{Title: "Link not available yet", ShortCode: shortCode})
+			return
+		case serviceErrors.ErrorCodeUnavailableForLegalReasons:
+			renderPage(c, http.StatusUnavailableForLegalReasons, "unavailable", pageData{Title: "Link unavailable", ShortCode: shortCode})
+			return
+		case serviceErrors.ErrorCodeGone:
+			renderPage(c, http.StatusGone, "gone", pageData{Title: "Link no longer available", ShortCode: shortCode})
+			return
+		case serviceErrors.ErrorCodeUnavailable:
+			log.Warn().Err(err).Str("short_code", shortCode).Msg("Storage unavailable while resolving short URL")
+			renderPage(c, http.StatusServiceUnavailable, "error", pageData{Title: "Temporarily unavailable"})
+			return
+		}
+	}
+
+	log.Error().Err(err).Str("short_code", shortCode).Msg("Failed to resolve short URL")
+	renderPage(c, http.StatusInternalServerError, "error", pageData{Title: "Something went wrong"})
+}
+
+func (h *RedirectHandler) renderPasswordPrompt(c *gin.Context, status int, shortCode, message string) {
+	renderPage(c, status, "password", pageData{
+		Title:     "Password required",
+		ShortCode: shortCode,
+		Message:   message,
+	})
+}
+
+func (h *RedirectHandler) renderNotFound(c *gin.Context, shortCode string) {
+	renderPage(c, http.StatusNotFound, "not_found", pageData{
+		Title:     "Link not found",
+		ShortCode: shortCode,
+	})
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"errors" 
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
@@ -19,6 +23,19 @@ func NewURLHandler(service services.URLService) *URLHandler {
 	return &URLHandler{service: service}
 }
 
+// actorHeader names who is making a change, for destination history. The
+// client IP is recorded when it is absent.
+const actorHeader = "X-Actor"
+
+// actorContext returns the request context carrying the caller as actor.
+func actorContext(c *gin.Context) context.Context {
+	actor := strings.TrimSpace(c.GetHeader(actorHeader))
+	if actor == "" {
+		actor = c.ClientIP()
+	}
+	return services.WithActor(c.Request.Context(), actor)
+}
+
 func (h *URLHandler) CreateShortURL(c *gin.Context) {
 	var req dto.CreateURLRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -31,7 +48,7 @@ func (h *URLHandler) CreateShortURL(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.CreateShortURL(c.Request.Context(), &req)
+	resp, err := h.service.CreateShortURL(actorContext(c), &req)
 	if err != nil {
 		h.handleServiceError(c, err)
 		return
@@ -83,7 +100,7 @@ func (h *URLHandler) UpdateShortURL(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.UpdateShortURL(c.Request.Context(), shortCode, &req)
+	resp, err := h.service.UpdateShortURL(actorContext(c), shortCode, &req)
 	if err != nil {
 		h.handleServiceError(c, err)
 		return
@@ -110,10 +127,103 @@ func (h *URLHandler) DeleteShortURL(c *gin.Context) {
 		return
 	}
 
-	log.Info().Str("short_code", shortCode).Msg("Short URL deleted successfully")
+	log.Info().Str("short_code", shortCode).Msg("Short URL moved to trash")
 	c.JSON(http.StatusOK, gin.H{"message": "Short URL deleted successfully"})
 }
 
+func (h *URLHandler) ListTrash(c *gin.Context) {
+	var req dto.ListURLsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid query parameters")
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Invalid query parameters",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.service.ListTrash(c.Request.Context(), &req)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *URLHandler) RestoreURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Missing short code",
+			Message: "Short code parameter is required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.service.RestoreURL(c.Request.Context(), shortCode)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	log.Info().Str("short_code", shortCode).Msg("Short URL restored from trash")
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *URLHandler) GetHistory(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Missing short code",
+			Message: "Short code parameter is required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.service.GetHistory(c.Request.Context(), shortCode)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *URLHandler) RollbackURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Missing short code",
+			Message: "Short code parameter is required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Invalid version",
+			Message: "Version must be a positive integer",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.service.RollbackURL(actorContext(c), shortCode, version)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	log.Info().Str("short_code", shortCode).Int("version", version).Str("url", resp.URL).Msg("Short URL rolled back")
+	c.JSON(http.StatusOK, resp)
+}
+
 func (h *URLHandler) GetStatistics(c *gin.Context) {
 	shortCode := c.Param("shortCode")
 	if shortCode == "" {
@@ -134,12 +244,118 @@ func (h *URLHandler) GetStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+func (h *URLHandler) ListURLs(c *gin.Context) {
+	var req dto.ListURLsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid query parameters")
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Invalid query parameters",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.service.ListURLs(c.Request.Context(), &req)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *URLHandler) UnlockURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Missing short code",
+			Message: "Short code parameter is required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	var req dto.UnlockURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Invalid request payload",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.service.UnlockURL(c.Request.Context(), shortCode, c.ClientIP(), &req)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *URLHandler) DisableURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Missing short code",
+			Message: "Short code parameter is required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	// The body is optional; a bare POST disables without a reason.
+	var req dto.DisableURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.Warn().Err(err).Msg("Invalid request payload")
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Invalid request payload",
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.service.DisableURL(c.Request.Context(), shortCode, &req)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	log.Info().Str("short_code", shortCode).Str("reason", req.Reason).Bool("legal", req.Legal).Msg("Short URL disabled")
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *URLHandler) EnableURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	if shortCode == "" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Missing short code",
+			Message: "Short code parameter is required",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	resp, err := h.service.EnableURL(c.Request.Context(), shortCode)
+	if err != nil {
+		h.handleServiceError(c, err)
+		return
+	}
+
+	log.Info().Str("short_code", shortCode).Msg("Short URL enabled")
+	c.JSON(http.StatusOK, resp)
+}
+
 func (h *URLHandler) handleServiceError(c *gin.Context, err error) {
 	var serviceErr *serviceErrors.ServiceError
 	if errors.As(err, &serviceErr) {
 		var statusCode int
 		switch serviceErr.Code {
-		case serviceErrors.ErrorCodeNotFound:
+		case serviceErrors.ErrorCodeNotFound, serviceErrors.ErrorCodeNotYetActive:
 			statusCode = http.StatusNotFound
 		case serviceErrors.ErrorCodeDuplicate:
 			statusCode = http.StatusConflict
@@ -147,6 +363,16 @@ func (h *URLHandler) handleServiceError(c *gin.Context, err error) {
 			statusCode = http.StatusBadRequest
 		case serviceErrors.ErrorCodeBadRequest:
 			statusCode = http.StatusBadRequest
+		case serviceErrors.ErrorCodeUnavailable:
+			statusCode = http.StatusServiceUnavailable
+		case serviceErrors.ErrorCodeGone:
+			statusCode = http.StatusGone
+		case serviceErrors.ErrorCodeUnauthorized:
+			statusCode = http.StatusUnauthorized
+		case serviceErrors.ErrorCodeTooManyRequests:
+			statusCode = http.StatusTooManyRequests
+		case serviceErrors.ErrorCodeUnavailableForLegalReasons:
+			statusCode = http.StatusUnavailableForLegalReasons
 		default:
 			statusCode = http.StatusInternalServerError
 		}
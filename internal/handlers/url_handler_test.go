@@ -0,0 +1,588 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rowjay/url-shortening-service/internal/counter"
+	"github.com/rowjay/url-shortening-service/internal/dto"
+	"github.com/rowjay/url-shortening-service/internal/models"
+	"github.com/rowjay/url-shortening-service/internal/repository"
+	"github.com/rowjay/url-shortening-service/internal/services"
+)
+
+// directClicks records clicks straight into the aggregator so tests can
+// assert on counts without waiting for a queue to drain.
+type directClicks struct {
+	*counter.Aggregator
+}
+
+func (d directClicks) RecordClick(ctx context.Context, shortCode string) {
+	d.Increment(shortCode)
+}
+
+func newTestRouter() *gin.Engine {
+	return newTestRouterFor(repository.NewMemoryURLRepository())
+}
+
+func newTestRouterFor(repo repository.URLRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	aggregator := counter.NewAggregator(repo, time.Hour)
+	service := services.NewURLService(repo, repository.NewMemoryHistoryRepository(), directClicks{aggregator}, aggregator, services.Options{})
+	urlHandler := NewURLHandler(service)
+	redirectHandler := NewRedirectHandler(service, http.StatusFound)
+
+	r := gin.New()
+	// As in main with no trusted_proxies configured.
+	_ = r.SetTrustedProxies(nil)
+	r.POST("/api/v1/shorten", urlHandler.CreateShortURL)
+	r.GET("/api/v1/shorten", urlHandler.ListURLs)
+	r.POST("/api/v1/shorten/batch", urlHandler.BatchCreateURLs)
+	r.DELETE("/api/v1/shorten/batch", urlHandler.BatchDeleteURLs)
+	r.GET("/api/v1/shorten/:shortCode", urlHandler.GetOriginalURL)
+	r.PUT("/api/v1/shorten/:shortCode", urlHandler.UpdateShortURL)
+	r.DELETE("/api/v1/shorten/:shortCode", urlHandler.DeleteShortURL)
+	r.GET("/api/v1/shorten/:shortCode/stats", urlHandler.GetStatistics)
+	r.GET("/api/v1/shorten/:shortCode/history", urlHandler.GetHistory)
+	r.POST("/api/v1/shorten/:shortCode/rollback/:version", urlHandler.RollbackURL)
+	r.POST("/api/v1/shorten/:shortCode/unlock", urlHandler.UnlockURL)
+	r.POST("/api/v1/shorten/:shortCode/disable", urlHandler.DisableURL)
+	r.POST("/api/v1/shorten/:shortCode/enable", urlHandler.EnableURL)
+	r.POST("/api/v1/shorten/:shortCode/restore", urlHandler.RestoreURL)
+	r.GET("/api/v1/trash", urlHandler.ListTrash)
+	r.POST("/api/v1/import", urlHandler.ImportURLs)
+	r.GET("/api/v1/export", urlHandler.ExportURLs)
+	r.GET("/:shortCode", redirectHandler.Redirect)
+	r.POST("/:shortCode", redirectHandler.Unlock)
+	return r
+}
+
+func doRequest(r http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestURLHandlerLifecycle(t *testing.T) {
+	r := newTestRouter()
+
+	w := doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com","customCode":"golang"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	w = doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com","customCode":"golang"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate POST status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/v1/shorten/golang", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("GET status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w = doRequest(r, http.MethodPut, "/api/v1/shorten/golang", `{"url":"https://go.dev"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("PUT status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/v1/shorten/golang/stats", "")
+	var stats dto.GetStatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("stats body: %v", err)
+	}
+	if stats.URL != "https://go.dev" || stats.AccessCount != 1 {
+		t.Errorf("stats = %+v, want updated URL and one access", stats)
+	}
+
+	w = doRequest(r, http.MethodDelete, "/api/v1/shorten/golang", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("DELETE status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/v1/shorten/golang", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET after delete status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestURLHandlerRejectsInvalidPayload(t *testing.T) {
+	r := newTestRouter()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Malformed JSON", `{"url":`},
+		{"Unsupported scheme", `{"url":"ftp://example.com"}`},
+		{"Invalid custom code", `{"url":"https://example.com","customCode":"a-b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(r, http.MethodPost, "/api/v1/shorten", tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("POST status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestURLHandlerListURLs(t *testing.T) {
+	r := newTestRouter()
+
+	for _, code := range []string{"first1", "second2", "third3"} {
+		w := doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/`+code+`","customCode":"`+code+`"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("POST status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+		}
+	}
+
+	w := doRequest(r, http.MethodGet, "/api/v1/shorten?sort=-shortCode&perPage=2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET list status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var list dto.ListURLsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("list body: %v", err)
+	}
+	if list.TotalItems != 3 || len(list.Items) != 2 || list.Items[0].ShortCode != "third3" || list.NextCursor == "" {
+		t.Errorf("list = %+v, want third3 first of 3 with a next cursor", list)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/v1/shorten?sort=-shortCode&perPage=2&cursor="+list.NextCursor, "")
+	var next dto.ListURLsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &next); err != nil {
+		t.Fatalf("list body: %v", err)
+	}
+	if len(next.Items) != 1 || next.Items[0].ShortCode != "first1" || next.NextCursor != "" {
+		t.Errorf("second page = %+v, want only first1", next)
+	}
+
+	for _, query := range []string{"perPage=abc", "sort=bogus", "createdAfter=yesterday"} {
+		if w := doRequest(r, http.MethodGet, "/api/v1/shorten?"+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET list ?%s status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	r := newTestRouter()
+
+	doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/a","customCode":"found"}`)
+	doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/b","customCode":"moved","redirectType":301}`)
+
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"Default status", "/found", http.StatusFound, "https://example.com/a"},
+		{"Per-link status", "/moved", http.StatusMovedPermanently, "https://example.com/b"},
+		{"Unknown code", "/missing", http.StatusNotFound, ""},
+		{"Invalid code", "/favicon.ico", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRequest(r, http.MethodGet, tt.path, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			if tt.wantStatus == http.StatusNotFound && !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+				t.Errorf("Content-Type = %q, want HTML", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	w := doRequest(r, http.MethodGet, "/api/v1/shorten/found/stats", "")
+	var stats dto.GetStatsResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &stats)
+	if stats.AccessCount != 1 {
+		t.Errorf("AccessCount after redirect = %d, want 1", stats.AccessCount)
+	}
+}
+
+func TestExpiredLinksAreGone(t *testing.T) {
+	repo := repository.NewMemoryURLRepository()
+	expiredAt := time.Now().Add(-time.Minute)
+	if err := repo.Create(context.Background(), &models.ShortURL{URL: "https://example.com", ShortCode: "promo", ExpiresAt: &expiredAt}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	r := newTestRouterFor(repo)
+
+	w := doRequest(r, http.MethodGet, "/promo", "")
+	if w.Code != http.StatusGone || !strings.Contains(w.Body.String(), "no longer available") {
+		t.Errorf("redirect status = %d, want %d with the gone page", w.Code, http.StatusGone)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/v1/shorten/promo", ""); w.Code != http.StatusGone {
+		t.Errorf("GET status = %d, want %d", w.Code, http.StatusGone)
+	}
+
+	// Extending the expiry brings the link back.
+	w = doRequest(r, http.MethodPut, "/api/v1/shorten/promo", `{"url":"https://example.com","ttlSeconds":3600}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := doRequest(r, http.MethodGet, "/promo", ""); w.Code != http.StatusFound {
+		t.Errorf("redirect after extending status = %d, want %d", w.Code, http.StatusFound)
+	}
+}
+
+func TestClickLimitedRedirects(t *testing.T) {
+	r := newTestRouter()
+
+	doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/a","customCode":"single","maxClicks":1}`)
+	doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/b","customCode":"backup","maxClicks":1,"fallbackUrl":"https://example.com/over"}`)
+
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"First click", "/single", http.StatusFound, "https://example.com/a"},
+		{"Limit reached", "/single", http.StatusGone, ""},
+		{"First click with fallback", "/backup", http.StatusFound, "https://example.com/b"},
+		{"Fallback after limit", "/backup", http.StatusFound, "https://example.com/over"},
+	}
+	for _, tt := range tests {
+		w := doRequest(r, http.MethodGet, tt.path, "")
+		if w.Code != tt.wantStatus || w.Header().Get("Location") != tt.wantLocation {
+			t.Errorf("%s: status = %d, Location = %q, want %d and %q", tt.name, w.Code, w.Header().Get("Location"), tt.wantStatus, tt.wantLocation)
+		}
+	}
+}
+
+func TestPasswordProtectedRedirects(t *testing.T) {
+	r := newTestRouter()
+
+	w := doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/internal","customCode":"intdocs","password":"hunter22"}`)
+	if w.Code != http.StatusCreated || strings.Contains(w.Body.String(), "hunter22") || strings.Contains(w.Body.String(), "passwordHash") {
+		t.Fatalf("POST status = %d, body = %s, want 201 without the password", w.Code, w.Body)
+	}
+
+	w = doRequest(r, http.MethodGet, "/intdocs", "")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `<form method="post"`) {
+		t.Fatalf("GET status = %d, want %d with the password prompt", w.Code, http.StatusUnauthorized)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/v1/shorten/intdocs", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("API GET status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	unlock := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/intdocs", strings.NewReader("password="+password))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := unlock("wrong"); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "Incorrect password") {
+		t.Errorf("POST wrong password status = %d, want %d with an error", w.Code, http.StatusUnauthorized)
+	}
+
+	w = unlock("hunter22")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "https://example.com/internal" {
+		t.Fatalf("POST status = %d, Location = %q, want %d to the destination", w.Code, w.Header().Get("Location"), http.StatusSeeOther)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].Path != "/intdocs" {
+		t.Fatalf("cookies = %+v, want one HttpOnly cookie scoped to /intdocs", cookies)
+	}
+
+	// The cookie lets later clicks through without prompting.
+	req := httptest.NewRequest(http.MethodGet, "/intdocs", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://example.com/internal" {
+		t.Errorf("GET with cookie status = %d, Location = %q, want %d to the destination", w.Code, w.Header().Get("Location"), http.StatusFound)
+	}
+
+	w = doRequest(r, http.MethodPost, "/api/v1/shorten/intdocs/unlock", `{"password":"hunter22"}`)
+	var unlocked dto.UnlockURLResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &unlocked)
+	if w.Code != http.StatusOK || unlocked.URL != "https://example.com/internal" || unlocked.AccessToken == "" {
+		t.Errorf("API unlock status = %d, body = %s, want the destination and a token", w.Code, w.Body)
+	}
+}
+
+func TestUnlockLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	r := newTestRouter()
+
+	w := doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/internal","customCode":"intdocs","password":"hunter22"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	// Every guess claims a new client address; they all come from the same
+	// untrusted peer, so they share one limit.
+	for i := 0; i <= constants.DefaultUnlockMaxAttempts; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/intdocs", strings.NewReader("password=wrong"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		want := http.StatusUnauthorized
+		if i == constants.DefaultUnlockMaxAttempts {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Fatalf("guess %d status = %d, want %d", i+1, w.Code, want)
+		}
+	}
+}
+
+func TestScheduledLinkRedirects(t *testing.T) {
+	repo := repository.NewMemoryURLRepository()
+	launch := time.Now().Add(time.Hour)
+	if err := repo.Create(context.Background(), &models.ShortURL{URL: "https://example.com/launch", ShortCode: "launch", ActiveFrom: &launch}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	r := newTestRouterFor(repo)
+
+	w := doRequest(r, http.MethodGet, "/launch", "")
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "not active yet") {
+		t.Errorf("redirect status = %d, want %d with the not yet available page", w.Code, http.StatusNotFound)
+	}
+
+	w = doRequest(r, http.MethodPut, "/api/v1/shorten/launch", `{"url":"https://example.com/launch","clearActiveFrom":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := doRequest(r, http.MethodGet, "/launch", ""); w.Code != http.StatusFound {
+		t.Errorf("redirect after opening the window status = %d, want %d", w.Code, http.StatusFound)
+	}
+}
+
+func TestDisabledLinkRedirects(t *testing.T) {
+	r := newTestRouter()
+	if w := doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/paused","customCode":"paused"}`); w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	w := doRequest(r, http.MethodPost, "/api/v1/shorten/paused/disable", "")
+	var stats dto.GetStatsResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &stats)
+	if w.Code != http.StatusOK || stats.Enabled {
+		t.Fatalf("disable status = %d, body = %s, want %d and enabled false", w.Code, w.Body, http.StatusOK)
+	}
+	if w := doRequest(r, http.MethodGet, "/paused", ""); w.Code != http.StatusNotFound {
+		t.Errorf("redirect while disabled status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/v1/shorten/paused/stats", ""); w.Code != http.StatusOK {
+		t.Errorf("stats while disabled status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if w := doRequest(r, http.MethodPost, "/api/v1/shorten/paused/disable", `{"reason":"takedown notice","legal":true}`); w.Code != http.StatusOK {
+		t.Fatalf("legal disable status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := doRequest(r, http.MethodGet, "/paused", ""); w.Code != http.StatusUnavailableForLegalReasons {
+		t.Errorf("redirect while legally disabled status = %d, want %d", w.Code, http.StatusUnavailableForLegalReasons)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/v1/shorten/paused", ""); w.Code != http.StatusUnavailableForLegalReasons {
+		t.Errorf("API lookup while legally disabled status = %d, want %d", w.Code, http.StatusUnavailableForLegalReasons)
+	}
+
+	if w := doRequest(r, http.MethodPost, "/api/v1/shorten/paused/enable", ""); w.Code != http.StatusOK {
+		t.Fatalf("enable status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := doRequest(r, http.MethodGet, "/paused", ""); w.Code != http.StatusFound {
+		t.Errorf("redirect after enabling status = %d, want %d", w.Code, http.StatusFound)
+	}
+}
+
+func TestTrashEndpoints(t *testing.T) {
+	r := newTestRouter()
+	if w := doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/oops","customCode":"oops"}`); w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if w := doRequest(r, http.MethodDelete, "/api/v1/shorten/oops", ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := doRequest(r, http.MethodGet, "/oops", ""); w.Code != http.StatusNotFound {
+		t.Errorf("redirect of a trashed link status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w := doRequest(r, http.MethodGet, "/api/v1/trash", "")
+	var trash dto.ListURLsResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &trash)
+	if w.Code != http.StatusOK || len(trash.Items) != 1 || trash.Items[0].DeletedAt == nil {
+		t.Fatalf("GET trash status = %d, body = %s, want the deleted link", w.Code, w.Body)
+	}
+
+	if w := doRequest(r, http.MethodPost, "/api/v1/shorten/oops/restore", ""); w.Code != http.StatusOK {
+		t.Fatalf("restore status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := doRequest(r, http.MethodGet, "/oops", ""); w.Code != http.StatusFound {
+		t.Errorf("redirect after restoring status = %d, want %d", w.Code, http.StatusFound)
+	}
+	if w := doRequest(r, http.MethodPost, "/api/v1/shorten/oops/restore", ""); w.Code != http.StatusNotFound {
+		t.Errorf("restoring a live link status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHistoryEndpoints(t *testing.T) {
+	r := newTestRouter()
+	if w := doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/v1","customCode":"docs"}`); w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/shorten/docs", strings.NewReader(`{"url":"https://example.com/v2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Actor", "alice")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/v1/shorten/docs/history", "")
+	var history dto.HistoryResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &history)
+	if w.Code != http.StatusOK || len(history.Versions) != 2 {
+		t.Fatalf("GET history status = %d, body = %s, want 2 versions", w.Code, w.Body)
+	}
+	if got := history.Versions[1]; got.OldURL != "https://example.com/v1" || got.NewURL != "https://example.com/v2" || got.Actor != "alice" {
+		t.Errorf("version 2 = %+v, want v1 -> v2 by alice", got)
+	}
+	if history.Versions[0].Actor == "" {
+		t.Error("version 1 actor is empty, want the client IP when X-Actor is absent")
+	}
+
+	if w := doRequest(r, http.MethodPost, "/api/v1/shorten/docs/rollback/1", ""); w.Code != http.StatusOK {
+		t.Fatalf("rollback status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := doRequest(r, http.MethodGet, "/docs", ""); w.Header().Get("Location") != "https://example.com/v1" {
+		t.Errorf("redirect after rollback Location = %q, want the version 1 destination", w.Header().Get("Location"))
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/shorten/docs/rollback/abc":  http.StatusBadRequest,
+		"/api/v1/shorten/docs/rollback/0":    http.StatusBadRequest,
+		"/api/v1/shorten/docs/rollback/42":   http.StatusNotFound,
+		"/api/v1/shorten/missing/rollback/1": http.StatusNotFound,
+	} {
+		if w := doRequest(r, http.MethodPost, path, ""); w.Code != want {
+			t.Errorf("POST %s status = %d, want %d", path, w.Code, want)
+		}
+	}
+}
+
+func TestImportExportEndpoints(t *testing.T) {
+	r := newTestRouter()
+	if w := doRequest(r, http.MethodPost, "/api/v1/shorten", `{"url":"https://example.com/old","customCode":"taken"}`); w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d: %s", w.Code, w.Body)
+	}
+
+	input := "url,customCode,metadata\n" +
+		"https://example.com/docs,docs,\"{\"\"team\"\":\"\"growth\"\"}\"\n" +
+		"https://example.com/new,taken,\n" +
+		"not a url,,\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import?onConflict=overwrite", strings.NewReader(input))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("import returned %d lines, want 3 results and a summary: %s", len(lines), w.Body)
+	}
+	var statuses []string
+	for _, line := range lines[:3] {
+		var result dto.ImportResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("result %q: %v", line, err)
+		}
+		statuses = append(statuses, result.Status)
+	}
+	if strings.Join(statuses, ",") != "created,updated,invalid" {
+		t.Errorf("import statuses = %v, want created, updated, invalid", statuses)
+	}
+	var trailer struct {
+		Summary dto.ImportSummary `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(lines[3]), &trailer); err != nil || trailer.Summary.Rows != 3 || trailer.Summary.Invalid != 1 {
+		t.Errorf("import summary = %s, want 3 rows with 1 invalid", lines[3])
+	}
+	if w := doRequest(r, http.MethodGet, "/taken", ""); w.Header().Get("Location") != "https://example.com/new" {
+		t.Errorf("redirect after overwrite Location = %q, want the imported URL", w.Header().Get("Location"))
+	}
+
+	for _, path := range []string{"/api/v1/import?format=xml", "/api/v1/import?format=csv&onConflict=merge", "/api/v1/import"} {
+		if w := doRequest(r, http.MethodPost, path, "url\n"); w.Code != http.StatusBadRequest {
+			t.Errorf("POST %s status = %d, want %d", path, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/v1/export?format=csv", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Disposition") != `attachment; filename="links.csv"` {
+		t.Fatalf("CSV export status = %d, Content-Disposition = %q", w.Code, w.Header().Get("Content-Disposition"))
+	}
+	if rows := strings.Split(strings.TrimSpace(w.Body.String()), "\n"); len(rows) != 3 || !strings.HasPrefix(rows[1], `docs,https://example.com/docs,"{""team"":""growth""}",`) {
+		t.Errorf("CSV export = %s, want a header and the two links", w.Body)
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/v1/export", "")
+	var first dto.GetStatsResponse
+	line, _, _ := strings.Cut(w.Body.String(), "\n")
+	if err := json.Unmarshal([]byte(line), &first); err != nil || first.ShortCode != "docs" || first.Metadata["team"] != "growth" {
+		t.Errorf("JSON Lines export first line = %q, want the docs link", line)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/x-ndjson") {
+		t.Errorf("JSON Lines export Content-Type = %q", got)
+	}
+}
+
+func TestBatchEndpoints(t *testing.T) {
+	r := newTestRouter()
+
+	w := doRequest(r, http.MethodPost, "/api/v1/shorten/batch", `{"items":[{"url":"https://example.com/a","customCode":"alpha"},{"url":"https://example.com/b","customCode":"alpha"},{"url":"nope"}]}`)
+	var created dto.BatchCreateResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusOK || created.Created != 1 || created.Duplicates != 1 || created.Invalid != 1 {
+		t.Fatalf("POST batch status = %d, body = %s, want one of each outcome", w.Code, w.Body)
+	}
+	if link := created.Items[0].Link; link == nil || link.ShortCode != "alpha" {
+		t.Errorf("POST batch item 0 = %+v, want the created link", created.Items[0])
+	}
+
+	w = doRequest(r, http.MethodDelete, "/api/v1/shorten/batch", `{"shortCodes":["alpha","missing"]}`)
+	var deleted dto.BatchDeleteResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &deleted)
+	if w.Code != http.StatusOK || deleted.Deleted != 1 || deleted.NotFound != 1 {
+		t.Fatalf("DELETE batch status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := doRequest(r, http.MethodGet, "/api/v1/shorten/alpha", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET deleted link status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	tooMany := `{"shortCodes":[` + strings.Repeat(`"abcd",`, 100) + `"abcd"]}`
+	for method, body := range map[string]string{http.MethodPost: `{"items":[]}`, http.MethodDelete: tooMany} {
+		if w := doRequest(r, method, "/api/v1/shorten/batch", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s batch status = %d, want %d", method, w.Code, http.StatusBadRequest)
+		}
+	}
+}
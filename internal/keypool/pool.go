@@ -0,0 +1,274 @@
+// Package keypool hands out short codes reserved in storage ahead of time,
+// so creating a link needs no existence check on the request path.
+package keypool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+)
+
+// fillAttempts bounds the batches a refill tries when every candidate
+// turns out to be taken.
+const fillAttempts = 3
+
+// ErrStopped is returned by Take once the pool has been stopped.
+var ErrStopped = errors.New("key pool stopped")
+
+// Store reserves codes so they are never handed out twice.
+// repository.KeyRepository satisfies it.
+type Store interface {
+	Reserve(ctx context.Context, candidates []string) ([]string, error)
+	// MaxReserve is how many candidates a Reserve call considers at most,
+	// or 0 when it considers them all.
+	MaxReserve() int
+}
+
+// Generator proposes candidate codes. The codegen generators satisfy it.
+type Generator interface {
+	Generate(ctx context.Context) (string, error)
+}
+
+type Options struct {
+	// Size is how many candidates a refill generates and reserves. A store
+	// that considers fewer per call is sent them in several calls.
+	Size int
+	// LowWater is the number of spare codes below which a refill starts. It
+	// is capped below Size.
+	LowWater int
+}
+
+// Stats is a point-in-time snapshot of the pool counters.
+type Stats struct {
+	Available int    `json:"available"`
+	Served    uint64 `json:"served"`
+	Reserved  uint64 `json:"reserved"`
+	Refills   uint64 `json:"refills"`
+	Failed    uint64 `json:"failed"`
+}
+
+// Pool keeps reserved codes in memory. Take hands them out in order, and a
+// background goroutine reserves another batch whenever fewer than LowWater
+// remain. Codes still in memory when the process exits stay reserved and
+// are never handed out, so a restart cannot reuse them.
+type Pool struct {
+	store Store
+	codes Generator
+	opts  Options
+
+	refill chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu   sync.Mutex
+	keys []string
+	// filled is closed when the next refill finishes; err is what it
+	// failed with.
+	filled chan struct{}
+	err    error
+
+	served   atomic.Uint64
+	reserved atomic.Uint64
+	refills  atomic.Uint64
+	failed   atomic.Uint64
+}
+
+func New(store Store, codes Generator, opts Options) *Pool {
+	if opts.Size <= 0 {
+		opts.Size = 1
+	}
+	opts.LowWater = min(max(opts.LowWater, 0), opts.Size-1)
+
+	return &Pool{
+		store:  store,
+		codes:  codes,
+		opts:   opts,
+		refill: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		filled: make(chan struct{}),
+	}
+}
+
+// Start fills the pool in the background and keeps it above the low-water
+// mark until Stop.
+func (p *Pool) Start() {
+	p.refill <- struct{}{}
+	go p.run()
+}
+
+func (p *Pool) run() {
+	defer close(p.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-p.stop
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.refill:
+		}
+		// Keep refilling while below the low-water mark, unless a refill
+		// failed or reserved nothing; the next Take asks again.
+		for p.fill(ctx) && p.Available() < p.opts.LowWater {
+		}
+	}
+}
+
+// fill reserves one batch and wakes the callers waiting for it. It reports
+// whether it added any codes.
+func (p *Pool) fill(ctx context.Context) bool {
+	p.refills.Add(1)
+	var (
+		reserved []string
+		err      error
+	)
+	for i := 0; i < fillAttempts && err == nil && len(reserved) == 0; i++ {
+		reserved, err = p.reserve(ctx)
+	}
+	if err == nil && len(reserved) == 0 {
+		err = errors.New("no candidate code was free")
+	}
+	if err != nil {
+		p.failed.Add(1)
+		log.Error().Err(err).Int("reserved", len(reserved)).Msg("Failed to refill the key pool")
+	}
+	p.reserved.Add(uint64(len(reserved)))
+
+	p.mu.Lock()
+	p.keys = append(p.keys, reserved...)
+	p.err = err
+	close(p.filled)
+	p.filled = make(chan struct{})
+	p.mu.Unlock()
+
+	return len(reserved) > 0 && err == nil
+}
+
+// reserve generates Size candidates and reserves the free ones, never
+// sending the store more than it considers per call, so no generated code
+// goes unused.
+func (p *Pool) reserve(ctx context.Context) ([]string, error) {
+	batch := p.opts.Size
+	if limit := p.store.MaxReserve(); limit > 0 {
+		batch = min(batch, limit)
+	}
+
+	var reserved []string
+	for remaining := p.opts.Size; remaining > 0; remaining -= batch {
+		candidates, err := p.candidates(ctx, min(batch, remaining))
+		if err != nil {
+			return reserved, err
+		}
+		free, err := p.store.Reserve(ctx, candidates)
+		reserved = append(reserved, free...)
+		if err != nil {
+			return reserved, err
+		}
+	}
+	return reserved, nil
+}
+
+// candidates generates n distinct codes.
+func (p *Pool) candidates(ctx context.Context, n int) ([]string, error) {
+	seen := make(map[string]bool, n)
+	candidates := make([]string, 0, n)
+	for len(candidates) < n {
+		code, err := p.codes.Generate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[code] {
+			seen[code] = true
+			candidates = append(candidates, code)
+		}
+	}
+	return candidates, nil
+}
+
+// Take returns the next reserved code. When the pool is empty it waits for
+// a refill, and fails if that refill does or ctx ends first.
+func (p *Pool) Take(ctx context.Context) (string, error) {
+	for {
+		p.mu.Lock()
+		if len(p.keys) > 0 {
+			key := p.keys[0]
+			p.keys = p.keys[1:]
+			low := len(p.keys) < p.opts.LowWater
+			p.mu.Unlock()
+
+			if low {
+				p.requestRefill()
+			}
+			p.served.Add(1)
+			return key, nil
+		}
+		filled := p.filled
+		p.mu.Unlock()
+
+		p.requestRefill()
+		select {
+		case <-filled:
+		case <-p.stop:
+			return "", ErrStopped
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+
+		p.mu.Lock()
+		empty, err := len(p.keys) == 0, p.err
+		p.mu.Unlock()
+		if empty && err != nil {
+			return "", err
+		}
+	}
+}
+
+func (p *Pool) requestRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+// Available returns how many codes are ready to be handed out.
+func (p *Pool) Available() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.keys)
+}
+
+// Stop ends background refills and waits for one in progress. Codes left
+// in the pool are discarded.
+func (p *Pool) Stop(ctx context.Context) error {
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Available: p.Available(),
+		Served:    p.served.Load(),
+		Reserved:  p.reserved.Load(),
+		Refills:   p.refills.Load(),
+		Failed:    p.failed.Load(),
+	}
+}
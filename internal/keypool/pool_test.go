@@ -0,0 +1,218 @@
+package keypool
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryStore reserves every candidate not reserved before. With a limit,
+// it considers only that many candidates per call.
+type memoryStore struct {
+	mu       sync.Mutex
+	reserved map[string]bool
+	calls    int
+	err      error
+	limit    int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{reserved: make(map[string]bool)}
+}
+
+func (s *memoryStore) MaxReserve() int {
+	return s.limit
+}
+
+func (s *memoryStore) Reserve(ctx context.Context, candidates []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.limit > 0 {
+		candidates = candidates[:min(len(candidates), s.limit)]
+	}
+	var free []string
+	for _, code := range candidates {
+		if !s.reserved[code] {
+			s.reserved[code] = true
+			free = append(free, code)
+		}
+	}
+	return free, nil
+}
+
+func (s *memoryStore) reserveCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// countingGenerator proposes consecutive numbers.
+type countingGenerator struct {
+	next atomic.Uint64
+}
+
+func (g *countingGenerator) Generate(ctx context.Context) (string, error) {
+	return strconv.FormatUint(g.next.Add(1)-1, 10), nil
+}
+
+func TestPoolHandsOutUniqueCodes(t *testing.T) {
+	p := New(newMemoryStore(), &countingGenerator{}, Options{Size: 10, LowWater: 3})
+	p.Start()
+	defer p.Stop(context.Background())
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				code, err := p.Take(context.Background())
+				if err != nil {
+					t.Errorf("Take() error = %v", err)
+					return
+				}
+				mu.Lock()
+				if seen[code] {
+					t.Errorf("Take() = %q twice", code)
+				}
+				seen[code] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stats := p.Stats(); stats.Served != 200 || stats.Reserved < 200 {
+		t.Errorf("Stats() = %+v, want 200 served of at least 200 reserved", stats)
+	}
+}
+
+func TestPoolRefillsBelowLowWater(t *testing.T) {
+	store := newMemoryStore()
+	p := New(store, &countingGenerator{}, Options{Size: 10, LowWater: 3})
+	p.Start()
+	defer p.Stop(context.Background())
+
+	waitFor(t, func() bool { return p.Available() == 10 })
+	for i := 0; i < 7; i++ {
+		if _, err := p.Take(context.Background()); err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+	}
+	if got := store.reserveCalls(); got != 1 {
+		t.Errorf("Reserve called %d times above the low-water mark, want 1", got)
+	}
+
+	if _, err := p.Take(context.Background()); err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	waitFor(t, func() bool { return p.Available() == 12 })
+	if got := store.reserveCalls(); got != 2 {
+		t.Errorf("Reserve called %d times, want 2", got)
+	}
+}
+
+func TestPoolRespectsTheStoreLimit(t *testing.T) {
+	store := newMemoryStore()
+	store.limit = 4
+	codes := &countingGenerator{}
+	p := New(store, codes, Options{Size: 10, LowWater: 3})
+	p.Start()
+	defer p.Stop(context.Background())
+
+	// Every generated candidate is considered, over several calls.
+	waitFor(t, func() bool { return p.Available() == 10 })
+	if got := store.reserveCalls(); got != 3 {
+		t.Errorf("Reserve called %d times, want 3", got)
+	}
+	if got := codes.next.Load(); got != 10 {
+		t.Errorf("generated %d candidates, want 10", got)
+	}
+}
+
+func TestPoolSurfacesStoreError(t *testing.T) {
+	store := newMemoryStore()
+	store.err = errors.New("storage down")
+	p := New(store, &countingGenerator{}, Options{Size: 10, LowWater: 3})
+	p.Start()
+	defer p.Stop(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := p.Take(ctx); err == nil || err.Error() != "storage down" {
+		t.Errorf("Take() error = %v, want the store error", err)
+	}
+	if stats := p.Stats(); stats.Failed == 0 {
+		t.Errorf("Stats() = %+v, want a failed refill", stats)
+	}
+
+	store.mu.Lock()
+	store.err = nil
+	store.mu.Unlock()
+	if _, err := p.Take(ctx); err != nil {
+		t.Errorf("Take() after recovery error = %v", err)
+	}
+}
+
+func TestPoolNeverReusesCodesAcrossRestarts(t *testing.T) {
+	store := newMemoryStore()
+	seen := make(map[string]bool)
+	for restart := 0; restart < 3; restart++ {
+		// Each generator starts among the codes the previous pool still
+		// held when it stopped.
+		codes := &countingGenerator{}
+		codes.next.Store(uint64(restart * 5))
+		p := New(store, codes, Options{Size: 10, LowWater: 3})
+		p.Start()
+		// Take a few codes and stop with the rest still pooled.
+		for i := 0; i < 5; i++ {
+			code, err := p.Take(context.Background())
+			if err != nil {
+				t.Fatalf("restart %d: Take() error = %v", restart, err)
+			}
+			if seen[code] {
+				t.Fatalf("restart %d: Take() = %q, handed out before", restart, code)
+			}
+			seen[code] = true
+		}
+		if err := p.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+	}
+}
+
+func TestPoolTakeAfterStop(t *testing.T) {
+	store := newMemoryStore()
+	store.err = errors.New("storage down")
+	p := New(store, &countingGenerator{}, Options{Size: 10})
+	p.Start()
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if _, err := p.Take(context.Background()); !errors.Is(err, ErrStopped) {
+		t.Errorf("Take() error = %v, want ErrStopped", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// DestinationChange is one version of a short URL's destination. Version 1
+// is the destination the link was created with; later versions record who
+// moved it, when, and from where.
+type DestinationChange struct {
+	// URLID is the ID of the short URL record, so a short code reused after
+	// a purge starts a fresh history.
+	URLID     string    `json:"urlId" db:"url_id"`
+	ShortCode string    `json:"shortCode" db:"short_code"`
+	Version   int       `json:"version" db:"version"`
+	OldURL    string    `json:"oldUrl,omitempty" db:"old_url"`
+	NewURL    string    `json:"newUrl" db:"new_url"`
+	Actor     string    `json:"actor,omitempty" db:"actor"`
+	ChangedAt time.Time `json:"changedAt" db:"changed_at"`
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// SortField is a column short URLs can be listed by. Ties are always broken
+// by short code, which is unique, so every ordering is total.
+type SortField string
+
+const (
+	SortByCreated     SortField = "created"
+	SortByUpdated     SortField = "updated"
+	SortByAccessCount SortField = "access_count"
+	SortByShortCode   SortField = "short_code"
+)
+
+// ListFilter narrows a listing. Zero values are ignored, except that trashed
+// links are only listed when Trashed is set, and then exclusively.
+type ListFilter struct {
+	CreatedAfter   time.Time // inclusive
+	CreatedBefore  time.Time // exclusive
+	URLContains    string    // case-insensitive substring of the destination
+	MinAccessCount int64
+	ExpiredBefore  time.Time // only links with an expiry before this
+	Trashed        bool
+	DeletedBefore  time.Time // only links trashed before this; needs Trashed
+}
+
+// ListCursor is the sort key of the last item of a page. Listing with it
+// returns the items strictly after that item in the requested order.
+type ListCursor struct {
+	ShortCode   string
+	Created     time.Time
+	Updated     time.Time
+	AccessCount int64
+}
+
+func CursorFor(shortURL *ShortURL) *ListCursor {
+	return &ListCursor{
+		ShortCode:   shortURL.ShortCode,
+		Created:     shortURL.Created,
+		Updated:     shortURL.Updated,
+		AccessCount: shortURL.AccessCount,
+	}
+}
+
+// ListOptions select one page of short URLs. Page is 1-based and ignored
+// when After is set.
+type ListOptions struct {
+	Filter  ListFilter
+	SortBy  SortField
+	Desc    bool
+	Page    int
+	PerPage int
+	After   *ListCursor
+}
+
+// ListResult is one page of short URLs. TotalItems counts every item that
+// matches the filter, not only those after the cursor.
+type ListResult struct {
+	Items      []*ShortURL
+	TotalItems int
+}
+
+// Matches reports whether shortURL passes the filter.
+func (f *ListFilter) Matches(shortURL *ShortURL) bool {
+	if shortURL.Trashed() != f.Trashed {
+		return false
+	}
+	if !f.DeletedBefore.IsZero() && (shortURL.DeletedAt == nil || !shortURL.DeletedAt.Before(f.DeletedBefore)) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && shortURL.Created.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !shortURL.Created.Before(f.CreatedBefore) {
+		return false
+	}
+	if !f.ExpiredBefore.IsZero() && (shortURL.ExpiresAt == nil || !shortURL.ExpiresAt.Before(f.ExpiredBefore)) {
+		return false
+	}
+	if f.URLContains != "" && !strings.Contains(strings.ToLower(shortURL.URL), strings.ToLower(f.URLContains)) {
+		return false
+	}
+	return shortURL.AccessCount >= f.MinAccessCount
+}
+
+// Compare orders a before b by the sort field, then by short code. It
+// returns a negative number, zero or a positive number.
+func (o *ListOptions) Compare(a, b *ListCursor) int {
+	c := 0
+	switch o.SortBy {
+	case SortByCreated:
+		c = a.Created.Compare(b.Created)
+	case SortByUpdated:
+		c = a.Updated.Compare(b.Updated)
+	case SortByAccessCount:
+		c = compareInt64(a.AccessCount, b.AccessCount)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ShortCode, b.ShortCode)
+	}
+	if o.Desc {
+		return -c
+	}
+	return c
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -5,12 +5,125 @@ import (
 )
 
 type ShortURL struct {
-	ID          string    `json:"id" db:"id"`
-	URL         string    `json:"url" db:"url" validate:"required,url"`
-	ShortCode   string    `json:"shortCode" db:"short_code"`
-	AccessCount int64     `json:"accessCount" db:"access_count"`
-	Created     time.Time `json:"created" db:"created"`
-	Updated     time.Time `json:"updated" db:"updated"`
+	ID           string    `json:"id" db:"id"`
+	URL          string    `json:"url" db:"url" validate:"required,url"`
+	ShortCode    string    `json:"shortCode" db:"short_code"`
+	AccessCount  int64     `json:"accessCount" db:"access_count"`
+	RedirectType int       `json:"redirectType,omitempty" db:"redirect_type"`
+	Created      time.Time `json:"created" db:"created"`
+	Updated      time.Time `json:"updated" db:"updated"`
+	// ExpiresAt is when the link stops resolving; nil means never.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
+	// MaxClicks caps how often the link resolves; 0 means unlimited.
+	MaxClicks int64 `json:"maxClicks,omitempty" db:"max_clicks"`
+	// FallbackURL is where the link redirects once MaxClicks is reached.
+	FallbackURL string `json:"fallbackUrl,omitempty" db:"fallback_url"`
+	// PasswordHash is the bcrypt hash of the link's passphrase; empty means
+	// the link is not protected. It is never encoded to JSON, so neither API
+	// responses, archives nor shared caches carry it.
+	PasswordHash string `json:"-" db:"password_hash"`
+	// ActiveFrom and ActiveUntil bound when the link resolves; nil leaves
+	// that side of the window open.
+	ActiveFrom  *time.Time `json:"activeFrom,omitempty" db:"active_from"`
+	ActiveUntil *time.Time `json:"activeUntil,omitempty" db:"active_until"`
+	// Disabled links stop resolving but keep their statistics. They answer
+	// 451 instead of 404 when DisabledLegal is set.
+	Disabled       bool   `json:"disabled,omitempty" db:"disabled"`
+	DisabledReason string `json:"disabledReason,omitempty" db:"disabled_reason"`
+	DisabledLegal  bool   `json:"disabledLegal,omitempty" db:"disabled_legal"`
+	// DeletedAt is set while the link is in the trash. Trashed links do not
+	// resolve and keep their short code until they are purged.
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	// Metadata holds free-form labels, such as the ID a link had in the
+	// system it was imported from.
+	Metadata map[string]string `json:"metadata,omitempty" db:"metadata"`
+}
+
+// Clone returns a deep copy of su.
+func (su *ShortURL) Clone() *ShortURL {
+	clone := *su
+	clone.ExpiresAt = cloneTime(su.ExpiresAt)
+	clone.ActiveFrom = cloneTime(su.ActiveFrom)
+	clone.ActiveUntil = cloneTime(su.ActiveUntil)
+	clone.DeletedAt = cloneTime(su.DeletedAt)
+	clone.Metadata = CloneMetadata(su.Metadata)
+	return &clone
+}
+
+// CloneMetadata returns a copy of metadata, or nil when it is empty.
+func CloneMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	clone := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		clone[key] = value
+	}
+	return clone
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
+
+// Expired reports whether the link has expired at now.
+func (su *ShortURL) Expired(now time.Time) bool {
+	return su.ExpiresAt != nil && !now.Before(*su.ExpiresAt)
+}
+
+// NotYetActive reports whether now is before the link's activation window.
+func (su *ShortURL) NotYetActive(now time.Time) bool {
+	return su.ActiveFrom != nil && now.Before(*su.ActiveFrom)
+}
+
+// WindowClosed reports whether the link's activation window has ended at now.
+func (su *ShortURL) WindowClosed(now time.Time) bool {
+	return su.ActiveUntil != nil && !now.Before(*su.ActiveUntil)
+}
+
+// Trashed reports whether the link has been deleted but not yet purged.
+func (su *ShortURL) Trashed() bool {
+	return su.DeletedAt != nil
+}
+
+// ClickLimited reports whether the link has a click limit.
+func (su *ShortURL) ClickLimited() bool {
+	return su.MaxClicks > 0
+}
+
+// Protected reports whether resolving the link requires a password.
+func (su *ShortURL) Protected() bool {
+	return su.PasswordHash != ""
+}
+
+// ShortURLUpdate carries the mutable fields of a short URL. Nil fields are
+// left untouched; the Clear flags remove the corresponding timestamp.
+type ShortURLUpdate struct {
+	URL            *string
+	RedirectType   *int
+	ExpiresAt      *time.Time
+	ClearExpiresAt bool
+	MaxClicks      *int64
+	FallbackURL    *string
+	// PasswordHash of "" removes the password.
+	PasswordHash     *string
+	ActiveFrom       *time.Time
+	ClearActiveFrom  bool
+	ActiveUntil      *time.Time
+	ClearActiveUntil bool
+	Disabled         *bool
+	DisabledReason   *string
+	DisabledLegal    *bool
+	// DeletedAt moves the link to the trash; ClearDeletedAt restores it.
+	DeletedAt      *time.Time
+	ClearDeletedAt bool
+	// Metadata replaces the link's metadata when non-nil; an empty map
+	// removes it.
+	Metadata map[string]string
 }
 
 type PBShortURL struct {
@@ -22,6 +135,7 @@ type PBShortURL struct {
 	URL            string    `json:"url"`
 	ShortCode      string    `json:"short_code"`
 	AccessCount    int64     `json:"access_count"`
+	RedirectType   int       `json:"redirect_type"`
 }
 
 type CreateShortURLRequest struct {
@@ -57,6 +171,7 @@ func (su *ShortURL) FromPBRecord(pb *PBShortURL) {
 	su.URL = pb.URL
 	su.ShortCode = pb.ShortCode
 	su.AccessCount = pb.AccessCount
+	su.RedirectType = pb.RedirectType
 	su.Created = pb.Created
 	su.Updated = pb.Updated
 }
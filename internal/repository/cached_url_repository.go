@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/cache"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+)
+
+type CacheOptions struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+
+type CacheStats struct {
+	Hits         uint64 `json:"hits"`
+	NegativeHits uint64 `json:"negativeHits"`
+	Misses       uint64 `json:"misses"`
+	Entries      int    `json:"entries"`
+}
+
+// cachedLookup is either a short URL or a remembered "not found".
+type cachedLookup struct {
+	shortURL *urlModels.ShortURL
+}
+
+// pendingFill is a lookup on its way into the cache. Invalidating its code
+// while it runs marks it stale, so a record read before a write is not
+// cached after it.
+type pendingFill struct {
+	stale bool
+}
+
+// CachedURLRepository wraps another URLRepository with a read-through LRU
+// cache for short code lookups. Writes go straight to the wrapped repository
+// and invalidate the affected code. Only writes through this repository
+// invalidate it, so other replicas' writes show up once entries expire.
+type CachedURLRepository struct {
+	inner URLRepository
+	opts  CacheOptions
+	lru   *cache.LRU[string, cachedLookup]
+
+	// fillMu orders filling the cache against invalidating it.
+	fillMu sync.Mutex
+	fills  map[string][]*pendingFill
+
+	hits         atomic.Uint64
+	negativeHits atomic.Uint64
+	misses       atomic.Uint64
+}
+
+func NewCachedURLRepository(inner URLRepository, opts CacheOptions) *CachedURLRepository {
+	return &CachedURLRepository{
+		inner: inner,
+		opts:  opts,
+		lru:   cache.NewLRU[string, cachedLookup](opts.Size),
+		fills: make(map[string][]*pendingFill),
+	}
+}
+
+func (r *CachedURLRepository) Stats() CacheStats {
+	return CacheStats{
+		Hits:         r.hits.Load(),
+		NegativeHits: r.negativeHits.Load(),
+		Misses:       r.misses.Load(),
+		Entries:      r.lru.Len(),
+	}
+}
+
+func (r *CachedURLRepository) Create(ctx context.Context, shortURL *urlModels.ShortURL) error {
+	if err := r.inner.Create(ctx, shortURL); err != nil {
+		return err
+	}
+	r.invalidate(shortURL.ShortCode)
+	return nil
+}
+
+func (r *CachedURLRepository) GetByShortCode(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	if cached, ok := r.lru.Get(shortCode); ok {
+		if cached.shortURL == nil {
+			r.negativeHits.Add(1)
+			return nil, serviceErrors.NewNotFoundError("repository.GetByShortCode", "short URL not found")
+		}
+		r.hits.Add(1)
+		return cached.shortURL.Clone(), nil
+	}
+
+	r.misses.Add(1)
+	fill := r.startFill(shortCode)
+	shortURL, err := r.inner.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		// Only a "not found" is remembered; other errors end the fill
+		// without caching anything.
+		var ttl time.Duration
+		var serviceErr *serviceErrors.ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeNotFound {
+			ttl = r.opts.NegativeTTL
+		}
+		r.finishFill(shortCode, fill, cachedLookup{}, ttl)
+		return nil, err
+	}
+
+	r.finishFill(shortCode, fill, cachedLookup{shortURL: shortURL.Clone()}, r.opts.TTL)
+	return shortURL, nil
+}
+
+func (r *CachedURLRepository) startFill(shortCode string) *pendingFill {
+	fill := &pendingFill{}
+	r.fillMu.Lock()
+	r.fills[shortCode] = append(r.fills[shortCode], fill)
+	r.fillMu.Unlock()
+	return fill
+}
+
+// finishFill caches value for ttl unless the code was invalidated since
+// fill started. A zero ttl caches nothing.
+func (r *CachedURLRepository) finishFill(shortCode string, fill *pendingFill, value cachedLookup, ttl time.Duration) {
+	r.fillMu.Lock()
+	defer r.fillMu.Unlock()
+
+	pending := slices.DeleteFunc(r.fills[shortCode], func(f *pendingFill) bool { return f == fill })
+	if len(pending) == 0 {
+		delete(r.fills, shortCode)
+	} else {
+		r.fills[shortCode] = pending
+	}
+	if !fill.stale && ttl > 0 {
+		r.lru.Set(shortCode, value, ttl)
+	}
+}
+
+// invalidate drops the cached lookup of shortCode and keeps lookups still
+// reading it from caching what they read.
+func (r *CachedURLRepository) invalidate(shortCode string) {
+	r.fillMu.Lock()
+	defer r.fillMu.Unlock()
+
+	for _, fill := range r.fills[shortCode] {
+		fill.stale = true
+	}
+	r.lru.Delete(shortCode)
+}
+
+func (r *CachedURLRepository) Update(ctx context.Context, shortCode string, update *urlModels.ShortURLUpdate) (*urlModels.ShortURL, error) {
+	defer r.invalidate(shortCode)
+	return r.inner.Update(ctx, shortCode, update)
+}
+
+func (r *CachedURLRepository) Delete(ctx context.Context, shortCode string) error {
+	defer r.invalidate(shortCode)
+	return r.inner.Delete(ctx, shortCode)
+}
+
+func (r *CachedURLRepository) IncrementAccessCount(ctx context.Context, shortCode string, delta int64) error {
+	if err := r.inner.IncrementAccessCount(ctx, shortCode, delta); err != nil {
+		return err
+	}
+
+	// Keep cached statistics in step without another round trip.
+	r.lru.Update(shortCode, func(cached cachedLookup) cachedLookup {
+		if cached.shortURL == nil {
+			return cached
+		}
+		updated := cached.shortURL.Clone()
+		updated.AccessCount += delta
+		return cachedLookup{shortURL: updated}
+	})
+	return nil
+}
+
+func (r *CachedURLRepository) ConsumeClick(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	defer r.invalidate(shortCode)
+	return r.inner.ConsumeClick(ctx, shortCode)
+}
+
+func (r *CachedURLRepository) ExistsByShortCode(ctx context.Context, shortCode string) (bool, error) {
+	if cached, ok := r.lru.Get(shortCode); ok {
+		if cached.shortURL == nil {
+			r.negativeHits.Add(1)
+			return false, nil
+		}
+		r.hits.Add(1)
+		return true, nil
+	}
+
+	r.misses.Add(1)
+	return r.inner.ExistsByShortCode(ctx, shortCode)
+}
+
+// List is not cached; listings are read straight from the wrapped repository.
+func (r *CachedURLRepository) List(ctx context.Context, opts *urlModels.ListOptions) (*urlModels.ListResult, error) {
+	return r.inner.List(ctx, opts)
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+)
+
+// countingRepository counts lookups that reach the wrapped repository.
+type countingRepository struct {
+	URLRepository
+	lookups atomic.Int32
+}
+
+func (r *countingRepository) GetByShortCode(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	r.lookups.Add(1)
+	return r.URLRepository.GetByShortCode(ctx, shortCode)
+}
+
+func newCachedTestRepository() (*CachedURLRepository, *countingRepository) {
+	inner := &countingRepository{URLRepository: NewMemoryURLRepository()}
+	return NewCachedURLRepository(inner, CacheOptions{Size: 100, TTL: time.Minute, NegativeTTL: time.Minute}), inner
+}
+
+func TestCachedURLRepository(t *testing.T) {
+	runURLRepositoryConformance(t, func(t *testing.T) URLRepository {
+		repo, _ := newCachedTestRepository()
+		return repo
+	})
+}
+
+func TestCachedURLRepositoryServesHotLinksFromMemory(t *testing.T) {
+	ctx := context.Background()
+	repo, inner := newCachedTestRepository()
+
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "hot"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := repo.GetByShortCode(ctx, "hot"); err != nil {
+			t.Fatalf("GetByShortCode() error = %v", err)
+		}
+	}
+
+	if got := inner.lookups.Load(); got != 1 {
+		t.Errorf("inner lookups = %d, want 1", got)
+	}
+	if stats := repo.Stats(); stats.Hits != 9 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want 9 hits and 1 miss", stats)
+	}
+}
+
+func TestCachedURLRepositoryNegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	repo, inner := newCachedTestRepository()
+
+	for i := 0; i < 3; i++ {
+		if _, err := repo.GetByShortCode(ctx, "nope"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+			t.Fatalf("GetByShortCode() error = %v, want not found", err)
+		}
+	}
+	if got := inner.lookups.Load(); got != 1 {
+		t.Errorf("inner lookups = %d, want 1", got)
+	}
+
+	// Creating the code must clear the remembered miss.
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "nope"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := repo.GetByShortCode(ctx, "nope"); err != nil {
+		t.Errorf("GetByShortCode() after create error = %v", err)
+	}
+}
+
+func TestCachedURLRepositoryInvalidatesOnWrite(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newCachedTestRepository()
+
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "code"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	repo.GetByShortCode(ctx, "code")
+
+	newURL := "https://example.org"
+	if _, err := repo.Update(ctx, "code", &urlModels.ShortURLUpdate{URL: &newURL}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := repo.GetByShortCode(ctx, "code"); got.URL != newURL {
+		t.Errorf("GetByShortCode() after update URL = %v, want %v", got.URL, newURL)
+	}
+
+	if err := repo.IncrementAccessCount(ctx, "code", 3); err != nil {
+		t.Fatalf("IncrementAccessCount() error = %v", err)
+	}
+	if got, _ := repo.GetByShortCode(ctx, "code"); got.AccessCount != 3 {
+		t.Errorf("cached AccessCount = %d, want 3", got.AccessCount)
+	}
+
+	if err := repo.Delete(ctx, "code"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.GetByShortCode(ctx, "code"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("GetByShortCode() after delete error = %v, want not found", err)
+	}
+}
+
+// stallingRepository holds a lookup between reading the record and
+// returning it, until release is closed.
+type stallingRepository struct {
+	URLRepository
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (r *stallingRepository) GetByShortCode(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	shortURL, err := r.URLRepository.GetByShortCode(ctx, shortCode)
+	close(r.read)
+	<-r.release
+	return shortURL, err
+}
+
+func TestCachedURLRepositoryDoesNotCacheLookupsRacingAWrite(t *testing.T) {
+	ctx := context.Background()
+	inner := &stallingRepository{URLRepository: NewMemoryURLRepository(), read: make(chan struct{}), release: make(chan struct{})}
+	repo := NewCachedURLRepository(inner, CacheOptions{Size: 100, TTL: time.Minute})
+	if err := inner.URLRepository.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "code"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		repo.GetByShortCode(ctx, "code")
+	}()
+	<-inner.read
+
+	newURL := "https://example.org"
+	if _, err := repo.Update(ctx, "code", &urlModels.ShortURLUpdate{URL: &newURL}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	close(inner.release)
+	<-done
+
+	// The lookup read the record before the update, so it must not have
+	// been cached.
+	inner.read = make(chan struct{})
+	if got, err := repo.GetByShortCode(ctx, "code"); err != nil || got.URL != newURL {
+		t.Errorf("GetByShortCode() after update = %+v, %v, want URL %s", got, err, newURL)
+	}
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rowjay/url-shortening-service/internal/database"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// HistoryRepository stores the destination history of short URLs, keyed by
+// the short URL's record ID.
+type HistoryRepository interface {
+	// Append stores change as the next version for change.URLID and sets
+	// change.Version. Versions start at 1 and have no gaps.
+	Append(ctx context.Context, change *urlModels.DestinationChange) error
+	// List returns every version of urlID in ascending order.
+	List(ctx context.Context, urlID string) ([]*urlModels.DestinationChange, error)
+}
+
+type pocketBaseHistoryRecord struct {
+	URLID     string `json:"url_id"`
+	ShortCode string `json:"short_code"`
+	Version   int    `json:"version"`
+	OldURL    string `json:"old_url"`
+	NewURL    string `json:"new_url"`
+	Actor     string `json:"actor"`
+	ChangedAt string `json:"changed_at"`
+}
+
+type pocketBaseHistoryListResponse struct {
+	Items []pocketBaseHistoryRecord `json:"items"`
+}
+
+// pocketBaseHistoryRepository implements HistoryRepository on the
+// short_url_history collection.
+type pocketBaseHistoryRepository struct {
+	pb *database.PBClient
+}
+
+func NewHistoryRepository(pb *database.PBClient) HistoryRepository {
+	return &pocketBaseHistoryRepository{pb: pb}
+}
+
+// Append reads the latest version and creates the next one. A writer that
+// loses a race trips the unique index on (url_id, version) and retries.
+func (r *pocketBaseHistoryRepository) Append(ctx context.Context, change *urlModels.DestinationChange) error {
+	const op = "repository.AppendHistory"
+
+	var err error
+	for attempt := 0; attempt < constants.MaxRetries; attempt++ {
+		var latest []pocketBaseHistoryRecord
+		latest, err = r.list(ctx, op, pbQuery{
+			Filter:    pbEq("url_id", change.URLID),
+			Sort:      []pbSort{{Field: "version", Desc: true}},
+			PerPage:   1,
+			SkipTotal: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		version := 1
+		if len(latest) > 0 {
+			version = latest[0].Version + 1
+		}
+		err = r.create(ctx, op, pocketBaseHistoryRecord{
+			URLID:     change.URLID,
+			ShortCode: change.ShortCode,
+			Version:   version,
+			OldURL:    change.OldURL,
+			NewURL:    change.NewURL,
+			Actor:     change.Actor,
+			ChangedAt: formatPBTime(&change.ChangedAt),
+		})
+		var serviceErr *serviceErrors.ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeDuplicate {
+			continue
+		}
+		if err == nil {
+			change.Version = version
+		}
+		return err
+	}
+	return err
+}
+
+func (r *pocketBaseHistoryRepository) List(ctx context.Context, urlID string) ([]*urlModels.DestinationChange, error) {
+	const op = "repository.ListHistory"
+
+	var changes []*urlModels.DestinationChange
+	for page := 1; ; page++ {
+		records, err := r.list(ctx, op, pbQuery{
+			Filter:    pbEq("url_id", urlID),
+			Sort:      []pbSort{{Field: "version"}},
+			Page:      page,
+			PerPage:   constants.MaxPageSize,
+			SkipTotal: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			changes = append(changes, &urlModels.DestinationChange{
+				URLID:     rec.URLID,
+				ShortCode: rec.ShortCode,
+				Version:   rec.Version,
+				OldURL:    rec.OldURL,
+				NewURL:    rec.NewURL,
+				Actor:     rec.Actor,
+				ChangedAt: parsePBTime(rec.ChangedAt),
+			})
+		}
+		if len(records) < constants.MaxPageSize {
+			return changes, nil
+		}
+	}
+}
+
+func (r *pocketBaseHistoryRepository) list(ctx context.Context, op string, query pbQuery) ([]pocketBaseHistoryRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	encoded, err := query.encode()
+	if err != nil {
+		return nil, serviceErrors.NewValidationError(op, "invalid filter value", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/api/collections/%s/records?%s", r.pb.BaseURL, constants.HistoryCollection, encoded), nil)
+	if err != nil {
+		return nil, serviceErrors.NewInternalError(op, "failed to create request", err)
+	}
+
+	resp, err := r.pb.HTTPClient.Do(req)
+	if err != nil {
+		return nil, pocketBaseTransportError(op, "failed to query history", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, pocketBaseError(op, constants.HistoryCollection, pbCollectionEndpoint, resp)
+	}
+
+	var pbResp pocketBaseHistoryListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&pbResp); err != nil {
+		return nil, serviceErrors.NewInternalError(op, "failed to decode response", err)
+	}
+	return pbResp.Items, nil
+}
+
+func (r *pocketBaseHistoryRepository) create(ctx context.Context, op string, record pocketBaseHistoryRecord) error {
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	jsonBody, err := json.Marshal(record)
+	if err != nil {
+		return serviceErrors.NewInternalError(op, "failed to marshal request", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		r.pb.BaseURL+"/api/collections/"+constants.HistoryCollection+"/records", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return serviceErrors.NewInternalError(op, "failed to create request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.pb.HTTPClient.Do(req)
+	if err != nil {
+		return pocketBaseTransportError(op, "failed to create history record", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		log.Error().Int("status", resp.StatusCode).Str("url_id", record.URLID).Msg("PocketBase rejected history record")
+		return pocketBaseError(op, constants.HistoryCollection, pbCollectionEndpoint, resp)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+)
+
+// runHistoryRepositoryConformance exercises the HistoryRepository contract
+// against a fresh repository returned by newRepo for every subtest.
+func runHistoryRepositoryConformance(t *testing.T, newRepo func(t *testing.T) HistoryRepository) {
+	t.Run("AppendAndList", func(t *testing.T) { testHistoryRepositoryAppendAndList(t, newRepo(t)) })
+	t.Run("ConcurrentAppend", func(t *testing.T) { testHistoryRepositoryConcurrentAppend(t, newRepo(t)) })
+}
+
+func testHistoryRepositoryAppendAndList(t *testing.T, repo HistoryRepository) {
+	ctx := context.Background()
+	changedAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	changes := []*urlModels.DestinationChange{
+		{URLID: "1", ShortCode: "docs", NewURL: "https://example.com/v1", Actor: "alice", ChangedAt: changedAt},
+		{URLID: "2", ShortCode: "other", NewURL: "https://example.com/other", ChangedAt: changedAt},
+		{URLID: "1", ShortCode: "docs", OldURL: "https://example.com/v1", NewURL: "https://example.com/v2", Actor: "bob", ChangedAt: changedAt.Add(time.Minute)},
+	}
+	for _, change := range changes {
+		if err := repo.Append(ctx, change); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	if changes[0].Version != 1 || changes[1].Version != 1 || changes[2].Version != 2 {
+		t.Errorf("Append() versions = %d, %d, %d, want 1, 1, 2", changes[0].Version, changes[1].Version, changes[2].Version)
+	}
+
+	got, err := repo.List(ctx, "1")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("List() returned %d versions, want 2", len(got))
+	}
+	second := got[1]
+	if second.Version != 2 || second.ShortCode != "docs" || second.OldURL != "https://example.com/v1" || second.NewURL != "https://example.com/v2" || second.Actor != "bob" || !second.ChangedAt.Equal(changedAt.Add(time.Minute)) {
+		t.Errorf("List()[1] = %+v, want the second version", second)
+	}
+
+	if got, err := repo.List(ctx, "missing"); err != nil || len(got) != 0 {
+		t.Errorf("List(missing) = %v, %v, want no versions", got, err)
+	}
+}
+
+func testHistoryRepositoryConcurrentAppend(t *testing.T, repo HistoryRepository) {
+	ctx := context.Background()
+
+	const writers = 4
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			change := &urlModels.DestinationChange{URLID: "1", ShortCode: "docs", NewURL: "https://example.com", ChangedAt: time.Now()}
+			if err := repo.Append(ctx, change); err != nil {
+				t.Errorf("Append() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := repo.List(ctx, "1")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	for i, change := range got {
+		if change.Version != i+1 {
+			t.Errorf("List()[%d].Version = %d, want %d", i, change.Version, i+1)
+		}
+	}
+	if len(got) != writers {
+		t.Errorf("List() returned %d versions, want %d", len(got), writers)
+	}
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rowjay/url-shortening-service/internal/database"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// KeyRepository records the short codes reserved by the key pool, so a code
+// is never handed out twice, even across restarts.
+type KeyRepository interface {
+	// Reserve marks the free codes among candidates as reserved and returns
+	// them. A code is free when it was never reserved and no link, trashed
+	// or not, uses it. candidates must be distinct. A backend may consider
+	// only some of them to bound how long a call takes. On error it returns
+	// the codes reserved before it.
+	Reserve(ctx context.Context, candidates []string) ([]string, error)
+	// MaxReserve is how many candidates a Reserve call considers at most,
+	// or 0 when it considers them all.
+	MaxReserve() int
+}
+
+const (
+	// pbReserveChunk keeps the filter looking up taken codes well within
+	// PocketBase's limits on filter length.
+	pbReserveChunk = 50
+	// PocketBase takes one request per reserved code, so a call considers
+	// at most pbReserveMax candidates and sends pbReserveConcurrency
+	// requests at a time.
+	pbReserveMax         = 100
+	pbReserveConcurrency = 8
+)
+
+type pocketBaseKeyRecord struct {
+	ShortCode string `json:"short_code"`
+}
+
+// pocketBaseKeyRepository implements KeyRepository on the short_code_keys
+// collection.
+type pocketBaseKeyRepository struct {
+	pb   *database.PBClient
+	urls *urlRepositoryImpl
+}
+
+func NewKeyRepository(pb *database.PBClient) KeyRepository {
+	return &pocketBaseKeyRepository{pb: pb, urls: &urlRepositoryImpl{pb: pb}}
+}
+
+func (r *pocketBaseKeyRepository) MaxReserve() int {
+	return pbReserveMax
+}
+
+// Reserve looks up taken codes in batches, then creates a key record per
+// remaining code, a few at a time. A code another writer reserved first
+// trips the unique index and is left out. Only the first pbReserveMax
+// candidates are considered.
+func (r *pocketBaseKeyRepository) Reserve(ctx context.Context, candidates []string) ([]string, error) {
+	const op = "repository.ReserveKeys"
+
+	candidates = candidates[:min(len(candidates), pbReserveMax)]
+	taken := make(map[string]bool)
+	for start := 0; start < len(candidates); start += pbReserveChunk {
+		chunk := candidates[start:min(start+pbReserveChunk, len(candidates))]
+		filters := make([]pbFilter, len(chunk))
+		for i, code := range chunk {
+			filters[i] = pbEq("short_code", code)
+		}
+		ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+		resp, err := r.urls.listRecords(ctx, op, pbQuery{Filter: pbOr(filters...), PerPage: len(chunk), SkipTotal: true})
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range resp.Items {
+			taken[rec.ShortCode] = true
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved []string
+		firstErr error
+	)
+	slots := make(chan struct{}, pbReserveConcurrency)
+	for _, code := range candidates {
+		if taken[code] {
+			continue
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			err := r.create(ctx, op, code)
+			var serviceErr *serviceErrors.ServiceError
+			if errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeDuplicate {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				firstErr = cmp.Or(firstErr, err)
+				return
+			}
+			reserved = append(reserved, code)
+		}()
+	}
+	wg.Wait()
+	return reserved, firstErr
+}
+
+func (r *pocketBaseKeyRepository) create(ctx context.Context, op, code string) error {
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	jsonBody, err := json.Marshal(pocketBaseKeyRecord{ShortCode: code})
+	if err != nil {
+		return serviceErrors.NewInternalError(op, "failed to marshal request", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/api/collections/%s/records", r.pb.BaseURL, constants.KeysCollection), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return serviceErrors.NewInternalError(op, "failed to create request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.pb.HTTPClient.Do(req)
+	if err != nil {
+		return pocketBaseTransportError(op, "failed to reserve short code", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		log.Error().Int("status", resp.StatusCode).Str("short_code", code).Msg("PocketBase rejected key record")
+		return pocketBaseError(op, constants.KeysCollection, pbCollectionEndpoint, resp)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+)
+
+// runKeyRepositoryConformance exercises the KeyRepository contract against
+// a fresh key repository and the URL repository it checks, returned by
+// newRepos for every subtest.
+func runKeyRepositoryConformance(t *testing.T, newRepos func(t *testing.T) (URLRepository, KeyRepository)) {
+	t.Run("Reserve", func(t *testing.T) {
+		urls, keys := newRepos(t)
+		testKeyRepositoryReserve(t, urls, keys)
+	})
+	t.Run("ConcurrentReserve", func(t *testing.T) {
+		_, keys := newRepos(t)
+		testKeyRepositoryConcurrentReserve(t, keys)
+	})
+}
+
+func testKeyRepositoryReserve(t *testing.T, urls URLRepository, keys KeyRepository) {
+	ctx := context.Background()
+	for _, code := range []string{"taken", "trashed"} {
+		if err := urls.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: code}); err != nil {
+			t.Fatalf("Create(%s) error = %v", code, err)
+		}
+	}
+	deletedAt := time.Now().UTC()
+	if _, err := urls.Update(ctx, "trashed", &urlModels.ShortURLUpdate{DeletedAt: &deletedAt}); err != nil {
+		t.Fatalf("Update(trashed) error = %v", err)
+	}
+
+	got, err := keys.Reserve(ctx, []string{"free1", "taken", "free2", "trashed"})
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"free1", "free2"}) {
+		t.Errorf("Reserve() = %v, want [free1 free2]", got)
+	}
+
+	got, err = keys.Reserve(ctx, []string{"free2", "free3"})
+	if err != nil {
+		t.Fatalf("Reserve() again error = %v", err)
+	}
+	if !slices.Equal(got, []string{"free3"}) {
+		t.Errorf("Reserve() again = %v, want only the code not reserved before", got)
+	}
+
+	if got, err := keys.Reserve(ctx, nil); err != nil || len(got) != 0 {
+		t.Errorf("Reserve(nil) = %v, %v, want nothing", got, err)
+	}
+}
+
+func testKeyRepositoryConcurrentReserve(t *testing.T, keys KeyRepository) {
+	ctx := context.Background()
+	candidates := make([]string, 40)
+	for i := range candidates {
+		candidates[i] = fmt.Sprintf("code%02d", i)
+	}
+
+	const reservers = 4
+	results := make([][]string, reservers)
+	var wg sync.WaitGroup
+	for i := range reservers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reserved, err := keys.Reserve(ctx, candidates)
+			if err != nil {
+				t.Errorf("Reserve() error = %v", err)
+			}
+			results[i] = reserved
+		}()
+	}
+	wg.Wait()
+
+	all := slices.Concat(results...)
+	slices.Sort(all)
+	if !slices.Equal(all, candidates) {
+		t.Errorf("concurrent Reserve() handed out %d codes, want each of the %d exactly once", len(all), len(candidates))
+	}
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+)
+
+// memoryHistoryRepository keeps destination histories in process memory.
+type memoryHistoryRepository struct {
+	mu      sync.RWMutex
+	history map[string][]urlModels.DestinationChange
+}
+
+func NewMemoryHistoryRepository() HistoryRepository {
+	return &memoryHistoryRepository{
+		history: make(map[string][]urlModels.DestinationChange),
+	}
+}
+
+func (r *memoryHistoryRepository) Append(ctx context.Context, change *urlModels.DestinationChange) error {
+	if err := ctx.Err(); err != nil {
+		return serviceErrors.NewInternalError("repository.AppendHistory", "context done", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change.Version = len(r.history[change.URLID]) + 1
+	r.history[change.URLID] = append(r.history[change.URLID], *change)
+	return nil
+}
+
+func (r *memoryHistoryRepository) List(ctx context.Context, urlID string) ([]*urlModels.DestinationChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, serviceErrors.NewInternalError("repository.ListHistory", "context done", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	changes := make([]*urlModels.DestinationChange, len(r.history[urlID]))
+	for i := range r.history[urlID] {
+		change := r.history[urlID][i]
+		changes[i] = &change
+	}
+	return changes, nil
+}
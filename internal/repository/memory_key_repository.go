@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+)
+
+// memoryKeyRepository keeps reserved codes in process memory and checks
+// candidates against urls.
+type memoryKeyRepository struct {
+	urls URLRepository
+
+	mu       sync.Mutex
+	reserved map[string]bool
+}
+
+func NewMemoryKeyRepository(urls URLRepository) KeyRepository {
+	return &memoryKeyRepository{urls: urls, reserved: make(map[string]bool)}
+}
+
+func (r *memoryKeyRepository) MaxReserve() int {
+	return 0
+}
+
+func (r *memoryKeyRepository) Reserve(ctx context.Context, candidates []string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, serviceErrors.NewInternalError("repository.ReserveKeys", "context done", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var reserved []string
+	for _, code := range candidates {
+		if r.reserved[code] {
+			continue
+		}
+		exists, err := r.urls.ExistsByShortCode(ctx, code)
+		if err != nil {
+			return reserved, err
+		}
+		if exists {
+			continue
+		}
+		r.reserved[code] = true
+		reserved = append(reserved, code)
+	}
+	return reserved, nil
+}
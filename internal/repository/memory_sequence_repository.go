@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+)
+
+// memorySequenceRepository counts in process memory, which lasts exactly
+// as long as the memory storage it numbers.
+type memorySequenceRepository struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+func NewMemorySequenceRepository() SequenceRepository {
+	return &memorySequenceRepository{}
+}
+
+func (r *memorySequenceRepository) Allocate(ctx context.Context, n uint64) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, serviceErrors.NewInternalError("repository.AllocateSequence", "context done", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	first := r.next
+	r.next += n
+	return first, nil
+}
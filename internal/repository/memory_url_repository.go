@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+)
+
+// memoryURLRepository keeps short URLs in process memory. It is meant for
+// tests and local development; nothing survives a restart.
+type memoryURLRepository struct {
+	mu     sync.RWMutex
+	urls   map[string]*urlModels.ShortURL
+	nextID uint64
+}
+
+func NewMemoryURLRepository() URLRepository {
+	return &memoryURLRepository{
+		urls: make(map[string]*urlModels.ShortURL),
+	}
+}
+
+func (r *memoryURLRepository) Create(ctx context.Context, shortURL *urlModels.ShortURL) error {
+	if err := ctx.Err(); err != nil {
+		return serviceErrors.NewInternalError("repository.Create", "context done", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.urls[shortURL.ShortCode]; exists {
+		return serviceErrors.NewDuplicateError("repository.Create", "short code already exists")
+	}
+
+	r.nextID++
+	now := time.Now().UTC()
+	shortURL.ID = strconv.FormatUint(r.nextID, 10)
+	shortURL.Created = now
+	shortURL.Updated = now
+
+	r.urls[shortURL.ShortCode] = shortURL.Clone()
+	return nil
+}
+
+func (r *memoryURLRepository) GetByShortCode(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, serviceErrors.NewInternalError("repository.GetByShortCode", "context done", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.urls[shortCode]
+	if !ok {
+		return nil, serviceErrors.NewNotFoundError("repository.GetByShortCode", "short URL not found")
+	}
+
+	return stored.Clone(), nil
+}
+
+func (r *memoryURLRepository) Update(ctx context.Context, shortCode string, update *urlModels.ShortURLUpdate) (*urlModels.ShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, serviceErrors.NewInternalError("repository.Update", "context done", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.urls[shortCode]
+	if !ok {
+		return nil, serviceErrors.NewNotFoundError("repository.Update", "short URL not found")
+	}
+
+	if update.URL != nil {
+		stored.URL = *update.URL
+	}
+	if update.RedirectType != nil {
+		stored.RedirectType = *update.RedirectType
+	}
+	if update.ExpiresAt != nil {
+		expiresAt := update.ExpiresAt.UTC()
+		stored.ExpiresAt = &expiresAt
+	}
+	if update.ClearExpiresAt {
+		stored.ExpiresAt = nil
+	}
+	if update.MaxClicks != nil {
+		stored.MaxClicks = *update.MaxClicks
+	}
+	if update.FallbackURL != nil {
+		stored.FallbackURL = *update.FallbackURL
+	}
+	if update.PasswordHash != nil {
+		stored.PasswordHash = *update.PasswordHash
+	}
+	if update.ActiveFrom != nil {
+		activeFrom := update.ActiveFrom.UTC()
+		stored.ActiveFrom = &activeFrom
+	}
+	if update.ClearActiveFrom {
+		stored.ActiveFrom = nil
+	}
+	if update.ActiveUntil != nil {
+		activeUntil := update.ActiveUntil.UTC()
+		stored.ActiveUntil = &activeUntil
+	}
+	if update.ClearActiveUntil {
+		stored.ActiveUntil = nil
+	}
+	if update.Disabled != nil {
+		stored.Disabled = *update.Disabled
+	}
+	if update.DisabledReason != nil {
+		stored.DisabledReason = *update.DisabledReason
+	}
+	if update.DisabledLegal != nil {
+		stored.DisabledLegal = *update.DisabledLegal
+	}
+	if update.DeletedAt != nil {
+		deletedAt := update.DeletedAt.UTC()
+		stored.DeletedAt = &deletedAt
+	}
+	if update.ClearDeletedAt {
+		stored.DeletedAt = nil
+	}
+	if update.Metadata != nil {
+		stored.Metadata = urlModels.CloneMetadata(update.Metadata)
+	}
+	stored.Updated = time.Now().UTC()
+
+	return stored.Clone(), nil
+}
+
+func (r *memoryURLRepository) Delete(ctx context.Context, shortCode string) error {
+	if err := ctx.Err(); err != nil {
+		return serviceErrors.NewInternalError("repository.Delete", "context done", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.urls[shortCode]; !ok {
+		return serviceErrors.NewNotFoundError("repository.Delete", "short URL not found")
+	}
+	delete(r.urls, shortCode)
+	return nil
+}
+
+func (r *memoryURLRepository) IncrementAccessCount(ctx context.Context, shortCode string, delta int64) error {
+	if err := ctx.Err(); err != nil {
+		return serviceErrors.NewInternalError("repository.IncrementAccessCount", "context done", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.urls[shortCode]
+	if !ok {
+		return serviceErrors.NewNotFoundError("repository.IncrementAccessCount", "short URL not found")
+	}
+	stored.AccessCount += delta
+	return nil
+}
+
+func (r *memoryURLRepository) ConsumeClick(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, serviceErrors.NewInternalError("repository.ConsumeClick", "context done", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.urls[shortCode]
+	if !ok {
+		return nil, serviceErrors.NewNotFoundError("repository.ConsumeClick", "short URL not found")
+	}
+	if stored.ClickLimited() && stored.AccessCount >= stored.MaxClicks {
+		return nil, serviceErrors.NewGoneError("repository.ConsumeClick", "click limit reached")
+	}
+	stored.AccessCount++
+	return stored.Clone(), nil
+}
+
+func (r *memoryURLRepository) ExistsByShortCode(ctx context.Context, shortCode string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, serviceErrors.NewInternalError("repository.ExistsByShortCode", "context done", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.urls[shortCode]
+	return ok, nil
+}
+
+func (r *memoryURLRepository) List(ctx context.Context, opts *urlModels.ListOptions) (*urlModels.ListResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, serviceErrors.NewInternalError("repository.List", "context done", err)
+	}
+
+	r.mu.RLock()
+	matched := make([]*urlModels.ShortURL, 0, len(r.urls))
+	for _, stored := range r.urls {
+		if opts.Filter.Matches(stored) {
+			matched = append(matched, stored.Clone())
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(matched, func(a, b *urlModels.ShortURL) int {
+		return opts.Compare(urlModels.CursorFor(a), urlModels.CursorFor(b))
+	})
+
+	result := &urlModels.ListResult{TotalItems: len(matched)}
+	start := 0
+	if opts.After != nil {
+		start, _ = slices.BinarySearchFunc(matched, opts.After, func(item *urlModels.ShortURL, after *urlModels.ListCursor) int {
+			if opts.Compare(urlModels.CursorFor(item), after) <= 0 {
+				return -1
+			}
+			return 1
+		})
+	} else if opts.Page > 1 {
+		start = (opts.Page - 1) * opts.PerPage
+	}
+
+	if start < len(matched) {
+		result.Items = matched[start:min(start+opts.PerPage, len(matched))]
+	}
+	return result, nil
+}
@@ -0,0 +1,28 @@
+package repository
+
+import "testing"
+
+func TestMemoryURLRepository(t *testing.T) {
+	runURLRepositoryConformance(t, func(t *testing.T) URLRepository {
+		return NewMemoryURLRepository()
+	})
+}
+
+func TestMemoryHistoryRepository(t *testing.T) {
+	runHistoryRepositoryConformance(t, func(t *testing.T) HistoryRepository {
+		return NewMemoryHistoryRepository()
+	})
+}
+
+func TestMemoryKeyRepository(t *testing.T) {
+	runKeyRepositoryConformance(t, func(t *testing.T) (URLRepository, KeyRepository) {
+		urls := NewMemoryURLRepository()
+		return urls, NewMemoryKeyRepository(urls)
+	})
+}
+
+func TestMemorySequenceRepository(t *testing.T) {
+	runSequenceRepositoryConformance(t, func(t *testing.T) SequenceRepository {
+		return NewMemorySequenceRepository()
+	})
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rowjay/url-shortening-service/internal/database"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+)
+
+// pbEndpoint tells pocketBaseError what a 404 means: collection endpoints
+// (list, create) only 404 when the collection itself is missing, while
+// record endpoints also 404 for a missing record.
+type pbEndpoint int
+
+const (
+	pbCollectionEndpoint pbEndpoint = iota
+	pbRecordEndpoint
+)
+
+// pbNotUnique is the field error code PocketBase reports for unique index
+// violations.
+const pbNotUnique = "validation_not_unique"
+
+// pocketBaseError converts a failed PocketBase response from collection
+// into a service error based on its status and JSON error body.
+func pocketBaseError(op, collection string, endpoint pbEndpoint, resp *http.Response) error {
+	apiErr := database.ParseAPIError(resp)
+
+	switch apiErr.Status {
+	case http.StatusBadRequest:
+		if field, ok := notUniqueField(apiErr); ok {
+			if field == "short_code" {
+				return serviceErrors.NewDuplicateError(op, "short code already exists")
+			}
+			return serviceErrors.NewDuplicateError(op, field+" already exists")
+		}
+		return serviceErrors.NewValidationError(op, validationMessage(apiErr), apiErr)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return serviceErrors.NewUnavailableError(op, "storage rejected the service credentials", apiErr)
+	case http.StatusNotFound:
+		if endpoint == pbCollectionEndpoint || isMissingCollection(apiErr) {
+			return serviceErrors.NewInternalError(op, collection+" collection does not exist", apiErr)
+		}
+		return serviceErrors.NewNotFoundError(op, "short URL not found")
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return serviceErrors.NewUnavailableError(op, "storage is unavailable", apiErr)
+	default:
+		return serviceErrors.NewInternalError(op, "PocketBase error", apiErr)
+	}
+}
+
+// pocketBaseTransportError wraps an error from sending a request. The
+// transport has already retried what it could, or the circuit breaker
+// failed fast, so storage is unavailable either way.
+func pocketBaseTransportError(op, message string, err error) error {
+	if errors.Is(err, database.ErrCircuitOpen) {
+		message = "storage is unavailable"
+	}
+	return serviceErrors.NewUnavailableError(op, message, err)
+}
+
+func notUniqueField(apiErr *database.APIError) (string, bool) {
+	for field, fieldErr := range apiErr.Data {
+		if fieldErr.Code == pbNotUnique {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+func validationMessage(apiErr *database.APIError) string {
+	if len(apiErr.Data) == 0 {
+		if apiErr.Message != "" {
+			return apiErr.Message
+		}
+		return "invalid request"
+	}
+
+	fields := make([]string, 0, len(apiErr.Data))
+	for field := range apiErr.Data {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field + ": " + apiErr.Data[field].Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// isMissingCollection recognizes PocketBase's 404 for an unknown collection,
+// which record endpoints return alongside the 404 for an unknown record.
+func isMissingCollection(apiErr *database.APIError) bool {
+	return strings.Contains(strings.ToLower(apiErr.Message), "collection")
+}
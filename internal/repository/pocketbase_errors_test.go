@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rowjay/url-shortening-service/internal/database"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+)
+
+func TestPocketBaseErrorMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection string
+		endpoint   pbEndpoint
+		status     int
+		body       string
+		want       serviceErrors.ErrorCode
+		message    string
+	}{
+		{
+			name:    "unique violation",
+			status:  http.StatusBadRequest,
+			body:    `{"status":400,"message":"Failed to create record.","data":{"short_code":{"code":"validation_not_unique","message":"Value must be unique."}}}`,
+			want:    serviceErrors.ErrorCodeDuplicate,
+			message: "short code already exists",
+		},
+		{
+			name:    "field validation",
+			status:  http.StatusBadRequest,
+			body:    `{"status":400,"message":"Failed to create record.","data":{"url":{"code":"validation_required","message":"Cannot be blank."},"access_count":{"code":"validation_not_a_number","message":"Must be a number."}}}`,
+			want:    serviceErrors.ErrorCodeValidation,
+			message: "access_count: Must be a number.; url: Cannot be blank.",
+		},
+		{
+			name:    "bad request without details",
+			status:  http.StatusBadRequest,
+			body:    `{"status":400,"message":"Invalid filter parameters.","data":{}}`,
+			want:    serviceErrors.ErrorCodeValidation,
+			message: "Invalid filter parameters.",
+		},
+		{
+			name:   "unauthorized",
+			status: http.StatusUnauthorized,
+			body:   `{"status":401,"message":"The request requires valid record authorization token.","data":{}}`,
+			want:   serviceErrors.ErrorCodeUnavailable,
+		},
+		{
+			name:   "forbidden",
+			status: http.StatusForbidden,
+			body:   `{"status":403,"message":"Only superusers can perform this action.","data":{}}`,
+			want:   serviceErrors.ErrorCodeUnavailable,
+		},
+		{
+			name:     "missing record",
+			endpoint: pbRecordEndpoint,
+			status:   http.StatusNotFound,
+			body:     `{"status":404,"message":"The requested resource wasn't found.","data":{}}`,
+			want:     serviceErrors.ErrorCodeNotFound,
+		},
+		{
+			name:     "missing collection on record endpoint",
+			endpoint: pbRecordEndpoint,
+			status:   http.StatusNotFound,
+			body:     `{"status":404,"message":"Missing collection context.","data":{}}`,
+			want:     serviceErrors.ErrorCodeInternal,
+			message:  "short_urls collection does not exist",
+		},
+		{
+			name:     "missing collection on list endpoint",
+			endpoint: pbCollectionEndpoint,
+			status:   http.StatusNotFound,
+			body:     `{"status":404,"message":"The requested resource wasn't found.","data":{}}`,
+			want:     serviceErrors.ErrorCodeInternal,
+			message:  "short_urls collection does not exist",
+		},
+		{
+			name:       "missing history collection",
+			collection: "short_url_history",
+			endpoint:   pbCollectionEndpoint,
+			status:     http.StatusNotFound,
+			body:       `{"status":404,"message":"The requested resource wasn't found.","data":{}}`,
+			want:       serviceErrors.ErrorCodeInternal,
+			message:    "short_url_history collection does not exist",
+		},
+		{
+			name:   "rate limited",
+			status: http.StatusTooManyRequests,
+			body:   `{"status":429,"message":"Too Many Requests.","data":{}}`,
+			want:   serviceErrors.ErrorCodeUnavailable,
+		},
+		{
+			name:   "server error with non-JSON body",
+			status: http.StatusInternalServerError,
+			body:   `upstream exploded`,
+			want:   serviceErrors.ErrorCodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(tt.status)
+			fmt.Fprint(rec, tt.body)
+
+			err := pocketBaseError("repository.Test", cmp.Or(tt.collection, "short_urls"), tt.endpoint, rec.Result())
+
+			var serviceErr *serviceErrors.ServiceError
+			if !errors.As(err, &serviceErr) {
+				t.Fatalf("pocketBaseError() = %v, want *ServiceError", err)
+			}
+			if serviceErr.Code != tt.want {
+				t.Errorf("Code = %d, want %d (%v)", serviceErr.Code, tt.want, err)
+			}
+			if tt.message != "" && serviceErr.Message != tt.message {
+				t.Errorf("Message = %q, want %q", serviceErr.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestPocketBaseTransportErrorMarksStorageUnavailable(t *testing.T) {
+	if got := errorCode(pocketBaseTransportError("repository.Test", "failed", database.ErrCircuitOpen)); got != serviceErrors.ErrorCodeUnavailable {
+		t.Errorf("code for open circuit = %d, want %d", got, serviceErrors.ErrorCodeUnavailable)
+	}
+	if got := errorCode(pocketBaseTransportError("repository.Test", "failed", errors.New("connection refused"))); got != serviceErrors.ErrorCodeUnavailable {
+		t.Errorf("code for network error = %d, want %d", got, serviceErrors.ErrorCodeUnavailable)
+	}
+}
+
+func TestPocketBaseMissingCollectionIsNotANotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeFakeError(w, http.StatusNotFound, "Missing collection context.", nil)
+	}))
+	t.Cleanup(server.Close)
+
+	repo := NewURLRepository(&database.PBClient{BaseURL: server.URL, HTTPClient: server.Client()})
+	if _, err := repo.GetByShortCode(context.Background(), "abc123"); errorCode(err) != serviceErrors.ErrorCodeInternal {
+		t.Errorf("GetByShortCode() error = %v, want internal error", err)
+	}
+	if _, err := repo.ExistsByShortCode(context.Background(), "abc123"); err == nil {
+		t.Error("ExistsByShortCode() error = nil, want the missing collection reported")
+	}
+}
@@ -0,0 +1,331 @@
+package repository
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/database"
+)
+
+// fakePocketBase is a minimal in-process stand-in for the PocketBase records
+// API of one collection, the short_urls collection unless stated otherwise.
+type fakePocketBase struct {
+	mu         sync.Mutex
+	collection string
+	// unique are the fields of the collection's unique index.
+	unique  []string
+	records map[string]map[string]any
+	nextID  int
+}
+
+func newFakePocketBase(t *testing.T) (*fakePocketBase, *database.PBClient) {
+	return newFakePocketBaseCollection(t, "short_urls", "short_code")
+}
+
+func newFakePocketBaseCollection(t *testing.T, collection string, unique ...string) (*fakePocketBase, *database.PBClient) {
+	t.Helper()
+
+	fake := &fakePocketBase{collection: collection, unique: unique, records: make(map[string]map[string]any)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return fake, &database.PBClient{BaseURL: server.URL, HTTPClient: server.Client()}
+}
+
+func (f *fakePocketBase) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := "/api/collections/" + f.collection + "/records"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeFakeError(w, http.StatusNotFound, "The requested resource wasn't found.", nil)
+		return
+	}
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		f.list(w, r)
+	case r.Method == http.MethodPost && id == "":
+		f.create(w, r)
+	case r.Method == http.MethodPatch && id != "":
+		f.update(w, r, id)
+	case r.Method == http.MethodDelete && id != "":
+		if _, ok := f.records[id]; !ok {
+			writeFakeError(w, http.StatusNotFound, "The requested resource wasn't found.", nil)
+			return
+		}
+		delete(f.records, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeFakeError(w, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+	}
+}
+
+func (f *fakePocketBase) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	tokens, err := scanPBFilter(query.Get("filter"))
+	if err != nil {
+		writeFakeError(w, http.StatusBadRequest, "Invalid filter parameters.", nil)
+		return
+	}
+
+	items := []map[string]any{}
+	for _, record := range f.records {
+		match := true
+		if len(tokens) > 0 {
+			eval := &fakeFilterEval{tokens: tokens, record: record}
+			match = eval.or()
+			if eval.err != nil || eval.pos != len(tokens) {
+				writeFakeError(w, http.StatusBadRequest, "Invalid filter parameters.", nil)
+				return
+			}
+		}
+		if match {
+			items = append(items, record)
+		}
+	}
+
+	if sortParam := query.Get("sort"); sortParam != "" {
+		keys := strings.Split(sortParam, ",")
+		slices.SortFunc(items, func(a, b map[string]any) int {
+			for _, key := range keys {
+				field, desc := strings.TrimPrefix(key, "-"), strings.HasPrefix(key, "-")
+				c := compareFakeValues(a[field], b[field])
+				if desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+	}
+
+	total := len(items)
+	page, _ := strconv.Atoi(query.Get("page"))
+	perPage, _ := strconv.Atoi(query.Get("perPage"))
+	page, perPage = max(page, 1), cmp.Or(perPage, 30)
+	start := min((page-1)*perPage, total)
+	items = items[start:min(start+perPage, total)]
+	if query.Get("skipTotal") == "true" {
+		total = -1
+	}
+
+	writeFakeJSON(w, http.StatusOK, map[string]any{
+		"page":       page,
+		"perPage":    perPage,
+		"totalItems": total,
+		"items":      items,
+	})
+}
+
+// fakeFilterEval evaluates a scanned filter against one record. It supports
+// the subset the repository emits: comparisons of a field with a literal,
+// joined by && and || and grouped with parentheses.
+type fakeFilterEval struct {
+	tokens []filterToken
+	pos    int
+	record map[string]any
+	err    error
+}
+
+func (e *fakeFilterEval) next() filterToken {
+	if e.pos >= len(e.tokens) {
+		e.err = fmt.Errorf("unexpected end of filter")
+		return filterToken{}
+	}
+	e.pos++
+	return e.tokens[e.pos-1]
+}
+
+func (e *fakeFilterEval) peek(tok filterToken) bool {
+	return e.pos < len(e.tokens) && e.tokens[e.pos] == tok
+}
+
+func (e *fakeFilterEval) or() bool {
+	result := e.and()
+	for e.err == nil && e.peek(filterToken{"join", "||"}) {
+		e.pos++
+		result = e.and() || result
+	}
+	return result
+}
+
+func (e *fakeFilterEval) and() bool {
+	result := e.term()
+	for e.err == nil && e.peek(filterToken{"join", "&&"}) {
+		e.pos++
+		result = e.term() && result
+	}
+	return result
+}
+
+func (e *fakeFilterEval) term() bool {
+	if e.peek(filterToken{"group", "("}) {
+		e.pos++
+		result := e.or()
+		if e.next() != (filterToken{"group", ")"}) {
+			e.err = fmt.Errorf("unbalanced parentheses")
+		}
+		return result
+	}
+
+	field, op, literal := e.next(), e.next(), e.next()
+	if e.err != nil || field.kind != "identifier" || op.kind != "sign" {
+		e.err = fmt.Errorf("unsupported expression")
+		return false
+	}
+
+	value := e.record[field.literal]
+	if op.literal == "~" {
+		s, _ := value.(string)
+		return strings.Contains(strings.ToLower(s), strings.ToLower(unescapeLike(literal.literal)))
+	}
+
+	var operand any = literal.literal
+	if literal.kind == "identifier" {
+		n, err := strconv.ParseFloat(literal.literal, 64)
+		if err != nil {
+			e.err = err
+			return false
+		}
+		operand = n
+	}
+	c := compareFakeValues(value, operand)
+	switch op.literal {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	e.err = fmt.Errorf("unsupported operator %q", op.literal)
+	return false
+}
+
+// compareFakeValues orders two JSON values of the same type. Timestamps are
+// stored in a sortable layout, so strings compare directly.
+func compareFakeValues(a, b any) int {
+	switch a := a.(type) {
+	case float64:
+		b, _ := b.(float64)
+		return cmp.Compare(a, b)
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	}
+	return 0
+}
+
+func (f *fakePocketBase) create(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "Failed to load the submitted data.", nil)
+		return
+	}
+
+	for _, record := range f.records {
+		if f.sameKey(record, body) {
+			data := map[string]any{}
+			for _, field := range f.unique {
+				data[field] = map[string]string{"code": "validation_not_unique", "message": "Value must be unique."}
+			}
+			writeFakeError(w, http.StatusBadRequest, "Failed to create record.", data)
+			return
+		}
+	}
+
+	f.nextID++
+	now := time.Now().UTC().Format("2006-01-02 15:04:05.000Z")
+	body["id"] = fmt.Sprintf("rec%012d", f.nextID)
+	body["created"] = now
+	body["updated"] = now
+	if _, ok := body["access_count"]; !ok {
+		body["access_count"] = float64(0)
+	}
+	f.records[body["id"].(string)] = body
+	writeFakeJSON(w, http.StatusOK, body)
+}
+
+func (f *fakePocketBase) sameKey(a, b map[string]any) bool {
+	for _, field := range f.unique {
+		if a[field] != b[field] {
+			return false
+		}
+	}
+	return len(f.unique) > 0
+}
+
+func (f *fakePocketBase) update(w http.ResponseWriter, r *http.Request, id string) {
+	record, ok := f.records[id]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "The requested resource wasn't found.", nil)
+		return
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "Failed to load the submitted data.", nil)
+		return
+	}
+
+	for key, value := range body {
+		switch {
+		case strings.HasSuffix(key, "+"):
+			field := strings.TrimSuffix(key, "+")
+			current, _ := record[field].(float64)
+			record[field] = current + value.(float64)
+		case strings.HasSuffix(key, "-"):
+			field := strings.TrimSuffix(key, "-")
+			current, _ := record[field].(float64)
+			record[field] = current - value.(float64)
+		default:
+			record[key] = value
+		}
+	}
+	record["updated"] = time.Now().UTC().Format("2006-01-02 15:04:05.000Z")
+	writeFakeJSON(w, http.StatusOK, record)
+}
+
+func (f *fakePocketBase) accessCount(shortCode string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, record := range f.records {
+		if record["short_code"] == shortCode {
+			count, _ := record["access_count"].(float64)
+			return int64(count)
+		}
+	}
+	return -1
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeFakeError(w http.ResponseWriter, status int, message string, data map[string]any) {
+	if data == nil {
+		data = map[string]any{}
+	}
+	writeFakeJSON(w, status, map[string]any{"status": status, "message": message, "data": data})
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PocketBase's filter scanner treats a quote preceded by a backslash as
+// escaped, and backslashes cannot themselves be escaped. Values ending in a
+// backslash would therefore swallow the closing quote and cannot be used.
+var errUnsafeFilterValue = errors.New("filter value cannot end with a backslash")
+
+var filterFieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// pbFilter is a PocketBase filter expression whose values are always
+// rendered as escaped literals, never spliced in as raw text.
+type pbFilter struct {
+	expr string
+	err  error
+}
+
+func pbEq(field string, value any) pbFilter {
+	return pbCompare(field, "=", value)
+}
+
+func pbGreater(field string, value any) pbFilter {
+	return pbCompare(field, ">", value)
+}
+
+func pbGreaterOrEqual(field string, value any) pbFilter {
+	return pbCompare(field, ">=", value)
+}
+
+func pbLess(field string, value any) pbFilter {
+	return pbCompare(field, "<", value)
+}
+
+// pbContains matches records whose field contains value as a plain
+// substring; LIKE wildcards in value are escaped.
+func pbContains(field, value string) pbFilter {
+	return pbCompare(field, "~", likeEscaper.Replace(value))
+}
+
+// pbAnd joins the non-empty filters with &&.
+func pbAnd(filters ...pbFilter) pbFilter {
+	return pbJoin(" && ", filters)
+}
+
+// pbOr joins the non-empty filters with ||.
+func pbOr(filters ...pbFilter) pbFilter {
+	return pbJoin(" || ", filters)
+}
+
+func pbJoin(op string, filters []pbFilter) pbFilter {
+	var parts []string
+	for _, f := range filters {
+		if f.err != nil {
+			return f
+		}
+		if f.expr != "" {
+			parts = append(parts, "("+f.expr+")")
+		}
+	}
+	return pbFilter{expr: strings.Join(parts, op)}
+}
+
+func pbCompare(field, op string, value any) pbFilter {
+	if !filterFieldPattern.MatchString(field) {
+		return pbFilter{err: fmt.Errorf("invalid filter field %q", field)}
+	}
+	literal, err := filterLiteral(value)
+	if err != nil {
+		return pbFilter{err: fmt.Errorf("filter %s %s: %w", field, op, err)}
+	}
+	return pbFilter{expr: field + op + literal}
+}
+
+func filterLiteral(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return quoteFilterString(v)
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return quoteFilterString(v.UTC().Format(pbTimeLayout))
+	default:
+		return "", fmt.Errorf("unsupported filter value type %T", value)
+	}
+}
+
+func quoteFilterString(s string) (string, error) {
+	if strings.HasSuffix(s, `\`) {
+		return "", errUnsafeFilterValue
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`, nil
+}
+
+// pbTimeLayout is the datetime format PocketBase stores and compares.
+const pbTimeLayout = "2006-01-02 15:04:05.000Z"
+
+type pbSort struct {
+	Field string
+	Desc  bool
+}
+
+// pbQuery holds the list parameters for a records request.
+type pbQuery struct {
+	Filter    pbFilter
+	Sort      []pbSort
+	Page      int
+	PerPage   int
+	SkipTotal bool
+}
+
+func (q pbQuery) encode() (string, error) {
+	if q.Filter.err != nil {
+		return "", q.Filter.err
+	}
+
+	values := url.Values{}
+	if q.Filter.expr != "" {
+		values.Set("filter", q.Filter.expr)
+	}
+	if len(q.Sort) > 0 {
+		fields := make([]string, len(q.Sort))
+		for i, s := range q.Sort {
+			if !filterFieldPattern.MatchString(s.Field) {
+				return "", fmt.Errorf("invalid sort field %q", s.Field)
+			}
+			fields[i] = s.Field
+			if s.Desc {
+				fields[i] = "-" + s.Field
+			}
+		}
+		values.Set("sort", strings.Join(fields, ","))
+	}
+	if q.Page > 0 {
+		values.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PerPage > 0 {
+		values.Set("perPage", strconv.Itoa(q.PerPage))
+	}
+	if q.SkipTotal {
+		values.Set("skipTotal", "true")
+	}
+	return values.Encode(), nil
+}
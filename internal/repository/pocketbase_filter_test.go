@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type filterToken struct {
+	kind    string
+	literal string
+}
+
+// scanPBFilter tokenizes a filter the way PocketBase's fexpr scanner does.
+// In particular, text literals end at the first quote that is not preceded
+// by a backslash, and only escaped quotes are unescaped.
+func scanPBFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expr); {
+		ch := expr[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(' || ch == ')':
+			tokens = append(tokens, filterToken{"group", string(ch)})
+			i++
+		case ch == '"' || ch == '\'':
+			end := -1
+			for j := i + 1; j < len(expr); j++ {
+				if expr[j] == ch && expr[j-1] != '\\' {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated text at %d", i)
+			}
+			text := strings.ReplaceAll(expr[i+1:end], `\`+string(ch), string(ch))
+			tokens = append(tokens, filterToken{"text", text})
+			i = end + 1
+		case strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, filterToken{"join", expr[i : i+2]})
+			i += 2
+		case strings.IndexByte("=!<>~?", ch) >= 0:
+			j := i
+			for j < len(expr) && strings.IndexByte("=!<>~?", expr[j]) >= 0 {
+				j++
+			}
+			tokens = append(tokens, filterToken{"sign", expr[i:j]})
+			i = j
+		case isFilterIdentByte(ch) || ch == '-':
+			j := i + 1
+			for j < len(expr) && isFilterIdentByte(expr[j]) {
+				j++
+			}
+			tokens = append(tokens, filterToken{"identifier", expr[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", ch, i)
+		}
+	}
+	return tokens, nil
+}
+
+func isFilterIdentByte(ch byte) bool {
+	return ch == '_' || ch == '.' || ch == '@' || ch == ':' ||
+		(ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+func unescapeLike(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\%`, `%`, `\_`, `_`).Replace(s)
+}
+
+func decodeFilter(t *testing.T, query string) string {
+	t.Helper()
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatalf("ParseQuery(%q) error = %v", query, err)
+	}
+	return values.Get("filter")
+}
+
+func TestPBQueryEncode(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query pbQuery
+		want  url.Values
+	}{
+		{
+			name:  "equality",
+			query: pbQuery{Filter: pbEq("short_code", "abc123"), PerPage: 1, SkipTotal: true},
+			want:  url.Values{"filter": {`short_code="abc123"`}, "perPage": {"1"}, "skipTotal": {"true"}},
+		},
+		{
+			name:  "quotes are escaped",
+			query: pbQuery{Filter: pbEq("short_code", `a" || id!="`)},
+			want:  url.Values{"filter": {`short_code="a\" || id!=\""`}},
+		},
+		{
+			name: "conjunction with sort and paging",
+			query: pbQuery{
+				Filter:  pbAnd(pbContains("url", "50%_off"), pbGreater("access_count", int64(10)), pbGreater("created", created)),
+				Sort:    []pbSort{{Field: "access_count", Desc: true}, {Field: "created"}},
+				Page:    2,
+				PerPage: 30,
+			},
+			want: url.Values{
+				"filter":  {`(url~"50\%\_off") && (access_count>10) && (created>"2025-03-01 12:00:00.000Z")`},
+				"sort":    {"-access_count,created"},
+				"page":    {"2"},
+				"perPage": {"30"},
+			},
+		},
+		{
+			name:  "empty filters are skipped",
+			query: pbQuery{Filter: pbAnd(pbFilter{}, pbEq("enabled", true))},
+			want:  url.Values{"filter": {`(enabled=true)`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.encode()
+			if err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+			if want := tt.want.Encode(); got != want {
+				t.Errorf("encode() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPBQueryEncodeRejectsUnsafeInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		query pbQuery
+	}{
+		{"trailing backslash", pbQuery{Filter: pbEq("short_code", `abc\`)}},
+		{"field injection", pbQuery{Filter: pbEq(`short_code="x" || id`, "x")}},
+		{"sort injection", pbQuery{Sort: []pbSort{{Field: "created,@random"}}}},
+		{"unsupported value", pbQuery{Filter: pbEq("short_code", []string{"x"})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.query.encode(); err == nil {
+				t.Errorf("encode() = %q, want error", got)
+			}
+		})
+	}
+
+	if _, err := (pbQuery{Filter: pbEq("short_code", `abc\`)}).encode(); !errors.Is(err, errUnsafeFilterValue) {
+		t.Errorf("encode() error = %v, want errUnsafeFilterValue", err)
+	}
+}
+
+func FuzzPBFilterLiteralsCannotBreakOut(f *testing.F) {
+	seeds := []string{"abc123", `"`, `'`, `\"`, `" || id!="`, `a\" && url~"`, `%_\`, "line\nbreak", `\\"x`, "(", ")&&("}
+	for _, a := range seeds {
+		for _, b := range seeds {
+			f.Add(a, b)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, code, contains string) {
+		query, err := pbQuery{Filter: pbAnd(pbEq("short_code", code), pbContains("url", contains))}.encode()
+		if strings.HasSuffix(code, `\`) || strings.HasSuffix(contains, `\`) {
+			if !errors.Is(err, errUnsafeFilterValue) {
+				t.Fatalf("encode() error = %v, want errUnsafeFilterValue", err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("encode() error = %v", err)
+		}
+
+		filter := decodeFilter(t, query)
+		tokens, err := scanPBFilter(filter)
+		if err != nil {
+			t.Fatalf("scanPBFilter(%q) error = %v", filter, err)
+		}
+
+		want := []filterToken{
+			{"group", "("}, {"identifier", "short_code"}, {"sign", "="}, {"text", code}, {"group", ")"},
+			{"join", "&&"},
+			{"group", "("}, {"identifier", "url"}, {"sign", "~"}, {"text", tokens[len(tokens)-2].literal}, {"group", ")"},
+		}
+		if fmt.Sprint(tokens) != fmt.Sprint(want) {
+			t.Fatalf("scanPBFilter(%q) = %v, want %v", filter, tokens, want)
+		}
+		if got := unescapeLike(tokens[len(tokens)-2].literal); got != contains {
+			t.Fatalf("contains literal = %q, want %q", got, contains)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+//go:build postgres
+
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rowjay/url-shortening-service/internal/database"
+)
+
+// TestPostgresURLRepository runs against the database in TEST_POSTGRES_DSN,
+// e.g. the one started by "task db:up". It truncates short_urls between runs.
+func TestPostgresURLRepository(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+
+	db, err := database.OpenSQL(constants.StorageDriverPostgres, dsn, true)
+	if err != nil {
+		t.Fatalf("OpenSQL() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	runURLRepositoryConformance(t, func(t *testing.T) URLRepository {
+		if _, err := db.ExecContext(context.Background(), "TRUNCATE short_urls"); err != nil {
+			t.Fatalf("truncate short_urls: %v", err)
+		}
+		return NewPostgresURLRepository(db)
+	})
+}
+
+func TestPostgresHistoryRepository(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+
+	db, err := database.OpenSQL(constants.StorageDriverPostgres, dsn, true)
+	if err != nil {
+		t.Fatalf("OpenSQL() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	runHistoryRepositoryConformance(t, func(t *testing.T) HistoryRepository {
+		if _, err := db.ExecContext(context.Background(), "TRUNCATE short_url_history"); err != nil {
+			t.Fatalf("truncate short_url_history: %v", err)
+		}
+		return NewPostgresHistoryRepository(db)
+	})
+}
+
+func TestPostgresKeyRepository(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+
+	db, err := database.OpenSQL(constants.StorageDriverPostgres, dsn, true)
+	if err != nil {
+		t.Fatalf("OpenSQL() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	runKeyRepositoryConformance(t, func(t *testing.T) (URLRepository, KeyRepository) {
+		if _, err := db.ExecContext(context.Background(), "TRUNCATE short_urls, short_code_keys"); err != nil {
+			t.Fatalf("truncate short_urls and short_code_keys: %v", err)
+		}
+		return NewPostgresURLRepository(db), NewPostgresKeyRepository(db)
+	})
+}
+
+func TestPostgresSequenceRepository(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+
+	db, err := database.OpenSQL(constants.StorageDriverPostgres, dsn, true)
+	if err != nil {
+		t.Fatalf("OpenSQL() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	runSequenceRepositoryConformance(t, func(t *testing.T) SequenceRepository {
+		if _, err := db.ExecContext(context.Background(), "TRUNCATE short_code_sequence"); err != nil {
+			t.Fatalf("truncate short_code_sequence: %v", err)
+		}
+		return NewPostgresSequenceRepository(db)
+	})
+}
@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/cache/redis"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// redisFlushBatch bounds how many dirty codes one flush pops at a time.
+const redisFlushBatch = 500
+
+// redisEntry is a cached lookup, tagged with the generation of the code it
+// was read under. Link is nil for an unknown code.
+type redisEntry struct {
+	Generation int64               `json:"generation"`
+	Link       *urlModels.ShortURL `json:"link,omitempty"`
+}
+
+type RedisCacheOptions struct {
+	KeyPrefix     string
+	TTL           time.Duration
+	NegativeTTL   time.Duration
+	FlushInterval time.Duration
+}
+
+// RedisURLRepository is a cache tier shared by all replicas. Lookups are
+// cached in Redis, and access counts are accumulated in Redis counters and
+// flushed back to the wrapped repository in pipelined batches, so every
+// replica reports the same statistics. Redis failures fall back to the
+// wrapped repository rather than failing requests.
+//
+// Every write bumps a per-code generation counter, and an entry is only
+// served while its generation is current, so a lookup that read storage
+// before a concurrent write cannot cache the old record.
+type RedisURLRepository struct {
+	inner  URLRepository
+	client *redis.Client
+	opts   RedisCacheOptions
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewRedisURLRepository(inner URLRepository, client *redis.Client, opts RedisCacheOptions) *RedisURLRepository {
+	return &RedisURLRepository{
+		inner:  inner,
+		client: client,
+		opts:   opts,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+func (r *RedisURLRepository) urlKey(shortCode string) string {
+	return r.opts.KeyPrefix + "lookup:" + shortCode
+}
+
+func (r *RedisURLRepository) generationKey(shortCode string) string {
+	return r.opts.KeyPrefix + "gen:" + shortCode
+}
+
+func (r *RedisURLRepository) clicksKey(shortCode string) string {
+	return r.opts.KeyPrefix + "clicks:" + shortCode
+}
+
+func (r *RedisURLRepository) dirtyKey() string {
+	return r.opts.KeyPrefix + "clicks:dirty"
+}
+
+func (r *RedisURLRepository) Create(ctx context.Context, shortURL *urlModels.ShortURL) error {
+	if err := r.inner.Create(ctx, shortURL); err != nil {
+		return err
+	}
+	r.invalidate(ctx, shortURL.ShortCode)
+	return nil
+}
+
+func (r *RedisURLRepository) GetByShortCode(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	replies, err := r.client.Pipeline(ctx, [][]string{
+		{"GET", r.urlKey(shortCode)},
+		{"GET", r.generationKey(shortCode)},
+		{"GET", r.clicksKey(shortCode)},
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("short_code", shortCode).Msg("Redis lookup failed, falling back to storage")
+		return r.inner.GetByShortCode(ctx, shortCode)
+	}
+
+	generation, _ := redis.AsInt64(replies[1])
+	pending, _ := redis.AsInt64(replies[2])
+
+	if entry, ok := r.entry(shortCode, replies[0], generation); ok {
+		if entry.Link == nil {
+			return nil, serviceErrors.NewNotFoundError("repository.GetByShortCode", "short URL not found")
+		}
+		entry.Link.AccessCount += pending
+		return entry.Link, nil
+	}
+
+	shortURL, err := r.inner.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		var serviceErr *serviceErrors.ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeNotFound && r.opts.NegativeTTL > 0 {
+			r.store(ctx, shortCode, redisEntry{Generation: generation}, r.opts.NegativeTTL)
+		}
+		return nil, err
+	}
+
+	// Password hashes stay out of the shared cache, so protected links are
+	// always read from storage.
+	if !shortURL.Protected() {
+		r.store(ctx, shortCode, redisEntry{Generation: generation, Link: shortURL}, r.opts.TTL)
+	}
+	shortURL.AccessCount += pending
+	return shortURL, nil
+}
+
+// entry decodes a cached lookup. Entries filled under an older generation
+// lost a race with a write and are ignored.
+func (r *RedisURLRepository) entry(shortCode string, reply any, generation int64) (redisEntry, bool) {
+	cached, ok := reply.(string)
+	if !ok {
+		return redisEntry{}, false
+	}
+	var entry redisEntry
+	if err := json.Unmarshal([]byte(cached), &entry); err != nil {
+		log.Warn().Str("short_code", shortCode).Msg("Discarding undecodable Redis cache entry")
+		return redisEntry{}, false
+	}
+	return entry, entry.Generation == generation
+}
+
+func (r *RedisURLRepository) Update(ctx context.Context, shortCode string, update *urlModels.ShortURLUpdate) (*urlModels.ShortURL, error) {
+	defer r.invalidate(ctx, shortCode)
+	return r.inner.Update(ctx, shortCode, update)
+}
+
+func (r *RedisURLRepository) Delete(ctx context.Context, shortCode string) error {
+	defer r.invalidate(ctx, shortCode)
+	defer r.dropCounter(ctx, shortCode)
+	return r.inner.Delete(ctx, shortCode)
+}
+
+// IncrementAccessCount adds delta to the shared Redis counter; the wrapped
+// repository sees it on the next flush.
+func (r *RedisURLRepository) IncrementAccessCount(ctx context.Context, shortCode string, delta int64) error {
+	if _, err := r.GetByShortCode(ctx, shortCode); err != nil {
+		return err
+	}
+
+	_, err := r.client.Pipeline(ctx, [][]string{
+		{"INCRBY", r.clicksKey(shortCode), strconv.FormatInt(delta, 10)},
+		{"SADD", r.dirtyKey(), shortCode},
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("short_code", shortCode).Msg("Redis counter update failed, writing to storage directly")
+		return r.inner.IncrementAccessCount(ctx, shortCode, delta)
+	}
+	return nil
+}
+
+// ConsumeClick bypasses the Redis counters: the limit is enforced by the
+// wrapped repository. The cached record is dropped rather than replaced,
+// since concurrent consumers finish in no particular order.
+func (r *RedisURLRepository) ConsumeClick(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	defer r.invalidate(ctx, shortCode)
+	return r.inner.ConsumeClick(ctx, shortCode)
+}
+
+func (r *RedisURLRepository) ExistsByShortCode(ctx context.Context, shortCode string) (bool, error) {
+	replies, err := r.client.Pipeline(ctx, [][]string{
+		{"GET", r.urlKey(shortCode)},
+		{"GET", r.generationKey(shortCode)},
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("short_code", shortCode).Msg("Redis lookup failed, falling back to storage")
+		return r.inner.ExistsByShortCode(ctx, shortCode)
+	}
+	generation, _ := redis.AsInt64(replies[1])
+	if entry, ok := r.entry(shortCode, replies[0], generation); ok {
+		return entry.Link != nil, nil
+	}
+	return r.inner.ExistsByShortCode(ctx, shortCode)
+}
+
+// List reads from the wrapped repository and adds the clicks still pending
+// in Redis to each item's count.
+func (r *RedisURLRepository) List(ctx context.Context, opts *urlModels.ListOptions) (*urlModels.ListResult, error) {
+	result, err := r.inner.List(ctx, opts)
+	if err != nil || len(result.Items) == 0 {
+		return result, err
+	}
+
+	cmds := make([][]string, len(result.Items))
+	for i, shortURL := range result.Items {
+		cmds[i] = []string{"GET", r.clicksKey(shortURL.ShortCode)}
+	}
+	replies, err := r.client.Pipeline(ctx, cmds)
+	if err != nil {
+		log.Warn().Err(err).Msg("Redis counter lookup failed, listing stored counts only")
+		return result, nil
+	}
+	for i, shortURL := range result.Items {
+		pending, _ := redis.AsInt64(replies[i])
+		shortURL.AccessCount += pending
+	}
+	return result, nil
+}
+
+// Start runs the periodic counter flush until Stop is called.
+func (r *RedisURLRepository) Start() {
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.opts.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.FlushCounters(context.Background()); err != nil {
+					log.Warn().Err(err).Msg("Failed to flush Redis access counters, will retry")
+				}
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the flush loop and flushes whatever is still pending.
+func (r *RedisURLRepository) Stop(ctx context.Context) error {
+	close(r.stop)
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return r.FlushCounters(ctx)
+}
+
+// FlushCounters moves accumulated counters from Redis into the wrapped
+// repository. Codes are claimed with SPOP and counters with GETDEL, so
+// replicas flushing concurrently never persist the same clicks twice.
+func (r *RedisURLRepository) FlushCounters(ctx context.Context) error {
+	var errs []error
+	for {
+		reply, err := r.client.Do(ctx, "SPOP", r.dirtyKey(), strconv.Itoa(redisFlushBatch))
+		if err != nil {
+			return err
+		}
+		codes, err := redis.AsStrings(reply)
+		if err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return errors.Join(errs...)
+		}
+
+		cmds := make([][]string, len(codes))
+		for i, shortCode := range codes {
+			cmds[i] = []string{"GETDEL", r.clicksKey(shortCode)}
+		}
+		replies, err := r.client.Pipeline(ctx, cmds)
+		if err != nil {
+			// Counters were not taken; mark the codes dirty again.
+			r.markDirty(ctx, codes...)
+			return err
+		}
+
+		var flushed []string
+		var requeue [][]string
+		for i, shortCode := range codes {
+			delta, err := redis.AsInt64(replies[i])
+			if err != nil || delta == 0 {
+				continue
+			}
+
+			err = r.inner.IncrementAccessCount(ctx, shortCode, delta)
+			var serviceErr *serviceErrors.ServiceError
+			switch {
+			case err == nil:
+				flushed = append(flushed, shortCode)
+			case errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeNotFound:
+				log.Debug().Str("short_code", shortCode).Int64("delta", delta).Msg("Dropping access count for missing short URL")
+			default:
+				errs = append(errs, err)
+				requeue = append(requeue,
+					[]string{"INCRBY", r.clicksKey(shortCode), strconv.FormatInt(delta, 10)},
+					[]string{"SADD", r.dirtyKey(), shortCode})
+			}
+		}
+
+		// The cached records now lag the stored counts; drop them.
+		if len(flushed) > 0 {
+			r.invalidate(ctx, flushed...)
+		}
+		if len(requeue) > 0 {
+			if _, err := r.client.Pipeline(ctx, requeue); err != nil {
+				log.Error().Err(err).Int("codes", len(requeue)/2).Msg("Failed to requeue access counters")
+			}
+			return errors.Join(errs...)
+		}
+
+		log.Debug().Int("codes", len(codes)).Msg("Flushed Redis access counters")
+	}
+}
+
+func (r *RedisURLRepository) store(ctx context.Context, shortCode string, entry redisEntry, ttl time.Duration) {
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := r.client.Set(ctx, r.urlKey(shortCode), string(encoded), ttl); err != nil {
+		log.Warn().Err(err).Str("short_code", shortCode).Msg("Failed to cache short URL in Redis")
+	}
+}
+
+func (r *RedisURLRepository) markDirty(ctx context.Context, codes ...string) {
+	if _, err := r.client.Do(ctx, append([]string{"SADD", r.dirtyKey()}, codes...)...); err != nil {
+		log.Error().Err(err).Msg("Failed to mark Redis access counters dirty")
+	}
+}
+
+// invalidate drops the cached lookups and bumps the generations of
+// shortCodes. Counters are kept for twice the longest TTL, so entries
+// filled under an older generation expire before a counter can start
+// again from zero.
+func (r *RedisURLRepository) invalidate(ctx context.Context, shortCodes ...string) {
+	keep := strconv.FormatInt((2 * max(r.opts.TTL, r.opts.NegativeTTL)).Milliseconds(), 10)
+	cmds := make([][]string, 0, 3*len(shortCodes))
+	for _, shortCode := range shortCodes {
+		cmds = append(cmds,
+			[]string{"DEL", r.urlKey(shortCode)},
+			[]string{"INCRBY", r.generationKey(shortCode), "1"},
+			[]string{"PEXPIRE", r.generationKey(shortCode), keep})
+	}
+	if _, err := r.client.Pipeline(ctx, cmds); err != nil {
+		log.Warn().Err(err).Strs("short_codes", shortCodes).Msg("Failed to invalidate Redis cache")
+	}
+}
+
+// dropCounter discards the clicks still pending for a deleted code.
+func (r *RedisURLRepository) dropCounter(ctx context.Context, shortCode string) {
+	if err := r.client.Del(ctx, r.clicksKey(shortCode)); err != nil {
+		log.Warn().Err(err).Str("short_code", shortCode).Msg("Failed to drop Redis access counter")
+	}
+}
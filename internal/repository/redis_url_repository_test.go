@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/cache/redis"
+	"github.com/rowjay/url-shortening-service/internal/cache/redis/redistest"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+)
+
+var testRedisOptions = RedisCacheOptions{
+	KeyPrefix:     "test:",
+	TTL:           time.Minute,
+	NegativeTTL:   time.Minute,
+	FlushInterval: time.Hour,
+}
+
+func newRedisTestRepository(t *testing.T, inner URLRepository, client *redis.Client) *RedisURLRepository {
+	t.Helper()
+	return NewRedisURLRepository(inner, client, testRedisOptions)
+}
+
+func TestRedisURLRepository(t *testing.T) {
+	runURLRepositoryConformance(t, func(t *testing.T) URLRepository {
+		server := redistest.NewServer(t)
+		return newRedisTestRepository(t, NewMemoryURLRepository(), server.Client(t))
+	})
+}
+
+func TestRedisURLRepositoryServesLookupsFromRedis(t *testing.T) {
+	ctx := context.Background()
+	server := redistest.NewServer(t)
+	inner := &countingRepository{URLRepository: NewMemoryURLRepository()}
+	repo := newRedisTestRepository(t, inner, server.Client(t))
+
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "hot"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := repo.GetByShortCode(ctx, "hot"); err != nil {
+			t.Fatalf("GetByShortCode() error = %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := repo.GetByShortCode(ctx, "nope"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+			t.Fatalf("GetByShortCode() error = %v, want not found", err)
+		}
+	}
+	if got := inner.lookups.Load(); got != 2 {
+		t.Errorf("inner lookups = %d, want 2", got)
+	}
+
+	// Entries expire after the TTL.
+	server.FastForward(2 * time.Minute)
+	if _, err := repo.GetByShortCode(ctx, "hot"); err != nil {
+		t.Fatalf("GetByShortCode() error = %v", err)
+	}
+	if got := inner.lookups.Load(); got != 3 {
+		t.Errorf("inner lookups after expiry = %d, want 3", got)
+	}
+}
+
+func TestRedisURLRepositorySharesCountersAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+	server := redistest.NewServer(t)
+	storage := NewMemoryURLRepository()
+	replicas := []*RedisURLRepository{
+		newRedisTestRepository(t, storage, server.Client(t)),
+		newRedisTestRepository(t, storage, server.Client(t)),
+	}
+
+	if err := replicas[0].Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "shared"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	const perReplica = 500
+	var wg sync.WaitGroup
+	for _, replica := range replicas {
+		for i := 0; i < perReplica; i++ {
+			wg.Add(1)
+			go func(r *RedisURLRepository) {
+				defer wg.Done()
+				if err := r.IncrementAccessCount(ctx, "shared", 1); err != nil {
+					t.Errorf("IncrementAccessCount() error = %v", err)
+				}
+			}(replica)
+		}
+	}
+	wg.Wait()
+
+	// Both replicas see every click before anything is flushed.
+	for i, replica := range replicas {
+		got, err := replica.GetByShortCode(ctx, "shared")
+		if err != nil {
+			t.Fatalf("GetByShortCode() error = %v", err)
+		}
+		if got.AccessCount != 2*perReplica {
+			t.Errorf("replica %d AccessCount = %d, want %d", i, got.AccessCount, 2*perReplica)
+		}
+	}
+	if stored, _ := storage.GetByShortCode(ctx, "shared"); stored.AccessCount != 0 {
+		t.Errorf("stored AccessCount before flush = %d, want 0", stored.AccessCount)
+	}
+
+	// Flushing from both replicas at once must persist each click once.
+	for _, replica := range replicas {
+		wg.Add(1)
+		go func(r *RedisURLRepository) {
+			defer wg.Done()
+			if err := r.FlushCounters(ctx); err != nil {
+				t.Errorf("FlushCounters() error = %v", err)
+			}
+		}(replica)
+	}
+	wg.Wait()
+
+	stored, err := storage.GetByShortCode(ctx, "shared")
+	if err != nil {
+		t.Fatalf("GetByShortCode() error = %v", err)
+	}
+	if stored.AccessCount != 2*perReplica {
+		t.Errorf("stored AccessCount = %d, want %d", stored.AccessCount, 2*perReplica)
+	}
+	if got, _ := replicas[1].GetByShortCode(ctx, "shared"); got.AccessCount != 2*perReplica {
+		t.Errorf("AccessCount after flush = %d, want %d", got.AccessCount, 2*perReplica)
+	}
+}
+
+// failingIncrementRepository rejects counter writes until healed.
+type failingIncrementRepository struct {
+	URLRepository
+	mu      sync.Mutex
+	failing bool
+}
+
+func (r *failingIncrementRepository) IncrementAccessCount(ctx context.Context, shortCode string, delta int64) error {
+	r.mu.Lock()
+	failing := r.failing
+	r.mu.Unlock()
+	if failing {
+		return serviceErrors.NewInternalError("repository.IncrementAccessCount", "storage unavailable", nil)
+	}
+	return r.URLRepository.IncrementAccessCount(ctx, shortCode, delta)
+}
+
+func TestRedisURLRepositoryRequeuesFailedFlushes(t *testing.T) {
+	ctx := context.Background()
+	server := redistest.NewServer(t)
+	inner := &failingIncrementRepository{URLRepository: NewMemoryURLRepository(), failing: true}
+	repo := newRedisTestRepository(t, inner, server.Client(t))
+
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "retry"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.IncrementAccessCount(ctx, "retry", 7); err != nil {
+		t.Fatalf("IncrementAccessCount() error = %v", err)
+	}
+
+	if err := repo.FlushCounters(ctx); err == nil {
+		t.Fatal("FlushCounters() error = nil, want storage error")
+	}
+	if got, _ := repo.GetByShortCode(ctx, "retry"); got.AccessCount != 7 {
+		t.Errorf("AccessCount after failed flush = %d, want 7", got.AccessCount)
+	}
+
+	inner.mu.Lock()
+	inner.failing = false
+	inner.mu.Unlock()
+
+	if err := repo.FlushCounters(ctx); err != nil {
+		t.Fatalf("FlushCounters() error = %v", err)
+	}
+	stored, _ := inner.GetByShortCode(ctx, "retry")
+	if stored.AccessCount != 7 {
+		t.Errorf("stored AccessCount = %d, want 7", stored.AccessCount)
+	}
+}
+
+func TestRedisURLRepositoryKeepsPasswordHashesOutOfRedis(t *testing.T) {
+	ctx := context.Background()
+	server := redistest.NewServer(t)
+	client := server.Client(t)
+	inner := &countingRepository{URLRepository: NewMemoryURLRepository()}
+	repo := newRedisTestRepository(t, inner, client)
+
+	for _, shortURL := range []*urlModels.ShortURL{
+		{URL: "https://example.com", ShortCode: "open"},
+		{URL: "https://example.com", ShortCode: "locked", PasswordHash: "$2a$10$secret"},
+	} {
+		if err := repo.Create(ctx, shortURL); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			got, err := repo.GetByShortCode(ctx, shortURL.ShortCode)
+			if err != nil {
+				t.Fatalf("GetByShortCode() error = %v", err)
+			}
+			if got.PasswordHash != shortURL.PasswordHash {
+				t.Errorf("GetByShortCode(%q) PasswordHash = %q, want %q", shortURL.ShortCode, got.PasswordHash, shortURL.PasswordHash)
+			}
+		}
+	}
+
+	replies, err := client.Pipeline(ctx, [][]string{
+		{"GET", testRedisOptions.KeyPrefix + "lookup:open"},
+		{"GET", testRedisOptions.KeyPrefix + "lookup:locked"},
+	})
+	if err != nil {
+		t.Fatalf("Pipeline() error = %v", err)
+	}
+	if cached, ok := replies[0].(string); !ok || strings.Contains(cached, "passwordHash") {
+		t.Errorf("cached open link = %v, want an entry without a password hash", replies[0])
+	}
+	if replies[1] != nil {
+		t.Errorf("cached protected link = %v, want none", replies[1])
+	}
+	// The open link is served from Redis; the protected one from storage.
+	if got := inner.lookups.Load(); got != 3 {
+		t.Errorf("inner lookups = %d, want 3", got)
+	}
+}
+
+func TestRedisURLRepositoryDoesNotCacheLookupsRacingAWrite(t *testing.T) {
+	ctx := context.Background()
+	server := redistest.NewServer(t)
+	client := server.Client(t)
+	inner := &stallingRepository{URLRepository: NewMemoryURLRepository(), read: make(chan struct{}), release: make(chan struct{})}
+	repo := newRedisTestRepository(t, inner, client)
+	if err := inner.URLRepository.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "code"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		repo.GetByShortCode(ctx, "code")
+	}()
+	<-inner.read
+
+	// Another replica updates the link while the lookup is in flight.
+	other := newRedisTestRepository(t, inner, client)
+	newURL := "https://example.org"
+	if _, err := other.Update(ctx, "code", &urlModels.ShortURLUpdate{URL: &newURL}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	close(inner.release)
+	<-done
+
+	inner.read = make(chan struct{})
+	if got, err := repo.GetByShortCode(ctx, "code"); err != nil || got.URL != newURL {
+		t.Errorf("GetByShortCode() after update = %+v, %v, want URL %s", got, err, newURL)
+	}
+}
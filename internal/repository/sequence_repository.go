@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rowjay/url-shortening-service/internal/database"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// SequenceRepository stores the sequence the counter-based code generators
+// encode, so every process and replica sharing the storage continues it.
+type SequenceRepository interface {
+	// Allocate reserves the next n numbers and returns the first of them.
+	// The sequence starts at 0, and no two calls get overlapping numbers,
+	// even across restarts.
+	Allocate(ctx context.Context, n uint64) (uint64, error)
+}
+
+// pocketBaseBlockRecord is a block of numbers, first included and next
+// excluded. Each block starts where the previous one ends.
+type pocketBaseBlockRecord struct {
+	First uint64 `json:"first"`
+	Next  uint64 `json:"next"`
+}
+
+type pocketBaseBlockListResponse struct {
+	Items []pocketBaseBlockRecord `json:"items"`
+}
+
+// pocketBaseSequenceRepository implements SequenceRepository on the
+// short_code_blocks collection.
+type pocketBaseSequenceRepository struct {
+	pb *database.PBClient
+}
+
+func NewSequenceRepository(pb *database.PBClient) SequenceRepository {
+	return &pocketBaseSequenceRepository{pb: pb}
+}
+
+// Allocate reads the latest block and creates the one after it. A writer
+// that loses a race trips the unique index on first and retries.
+func (r *pocketBaseSequenceRepository) Allocate(ctx context.Context, n uint64) (uint64, error) {
+	const op = "repository.AllocateSequence"
+
+	var err error
+	for attempt := 0; attempt < constants.MaxRetries; attempt++ {
+		var first uint64
+		first, err = r.latest(ctx, op)
+		if err != nil {
+			return 0, err
+		}
+		err = r.create(ctx, op, pocketBaseBlockRecord{First: first, Next: first + n})
+		var serviceErr *serviceErrors.ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeDuplicate {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return first, nil
+	}
+	return 0, err
+}
+
+// latest returns where the latest block ends, or 0 before the first one.
+func (r *pocketBaseSequenceRepository) latest(ctx context.Context, op string) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	encoded, err := pbQuery{Sort: []pbSort{{Field: "first", Desc: true}}, PerPage: 1, SkipTotal: true}.encode()
+	if err != nil {
+		return 0, serviceErrors.NewInternalError(op, "failed to encode query", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/api/collections/%s/records?%s", r.pb.BaseURL, constants.SequenceCollection, encoded), nil)
+	if err != nil {
+		return 0, serviceErrors.NewInternalError(op, "failed to create request", err)
+	}
+
+	resp, err := r.pb.HTTPClient.Do(req)
+	if err != nil {
+		return 0, pocketBaseTransportError(op, "failed to query sequence", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, pocketBaseError(op, constants.SequenceCollection, pbCollectionEndpoint, resp)
+	}
+
+	var pbResp pocketBaseBlockListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&pbResp); err != nil {
+		return 0, serviceErrors.NewInternalError(op, "failed to decode response", err)
+	}
+	if len(pbResp.Items) == 0 {
+		return 0, nil
+	}
+	return pbResp.Items[0].Next, nil
+}
+
+func (r *pocketBaseSequenceRepository) create(ctx context.Context, op string, record pocketBaseBlockRecord) error {
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	jsonBody, err := json.Marshal(record)
+	if err != nil {
+		return serviceErrors.NewInternalError(op, "failed to marshal request", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		r.pb.BaseURL+"/api/collections/"+constants.SequenceCollection+"/records", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return serviceErrors.NewInternalError(op, "failed to create request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.pb.HTTPClient.Do(req)
+	if err != nil {
+		return pocketBaseTransportError(op, "failed to create sequence block", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		log.Error().Int("status", resp.StatusCode).Uint64("first", record.First).Msg("PocketBase rejected sequence block")
+		return pocketBaseError(op, constants.SequenceCollection, pbCollectionEndpoint, resp)
+	}
+	return nil
+}
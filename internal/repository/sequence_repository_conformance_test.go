@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// runSequenceRepositoryConformance exercises the SequenceRepository
+// contract against a fresh repository returned by newRepo for every
+// subtest.
+func runSequenceRepositoryConformance(t *testing.T, newRepo func(t *testing.T) SequenceRepository) {
+	t.Run("Allocate", func(t *testing.T) { testSequenceRepositoryAllocate(t, newRepo(t)) })
+	t.Run("ConcurrentAllocate", func(t *testing.T) { testSequenceRepositoryConcurrentAllocate(t, newRepo(t)) })
+}
+
+func testSequenceRepositoryAllocate(t *testing.T, repo SequenceRepository) {
+	ctx := context.Background()
+	var got []uint64
+	for _, n := range []uint64{10, 1, 5} {
+		first, err := repo.Allocate(ctx, n)
+		if err != nil {
+			t.Fatalf("Allocate(%d) error = %v", n, err)
+		}
+		got = append(got, first)
+	}
+	if want := []uint64{0, 10, 11}; !slices.Equal(got, want) {
+		t.Errorf("Allocate() = %v, want %v", got, want)
+	}
+}
+
+func testSequenceRepositoryConcurrentAllocate(t *testing.T, repo SequenceRepository) {
+	ctx := context.Background()
+	const (
+		allocators = 4
+		block      = 10
+	)
+	firsts := make([]uint64, allocators)
+	var wg sync.WaitGroup
+	for i := range allocators {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			first, err := repo.Allocate(ctx, block)
+			if err != nil {
+				t.Errorf("Allocate() error = %v", err)
+			}
+			firsts[i] = first
+		}()
+	}
+	wg.Wait()
+
+	slices.Sort(firsts)
+	if want := []uint64{0, 10, 20, 30}; !slices.Equal(firsts, want) {
+		t.Errorf("concurrent Allocate() = %v, want consecutive blocks %v", firsts, want)
+	}
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// sqlHistoryRepository implements HistoryRepository on the
+// short_url_history table.
+type sqlHistoryRepository struct {
+	db     *sql.DB
+	rebind func(query string) string
+}
+
+func NewSQLiteHistoryRepository(db *sql.DB) HistoryRepository {
+	return &sqlHistoryRepository{db: db, rebind: func(query string) string { return query }}
+}
+
+func NewPostgresHistoryRepository(db *sql.DB) HistoryRepository {
+	return &sqlHistoryRepository{db: db, rebind: rebindDollar}
+}
+
+// Append numbers the change in the same statement that stores it. Writers
+// racing for a version collide on the primary key and try the next one.
+func (r *sqlHistoryRepository) Append(ctx context.Context, change *urlModels.DestinationChange) error {
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	query := r.rebind(`INSERT INTO short_url_history (url_id, version, short_code, old_url, new_url, actor, changed_at)
+		SELECT ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, ?, ? FROM short_url_history WHERE url_id = ?
+		RETURNING version`)
+
+	var err error
+	for attempt := 0; attempt < constants.MaxRetries; attempt++ {
+		err = r.db.QueryRowContext(ctx, query, change.URLID, change.ShortCode, change.OldURL, change.NewURL,
+			change.Actor, change.ChangedAt.UTC(), change.URLID).Scan(&change.Version)
+		if err == nil || !isUniqueViolation(err) {
+			break
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Str("short_code", change.ShortCode).Msg("Failed to append destination history")
+		return serviceErrors.NewInternalError("repository.AppendHistory", "failed to insert history", err)
+	}
+	return nil
+}
+
+func (r *sqlHistoryRepository) List(ctx context.Context, urlID string) ([]*urlModels.DestinationChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT url_id, short_code, version, old_url, new_url, actor, changed_at
+		FROM short_url_history WHERE url_id = ? ORDER BY version`), urlID)
+	if err != nil {
+		log.Error().Err(err).Str("url_id", urlID).Msg("Failed to list destination history")
+		return nil, serviceErrors.NewInternalError("repository.ListHistory", "failed to list history", err)
+	}
+	defer rows.Close()
+
+	var changes []*urlModels.DestinationChange
+	for rows.Next() {
+		var change urlModels.DestinationChange
+		var changedAt sqlTime
+		if err := rows.Scan(&change.URLID, &change.ShortCode, &change.Version, &change.OldURL, &change.NewURL, &change.Actor, &changedAt); err != nil {
+			return nil, serviceErrors.NewInternalError("repository.ListHistory", "failed to scan history", err)
+		}
+		change.ChangedAt = changedAt.Time
+		changes = append(changes, &change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, serviceErrors.NewInternalError("repository.ListHistory", "failed to list history", err)
+	}
+	return changes, nil
+}
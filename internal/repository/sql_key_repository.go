@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// sqlKeyRepository implements KeyRepository on the short_code_keys table.
+type sqlKeyRepository struct {
+	db     *sql.DB
+	rebind func(query string) string
+}
+
+func NewSQLiteKeyRepository(db *sql.DB) KeyRepository {
+	return &sqlKeyRepository{db: db, rebind: func(query string) string { return query }}
+}
+
+func NewPostgresKeyRepository(db *sql.DB) KeyRepository {
+	return &sqlKeyRepository{db: db, rebind: rebindDollar}
+}
+
+func (r *sqlKeyRepository) MaxReserve() int {
+	return 0
+}
+
+// Reserve inserts the candidates no link uses in one statement; the
+// primary key skips codes reserved before, including by a concurrent
+// caller.
+func (r *sqlKeyRepository) Reserve(ctx context.Context, candidates []string) ([]string, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	args := make([]any, len(candidates))
+	for i, code := range candidates {
+		args[i] = code
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(candidates)), ", ")
+	taken, err := r.codes(ctx, `SELECT short_code FROM short_urls WHERE short_code IN (`+placeholders+`)`, args...)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to look up taken short codes")
+		return nil, serviceErrors.NewInternalError("repository.ReserveKeys", "failed to look up short codes", err)
+	}
+	takenSet := make(map[string]bool, len(taken))
+	for _, code := range taken {
+		takenSet[code] = true
+	}
+
+	now := time.Now().UTC()
+	var values []string
+	args = args[:0]
+	for _, code := range candidates {
+		if !takenSet[code] {
+			values = append(values, "(?, ?)")
+			args = append(args, code, now)
+		}
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	reserved, err := r.codes(ctx, `INSERT INTO short_code_keys (short_code, reserved_at) VALUES `+strings.Join(values, ", ")+`
+		ON CONFLICT (short_code) DO NOTHING RETURNING short_code`, args...)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reserve short codes")
+		return nil, serviceErrors.NewInternalError("repository.ReserveKeys", "failed to reserve short codes", err)
+	}
+	return reserved, nil
+}
+
+// codes runs query and returns the single text column of its rows.
+func (r *sqlKeyRepository) codes(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
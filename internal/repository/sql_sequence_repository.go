@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// shortCodeSequence names the row of short_code_sequence the code
+// generators count in.
+const shortCodeSequence = "short_code"
+
+// sqlSequenceRepository implements SequenceRepository on the
+// short_code_sequence table.
+type sqlSequenceRepository struct {
+	db     *sql.DB
+	rebind func(query string) string
+}
+
+func NewSQLiteSequenceRepository(db *sql.DB) SequenceRepository {
+	return &sqlSequenceRepository{db: db, rebind: func(query string) string { return query }}
+}
+
+func NewPostgresSequenceRepository(db *sql.DB) SequenceRepository {
+	return &sqlSequenceRepository{db: db, rebind: rebindDollar}
+}
+
+// Allocate advances the counter row in one statement, creating it on first
+// use; the row lock orders concurrent callers.
+func (r *sqlSequenceRepository) Allocate(ctx context.Context, n uint64) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	var next int64
+	err := r.db.QueryRowContext(ctx, r.rebind(`INSERT INTO short_code_sequence (name, next_value) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET next_value = short_code_sequence.next_value + excluded.next_value
+		RETURNING next_value`), shortCodeSequence, int64(n)).Scan(&next)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to allocate sequence numbers")
+		return 0, serviceErrors.NewInternalError("repository.AllocateSequence", "failed to advance sequence", err)
+	}
+	return uint64(next) - n, nil
+}
@@ -0,0 +1,552 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+const shortURLColumns = "CAST(id AS TEXT), url, short_code, access_count, redirect_type, created, updated, expires_at, max_clicks, fallback_url, password_hash, active_from, active_until, disabled, disabled_reason, disabled_legal, deleted_at, metadata"
+
+// sqlURLRepository implements URLRepository on top of database/sql. Queries
+// are written with "?" placeholders and rebound for the target dialect.
+type sqlURLRepository struct {
+	db     *sql.DB
+	rebind func(query string) string
+	// ilike is the dialect's case-insensitive LIKE operator.
+	ilike string
+}
+
+func NewSQLiteURLRepository(db *sql.DB) URLRepository {
+	return &sqlURLRepository{
+		db:     db,
+		rebind: func(query string) string { return query },
+		ilike:  "LIKE",
+	}
+}
+
+func NewPostgresURLRepository(db *sql.DB) URLRepository {
+	return &sqlURLRepository{
+		db:     db,
+		rebind: rebindDollar,
+		ilike:  "ILIKE",
+	}
+}
+
+// rebindDollar rewrites "?" placeholders into PostgreSQL's $1, $2, ... form.
+func rebindDollar(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// sqlTime scans timestamps regardless of whether the driver hands them back
+// as time.Time or as text.
+type sqlTime struct {
+	time.Time
+}
+
+var sqlTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+func (t *sqlTime) Scan(value any) error {
+	switch v := value.(type) {
+	case time.Time:
+		t.Time = v.UTC()
+		return nil
+	case string:
+		return t.parse(v)
+	case []byte:
+		return t.parse(string(v))
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into timestamp", value)
+	}
+}
+
+func (t *sqlTime) parse(value string) error {
+	for _, layout := range sqlTimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			t.Time = parsed.UTC()
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognised timestamp %q", value)
+}
+
+// sqlNullTime converts an optional timestamp into a driver value, storing
+// nil as NULL.
+func sqlNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// sqlMetadata scans a JSON-encoded metadata column, which is empty when a
+// link has none.
+type sqlMetadata map[string]string
+
+func (m *sqlMetadata) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into metadata", value)
+	}
+	*m = nil
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, (*map[string]string)(m))
+}
+
+// sqlMetadataValue encodes metadata for the metadata column.
+func sqlMetadataValue(metadata map[string]string) (string, error) {
+	if len(metadata) == 0 {
+		return "", nil
+	}
+	encoded, err := json.Marshal(metadata)
+	return string(encoded), err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanShortURL(row rowScanner) (*urlModels.ShortURL, error) {
+	var shortURL urlModels.ShortURL
+	var created, updated, expiresAt, activeFrom, activeUntil, deletedAt sqlTime
+	var metadata sqlMetadata
+	if err := row.Scan(
+		&shortURL.ID,
+		&shortURL.URL,
+		&shortURL.ShortCode,
+		&shortURL.AccessCount,
+		&shortURL.RedirectType,
+		&created,
+		&updated,
+		&expiresAt,
+		&shortURL.MaxClicks,
+		&shortURL.FallbackURL,
+		&shortURL.PasswordHash,
+		&activeFrom,
+		&activeUntil,
+		&shortURL.Disabled,
+		&shortURL.DisabledReason,
+		&shortURL.DisabledLegal,
+		&deletedAt,
+		&metadata,
+	); err != nil {
+		return nil, err
+	}
+	shortURL.Created = created.Time
+	shortURL.Updated = updated.Time
+	if !expiresAt.IsZero() {
+		shortURL.ExpiresAt = &expiresAt.Time
+	}
+	if !activeFrom.IsZero() {
+		shortURL.ActiveFrom = &activeFrom.Time
+	}
+	if !activeUntil.IsZero() {
+		shortURL.ActiveUntil = &activeUntil.Time
+	}
+	if !deletedAt.IsZero() {
+		shortURL.DeletedAt = &deletedAt.Time
+	}
+	shortURL.Metadata = urlModels.CloneMetadata(metadata)
+	return &shortURL, nil
+}
+
+func isUniqueViolation(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") ||
+		strings.Contains(msg, "duplicate key value violates unique constraint") ||
+		strings.Contains(msg, "SQLSTATE 23505")
+}
+
+func (r *sqlURLRepository) Create(ctx context.Context, shortURL *urlModels.ShortURL) error {
+	log.Debug().Str("short_code", shortURL.ShortCode).Str("url", shortURL.URL).Msg("Creating new short URL")
+
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	metadata, err := sqlMetadataValue(shortURL.Metadata)
+	if err != nil {
+		return serviceErrors.NewInternalError("repository.Create", "failed to encode metadata", err)
+	}
+
+	now := time.Now().UTC()
+	query := r.rebind(`INSERT INTO short_urls (url, short_code, access_count, redirect_type, created, updated, expires_at, max_clicks, fallback_url, password_hash, active_from, active_until, disabled, disabled_reason, disabled_legal, deleted_at, metadata)
+		VALUES (?, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING CAST(id AS TEXT)`)
+
+	var id string
+	err = r.db.QueryRowContext(ctx, query, shortURL.URL, shortURL.ShortCode, shortURL.RedirectType, now, now,
+		sqlNullTime(shortURL.ExpiresAt), shortURL.MaxClicks, shortURL.FallbackURL, shortURL.PasswordHash,
+		sqlNullTime(shortURL.ActiveFrom), sqlNullTime(shortURL.ActiveUntil),
+		shortURL.Disabled, shortURL.DisabledReason, shortURL.DisabledLegal, sqlNullTime(shortURL.DeletedAt), metadata).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return serviceErrors.NewDuplicateError("repository.Create", "short code already exists")
+		}
+		log.Error().Err(err).Msg("Failed to create short URL")
+		return serviceErrors.NewInternalError("repository.Create", "failed to insert record", err)
+	}
+
+	shortURL.ID = id
+	shortURL.AccessCount = 0
+	shortURL.Created = now
+	shortURL.Updated = now
+
+	log.Info().Str("short_code", shortURL.ShortCode).Str("id", id).Msg("Short URL record created successfully")
+	return nil
+}
+
+func (r *sqlURLRepository) GetByShortCode(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	log.Debug().Str("short_code", shortCode).Msg("Looking up short URL by code")
+
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	query := r.rebind("SELECT " + shortURLColumns + " FROM short_urls WHERE short_code = ?")
+	shortURL, err := scanShortURL(r.db.QueryRowContext(ctx, query, shortCode))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, serviceErrors.NewNotFoundError("repository.GetByShortCode", "short URL not found")
+		}
+		log.Error().Err(err).Str("short_code", shortCode).Msg("Failed to lookup short URL")
+		return nil, serviceErrors.NewInternalError("repository.GetByShortCode", "failed to lookup record", err)
+	}
+
+	return shortURL, nil
+}
+
+func (r *sqlURLRepository) Update(ctx context.Context, shortCode string, update *urlModels.ShortURLUpdate) (*urlModels.ShortURL, error) {
+	log.Debug().Str("short_code", shortCode).Msg("Updating short URL")
+
+	sets := []string{"updated = ?"}
+	args := []any{time.Now().UTC()}
+	if update.URL != nil {
+		sets = append(sets, "url = ?")
+		args = append(args, *update.URL)
+	}
+	if update.RedirectType != nil {
+		sets = append(sets, "redirect_type = ?")
+		args = append(args, *update.RedirectType)
+	}
+	if update.ExpiresAt != nil || update.ClearExpiresAt {
+		expiresAt := update.ExpiresAt
+		if update.ClearExpiresAt {
+			expiresAt = nil
+		}
+		sets = append(sets, "expires_at = ?")
+		args = append(args, sqlNullTime(expiresAt))
+	}
+	if update.MaxClicks != nil {
+		sets = append(sets, "max_clicks = ?")
+		args = append(args, *update.MaxClicks)
+	}
+	if update.FallbackURL != nil {
+		sets = append(sets, "fallback_url = ?")
+		args = append(args, *update.FallbackURL)
+	}
+	if update.PasswordHash != nil {
+		sets = append(sets, "password_hash = ?")
+		args = append(args, *update.PasswordHash)
+	}
+	if update.ActiveFrom != nil || update.ClearActiveFrom {
+		activeFrom := update.ActiveFrom
+		if update.ClearActiveFrom {
+			activeFrom = nil
+		}
+		sets = append(sets, "active_from = ?")
+		args = append(args, sqlNullTime(activeFrom))
+	}
+	if update.ActiveUntil != nil || update.ClearActiveUntil {
+		activeUntil := update.ActiveUntil
+		if update.ClearActiveUntil {
+			activeUntil = nil
+		}
+		sets = append(sets, "active_until = ?")
+		args = append(args, sqlNullTime(activeUntil))
+	}
+	if update.Disabled != nil {
+		sets = append(sets, "disabled = ?")
+		args = append(args, *update.Disabled)
+	}
+	if update.DisabledReason != nil {
+		sets = append(sets, "disabled_reason = ?")
+		args = append(args, *update.DisabledReason)
+	}
+	if update.DisabledLegal != nil {
+		sets = append(sets, "disabled_legal = ?")
+		args = append(args, *update.DisabledLegal)
+	}
+	if update.DeletedAt != nil || update.ClearDeletedAt {
+		deletedAt := update.DeletedAt
+		if update.ClearDeletedAt {
+			deletedAt = nil
+		}
+		sets = append(sets, "deleted_at = ?")
+		args = append(args, sqlNullTime(deletedAt))
+	}
+	if update.Metadata != nil {
+		metadata, err := sqlMetadataValue(update.Metadata)
+		if err != nil {
+			return nil, serviceErrors.NewInternalError("repository.Update", "failed to encode metadata", err)
+		}
+		sets = append(sets, "metadata = ?")
+		args = append(args, metadata)
+	}
+	args = append(args, shortCode)
+
+	updateCtx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	query := r.rebind("UPDATE short_urls SET " + strings.Join(sets, ", ") + " WHERE short_code = ?")
+	if err := r.execAffectingOne(updateCtx, "repository.Update", query, args...); err != nil {
+		return nil, err
+	}
+
+	updatedURL, err := r.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("short_code", shortCode).Str("url", updatedURL.URL).Msg("Short URL updated successfully")
+	return updatedURL, nil
+}
+
+func (r *sqlURLRepository) Delete(ctx context.Context, shortCode string) error {
+	log.Debug().Str("short_code", shortCode).Msg("Deleting short URL")
+
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	query := r.rebind("DELETE FROM short_urls WHERE short_code = ?")
+	if err := r.execAffectingOne(ctx, "repository.Delete", query, shortCode); err != nil {
+		return err
+	}
+
+	log.Info().Str("short_code", shortCode).Msg("Short URL deleted successfully")
+	return nil
+}
+
+func (r *sqlURLRepository) IncrementAccessCount(ctx context.Context, shortCode string, delta int64) error {
+	log.Debug().Str("short_code", shortCode).Int64("delta", delta).Msg("Incrementing access count")
+
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	query := r.rebind("UPDATE short_urls SET access_count = access_count + ? WHERE short_code = ?")
+	return r.execAffectingOne(ctx, "repository.IncrementAccessCount", query, delta, shortCode)
+}
+
+// ConsumeClick checks the limit in the UPDATE itself, so the database
+// serialises concurrent consumers.
+func (r *sqlURLRepository) ConsumeClick(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	log.Debug().Str("short_code", shortCode).Msg("Consuming click")
+
+	consumeCtx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	query := r.rebind(`UPDATE short_urls SET access_count = access_count + 1
+		WHERE short_code = ? AND (max_clicks = 0 OR access_count < max_clicks)
+		RETURNING ` + shortURLColumns)
+	shortURL, err := scanShortURL(r.db.QueryRowContext(consumeCtx, query, shortCode))
+	if err == nil {
+		return shortURL, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Error().Err(err).Str("short_code", shortCode).Msg("Failed to consume click")
+		return nil, serviceErrors.NewInternalError("repository.ConsumeClick", "failed to update record", err)
+	}
+
+	// Nothing matched: the code is unknown or the limit is used up.
+	exists, err := r.ExistsByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, serviceErrors.NewNotFoundError("repository.ConsumeClick", "short URL not found")
+	}
+	return nil, serviceErrors.NewGoneError("repository.ConsumeClick", "click limit reached")
+}
+
+func (r *sqlURLRepository) ExistsByShortCode(ctx context.Context, shortCode string) (bool, error) {
+	log.Debug().Str("short_code", shortCode).Msg("Checking if short code exists")
+
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	var exists bool
+	query := r.rebind("SELECT EXISTS (SELECT 1 FROM short_urls WHERE short_code = ?)")
+	if err := r.db.QueryRowContext(ctx, query, shortCode).Scan(&exists); err != nil {
+		return false, serviceErrors.NewInternalError("repository.ExistsByShortCode", "failed to check record", err)
+	}
+	return exists, nil
+}
+
+// sqlSortColumns whitelists the columns listings may be ordered by.
+var sqlSortColumns = map[urlModels.SortField]string{
+	urlModels.SortByCreated:     "created",
+	urlModels.SortByUpdated:     "updated",
+	urlModels.SortByAccessCount: "access_count",
+	urlModels.SortByShortCode:   "short_code",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *sqlURLRepository) List(ctx context.Context, opts *urlModels.ListOptions) (*urlModels.ListResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	var conds []string
+	var args []any
+	if !opts.Filter.CreatedAfter.IsZero() {
+		conds = append(conds, "created >= ?")
+		args = append(args, opts.Filter.CreatedAfter.UTC())
+	}
+	if !opts.Filter.CreatedBefore.IsZero() {
+		conds = append(conds, "created < ?")
+		args = append(args, opts.Filter.CreatedBefore.UTC())
+	}
+	if opts.Filter.URLContains != "" {
+		conds = append(conds, "url "+r.ilike+` ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(opts.Filter.URLContains)+"%")
+	}
+	if opts.Filter.MinAccessCount > 0 {
+		conds = append(conds, "access_count >= ?")
+		args = append(args, opts.Filter.MinAccessCount)
+	}
+	if !opts.Filter.ExpiredBefore.IsZero() {
+		conds = append(conds, "expires_at IS NOT NULL AND expires_at < ?")
+		args = append(args, opts.Filter.ExpiredBefore.UTC())
+	}
+	if opts.Filter.Trashed {
+		conds = append(conds, "deleted_at IS NOT NULL")
+	} else {
+		conds = append(conds, "deleted_at IS NULL")
+	}
+	if !opts.Filter.DeletedBefore.IsZero() {
+		conds = append(conds, "deleted_at < ?")
+		args = append(args, opts.Filter.DeletedBefore.UTC())
+	}
+
+	result := &urlModels.ListResult{}
+	countQuery := "SELECT COUNT(*) FROM short_urls" + sqlWhere(conds)
+	if err := r.db.QueryRowContext(ctx, r.rebind(countQuery), args...).Scan(&result.TotalItems); err != nil {
+		log.Error().Err(err).Msg("Failed to count short URLs")
+		return nil, serviceErrors.NewInternalError("repository.List", "failed to count records", err)
+	}
+
+	column, ok := sqlSortColumns[opts.SortBy]
+	if !ok {
+		column = "created"
+	}
+	cmp, dir := ">", "ASC"
+	if opts.Desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	order := column + " " + dir
+	if column != "short_code" {
+		order += ", short_code " + dir
+	}
+
+	offset := 0
+	if after := opts.After; after != nil {
+		if column == "short_code" {
+			conds = append(conds, "short_code "+cmp+" ?")
+			args = append(args, after.ShortCode)
+		} else {
+			var value any
+			switch column {
+			case "created":
+				value = after.Created.UTC()
+			case "updated":
+				value = after.Updated.UTC()
+			default:
+				value = after.AccessCount
+			}
+			conds = append(conds, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND short_code %[2]s ?))", column, cmp))
+			args = append(args, value, value, after.ShortCode)
+		}
+	} else if opts.Page > 1 {
+		offset = (opts.Page - 1) * opts.PerPage
+	}
+
+	query := "SELECT " + shortURLColumns + " FROM short_urls" + sqlWhere(conds) + " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	rows, err := r.db.QueryContext(ctx, r.rebind(query), append(args, opts.PerPage, offset)...)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list short URLs")
+		return nil, serviceErrors.NewInternalError("repository.List", "failed to list records", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		shortURL, err := scanShortURL(rows)
+		if err != nil {
+			return nil, serviceErrors.NewInternalError("repository.List", "failed to scan record", err)
+		}
+		result.Items = append(result.Items, shortURL)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, serviceErrors.NewInternalError("repository.List", "failed to list records", err)
+	}
+	return result, nil
+}
+
+func sqlWhere(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+func (r *sqlURLRepository) execAffectingOne(ctx context.Context, op, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error().Err(err).Str("op", op).Msg("Failed to execute statement")
+		return serviceErrors.NewInternalError(op, "failed to execute statement", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return serviceErrors.NewInternalError(op, "failed to read affected rows", err)
+	}
+	if affected == 0 {
+		return serviceErrors.NewNotFoundError(op, "short URL not found")
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package repository
+
+import "testing"
+
+func TestRebindDollar(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM t WHERE a = ?", "SELECT * FROM t WHERE a = $1"},
+		{"UPDATE t SET a = ?, b = ? WHERE c = ?", "UPDATE t SET a = $1, b = $2 WHERE c = $3"},
+	}
+
+	for _, tt := range tests {
+		if got := rebindDollar(tt.query); got != tt.want {
+			t.Errorf("rebindDollar(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rowjay/url-shortening-service/internal/database"
+)
+
+func TestSQLiteURLRepository(t *testing.T) {
+	runURLRepositoryConformance(t, func(t *testing.T) URLRepository {
+		db, err := database.OpenSQL(constants.StorageDriverSQLite, filepath.Join(t.TempDir(), "shortener.db"), true)
+		if err != nil {
+			t.Fatalf("OpenSQL() error = %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return NewSQLiteURLRepository(db)
+	})
+}
+
+func TestSQLiteHistoryRepository(t *testing.T) {
+	runHistoryRepositoryConformance(t, func(t *testing.T) HistoryRepository {
+		db, err := database.OpenSQL(constants.StorageDriverSQLite, filepath.Join(t.TempDir(), "shortener.db"), true)
+		if err != nil {
+			t.Fatalf("OpenSQL() error = %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return NewSQLiteHistoryRepository(db)
+	})
+}
+
+func TestSQLiteKeyRepository(t *testing.T) {
+	runKeyRepositoryConformance(t, func(t *testing.T) (URLRepository, KeyRepository) {
+		db, err := database.OpenSQL(constants.StorageDriverSQLite, filepath.Join(t.TempDir(), "shortener.db"), true)
+		if err != nil {
+			t.Fatalf("OpenSQL() error = %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return NewSQLiteURLRepository(db), NewSQLiteKeyRepository(db)
+	})
+}
+
+func TestSQLiteSequenceRepository(t *testing.T) {
+	runSequenceRepositoryConformance(t, func(t *testing.T) SequenceRepository {
+		db, err := database.OpenSQL(constants.StorageDriverSQLite, filepath.Join(t.TempDir(), "shortener.db"), true)
+		if err != nil {
+			t.Fatalf("OpenSQL() error = %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return NewSQLiteSequenceRepository(db)
+	})
+}
@@ -12,7 +12,6 @@ import (
 	urlModels "github.com/rowjay/url-shortening-service/internal/models"
 	"github.com/rs/zerolog/log"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 )
@@ -20,40 +19,140 @@ import (
 type URLRepository interface {
 	Create(ctx context.Context, shortURL *urlModels.ShortURL) error
 	GetByShortCode(ctx context.Context, shortCode string) (*urlModels.ShortURL, error)
-	Update(ctx context.Context, shortCode string, url string) (*urlModels.ShortURL, error)
+	Update(ctx context.Context, shortCode string, update *urlModels.ShortURLUpdate) (*urlModels.ShortURL, error)
 	Delete(ctx context.Context, shortCode string) error
-	IncrementAccessCount(ctx context.Context, shortCode string) error
+	IncrementAccessCount(ctx context.Context, shortCode string, delta int64) error
 	ExistsByShortCode(ctx context.Context, shortCode string) (bool, error)
+	List(ctx context.Context, opts *urlModels.ListOptions) (*urlModels.ListResult, error)
+	// ConsumeClick counts one access to a click-limited link and returns
+	// the updated record. It fails with a gone error, without counting, once
+	// the limit is reached, and is atomic across concurrent callers.
+	ConsumeClick(ctx context.Context, shortCode string) (*urlModels.ShortURL, error)
 }
 
 type pocketBaseRecord struct {
-	ID          string `json:"id"`
-	Created     string `json:"created"`
-	Updated     string `json:"updated"`
-	URL         string `json:"url"`
-	ShortCode   string `json:"short_code"`
-	AccessCount int64  `json:"access_count"`
+	ID             string `json:"id"`
+	Created        string `json:"created"`
+	Updated        string `json:"updated"`
+	URL            string `json:"url"`
+	ShortCode      string `json:"short_code"`
+	AccessCount    int64  `json:"access_count"`
+	RedirectType   int    `json:"redirect_type"`
+	ExpiresAt      string `json:"expires_at"`
+	MaxClicks      int64  `json:"max_clicks"`
+	FallbackURL    string `json:"fallback_url"`
+	PasswordHash   string `json:"password_hash"`
+	ActiveFrom     string `json:"active_from"`
+	ActiveUntil    string `json:"active_until"`
+	Disabled       bool   `json:"disabled"`
+	DisabledReason string `json:"disabled_reason"`
+	DisabledLegal  bool   `json:"disabled_legal"`
+	DeletedAt      string `json:"deleted_at"`
+	// Metadata is null or {} for links without any.
+	Metadata map[string]string `json:"metadata"`
+}
+
+func (rec *pocketBaseRecord) toModel() *urlModels.ShortURL {
+	shortURL := &urlModels.ShortURL{
+		ID:             rec.ID,
+		URL:            rec.URL,
+		ShortCode:      rec.ShortCode,
+		AccessCount:    rec.AccessCount,
+		RedirectType:   rec.RedirectType,
+		MaxClicks:      rec.MaxClicks,
+		FallbackURL:    rec.FallbackURL,
+		PasswordHash:   rec.PasswordHash,
+		Disabled:       rec.Disabled,
+		DisabledReason: rec.DisabledReason,
+		DisabledLegal:  rec.DisabledLegal,
+		Metadata:       urlModels.CloneMetadata(rec.Metadata),
+		Created:        parsePBTime(rec.Created),
+		Updated:        parsePBTime(rec.Updated),
+	}
+	if expiresAt := parsePBTime(rec.ExpiresAt); !expiresAt.IsZero() {
+		shortURL.ExpiresAt = &expiresAt
+	}
+	if activeFrom := parsePBTime(rec.ActiveFrom); !activeFrom.IsZero() {
+		shortURL.ActiveFrom = &activeFrom
+	}
+	if activeUntil := parsePBTime(rec.ActiveUntil); !activeUntil.IsZero() {
+		shortURL.ActiveUntil = &activeUntil
+	}
+	if deletedAt := parsePBTime(rec.DeletedAt); !deletedAt.IsZero() {
+		shortURL.DeletedAt = &deletedAt
+	}
+	return shortURL
 }
 
 type pocketBaseListResponse struct {
-	Items []pocketBaseRecord `json:"items"`
+	Items      []pocketBaseRecord `json:"items"`
+	TotalItems int                `json:"totalItems"`
 }
 
 type pocketBaseCreateRequest struct {
-	URL         string `json:"url"`
-	ShortCode   string `json:"short_code"`
-	AccessCount int64  `json:"access_count"`
+	URL            string `json:"url"`
+	ShortCode      string `json:"short_code"`
+	AccessCount    int64  `json:"access_count"`
+	RedirectType   int    `json:"redirect_type,omitempty"`
+	ExpiresAt      string `json:"expires_at,omitempty"`
+	MaxClicks      int64  `json:"max_clicks,omitempty"`
+	FallbackURL    string `json:"fallback_url,omitempty"`
+	PasswordHash   string `json:"password_hash,omitempty"`
+	ActiveFrom     string `json:"active_from,omitempty"`
+	ActiveUntil    string `json:"active_until,omitempty"`
+	Disabled       bool   `json:"disabled,omitempty"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+	DisabledLegal  bool   `json:"disabled_legal,omitempty"`
+	DeletedAt      string `json:"deleted_at,omitempty"`
+	// Metadata is omitted for links without any.
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
+// pocketBaseUpdateRequest uses PocketBase's "field+" modifier for the access
+// count so the increment is applied atomically on the server.
 type pocketBaseUpdateRequest struct {
-	AccessCount *int64  `json:"access_count,omitempty"`
-	URL         *string `json:"url,omitempty"`
+	AccessCountDelta *int64  `json:"access_count+,omitempty"`
+	URL              *string `json:"url,omitempty"`
+	RedirectType     *int    `json:"redirect_type,omitempty"`
+	// ExpiresAt is sent as "" to clear the expiry.
+	ExpiresAt   *string `json:"expires_at,omitempty"`
+	MaxClicks   *int64  `json:"max_clicks,omitempty"`
+	FallbackURL *string `json:"fallback_url,omitempty"`
+	// PasswordHash is sent as "" to remove the password.
+	PasswordHash *string `json:"password_hash,omitempty"`
+	// ActiveFrom and ActiveUntil are sent as "" to open that side of the
+	// window.
+	ActiveFrom     *string `json:"active_from,omitempty"`
+	ActiveUntil    *string `json:"active_until,omitempty"`
+	Disabled       *bool   `json:"disabled,omitempty"`
+	DisabledReason *string `json:"disabled_reason,omitempty"`
+	DisabledLegal  *bool   `json:"disabled_legal,omitempty"`
+	// DeletedAt is sent as "" to restore the record from the trash.
+	DeletedAt *string `json:"deleted_at,omitempty"`
+	// Metadata is sent as {} to remove it.
+	Metadata *map[string]string `json:"metadata,omitempty"`
 }
 
 type urlRepositoryImpl struct {
 	pb *database.PBClient
 }
 
+// A click taken back in ConsumeClick is attempted this often, waiting a
+// growing multiple of pbReturnClickBackoff in between.
+const (
+	pbReturnClickAttempts = 4
+	pbReturnClickBackoff  = 100 * time.Millisecond
+)
+
+// formatPBTime renders an optional timestamp as a PocketBase date value;
+// nil becomes the empty date.
+func formatPBTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(pbTimeLayout)
+}
+
 func parsePBTime(pbTime string) time.Time {
 	if pbTime == "" {
 		return time.Time{}
@@ -77,9 +176,21 @@ func (r *urlRepositoryImpl) Create(ctx context.Context, shortURL *urlModels.Shor
 	log.Debug().Str("short_code", shortURL.ShortCode).Str("url", shortURL.URL).Msg("Creating new short URL")
 
 	reqBody := pocketBaseCreateRequest{
-		URL:         shortURL.URL,
-		ShortCode:   shortURL.ShortCode,
-		AccessCount: 0,
+		URL:            shortURL.URL,
+		ShortCode:      shortURL.ShortCode,
+		AccessCount:    0,
+		RedirectType:   shortURL.RedirectType,
+		ExpiresAt:      formatPBTime(shortURL.ExpiresAt),
+		MaxClicks:      shortURL.MaxClicks,
+		FallbackURL:    shortURL.FallbackURL,
+		PasswordHash:   shortURL.PasswordHash,
+		ActiveFrom:     formatPBTime(shortURL.ActiveFrom),
+		ActiveUntil:    formatPBTime(shortURL.ActiveUntil),
+		Disabled:       shortURL.Disabled,
+		DisabledReason: shortURL.DisabledReason,
+		DisabledLegal:  shortURL.DisabledLegal,
+		DeletedAt:      formatPBTime(shortURL.DeletedAt),
+		Metadata:       shortURL.Metadata,
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
@@ -102,16 +213,13 @@ func (r *urlRepositoryImpl) Create(ctx context.Context, shortURL *urlModels.Shor
 	resp, err := r.pb.HTTPClient.Do(req)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create short URL")
-		return serviceErrors.NewInternalError("repository.Create", "failed to create record", err)
+		return pocketBaseTransportError("repository.Create", "failed to create record", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		log.Error().Int("status", resp.StatusCode).Msg("PocketBase returned error status")
-		if resp.StatusCode == http.StatusConflict {
-			return serviceErrors.NewDuplicateError("repository.Create", "short code already exists")
-		}
-		return serviceErrors.NewInternalError("repository.Create", "PocketBase error", fmt.Errorf("status %d", resp.StatusCode))
+		return pocketBaseError("repository.Create", constants.ShortURLsCollection, pbCollectionEndpoint, resp)
 	}
 
 	var pbResp pocketBaseRecord
@@ -134,31 +242,10 @@ func (r *urlRepositoryImpl) GetByShortCode(ctx context.Context, shortCode string
 	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
 	defer cancel()
 
-	filter := fmt.Sprintf("(short_code=\"%s\")", shortCode)
-	reqURL := fmt.Sprintf("%s/api/collections/%s/records?filter=%s",
-		r.pb.BaseURL, constants.ShortURLsCollection, url.QueryEscape(filter))
-
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	query := pbQuery{Filter: pbEq("short_code", shortCode), PerPage: 1, SkipTotal: true}
+	pbResp, err := r.listRecords(ctx, "repository.GetByShortCode", query)
 	if err != nil {
-		return nil, serviceErrors.NewInternalError("repository.GetByShortCode", "failed to create request", err)
-	}
-
-	resp, err := r.pb.HTTPClient.Do(req)
-	if err != nil {
-		log.Error().Err(err).Str("short_code", shortCode).Msg("Failed to lookup short URL")
-		return nil, serviceErrors.NewInternalError("repository.GetByShortCode", "failed to lookup record", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Debug().Int("status", resp.StatusCode).Str("short_code", shortCode).Msg("Short URL not found")
-		return nil, serviceErrors.NewNotFoundError("repository.GetByShortCode", "short URL not found")
-	}
-
-	var pbResp pocketBaseListResponse
-	if err := json.NewDecoder(resp.Body).Decode(&pbResp); err != nil {
-		log.Error().Err(err).Msg("Failed to decode response")
-		return nil, serviceErrors.NewInternalError("repository.GetByShortCode", "failed to decode response", err)
+		return nil, err
 	}
 
 	if len(pbResp.Items) == 0 {
@@ -167,75 +254,199 @@ func (r *urlRepositoryImpl) GetByShortCode(ctx context.Context, shortCode string
 	}
 
 	record := pbResp.Items[0]
-	shortURL := &urlModels.ShortURL{
-		ID:          record.ID,
-		URL:         record.URL,
-		ShortCode:   record.ShortCode,
-		AccessCount: record.AccessCount,
-		Created:     parsePBTime(record.Created),
-		Updated:     parsePBTime(record.Updated),
-	}
+	shortURL := record.toModel()
 
 	log.Debug().Str("short_code", shortCode).Str("url", record.URL).Msg("Short URL found")
 	return shortURL, nil
 }
 
-func (r *urlRepositoryImpl) Update(ctx context.Context, shortCode string, newURL string) (*urlModels.ShortURL, error) {
-	log.Debug().Str("short_code", shortCode).Str("new_url", newURL).Msg("Updating short URL")
+func (r *urlRepositoryImpl) List(ctx context.Context, opts *urlModels.ListOptions) (*urlModels.ListResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
+	defer cancel()
+
+	var filters []pbFilter
+	if !opts.Filter.CreatedAfter.IsZero() {
+		filters = append(filters, pbGreaterOrEqual("created", opts.Filter.CreatedAfter))
+	}
+	if !opts.Filter.CreatedBefore.IsZero() {
+		filters = append(filters, pbLess("created", opts.Filter.CreatedBefore))
+	}
+	if opts.Filter.URLContains != "" {
+		filters = append(filters, pbContains("url", opts.Filter.URLContains))
+	}
+	if opts.Filter.MinAccessCount > 0 {
+		filters = append(filters, pbGreaterOrEqual("access_count", opts.Filter.MinAccessCount))
+	}
+	if !opts.Filter.ExpiredBefore.IsZero() {
+		filters = append(filters, pbCompare("expires_at", "!=", ""), pbLess("expires_at", opts.Filter.ExpiredBefore))
+	}
+	if opts.Filter.Trashed {
+		filters = append(filters, pbCompare("deleted_at", "!=", ""))
+	} else {
+		filters = append(filters, pbCompare("deleted_at", "=", ""))
+	}
+	if !opts.Filter.DeletedBefore.IsZero() {
+		filters = append(filters, pbLess("deleted_at", opts.Filter.DeletedBefore))
+	}
 
-	shortURL, err := r.GetByShortCode(ctx, shortCode)
+	field := string(opts.SortBy)
+	if field == "" {
+		field = string(urlModels.SortByCreated)
+	}
+	sort := []pbSort{{Field: field, Desc: opts.Desc}}
+	if opts.SortBy != urlModels.SortByShortCode {
+		sort = append(sort, pbSort{Field: "short_code", Desc: opts.Desc})
+	}
+
+	query := pbQuery{Filter: pbAnd(filters...), Sort: sort, Page: opts.Page, PerPage: opts.PerPage}
+	if opts.After != nil {
+		query.Page = 1
+		query.SkipTotal = true
+		query.Filter = pbAnd(query.Filter, pbKeyset(opts, field))
+	}
+
+	pbResp, err := r.listRecords(ctx, "repository.List", query)
 	if err != nil {
 		return nil, err
 	}
-	if shortURL == nil {
-		return nil, serviceErrors.NewNotFoundError("repository.Update", "record not found")
+
+	result := &urlModels.ListResult{TotalItems: pbResp.TotalItems, Items: make([]*urlModels.ShortURL, len(pbResp.Items))}
+	for i := range pbResp.Items {
+		result.Items[i] = pbResp.Items[i].toModel()
 	}
 
-	reqBody := pocketBaseUpdateRequest{
-		URL: &newURL,
+	// The keyset condition narrows totalItems, so count the whole filter
+	// separately.
+	if opts.After != nil {
+		total, err := r.listRecords(ctx, "repository.List", pbQuery{Filter: pbAnd(filters...), PerPage: 1})
+		if err != nil {
+			return nil, err
+		}
+		result.TotalItems = total.TotalItems
 	}
+	return result, nil
+}
 
-	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
-	defer cancel()
+// pbKeyset selects the records after opts.After in the listing order.
+func pbKeyset(opts *urlModels.ListOptions, field string) pbFilter {
+	after := opts.After
+	compare := pbGreater
+	if opts.Desc {
+		compare = pbLess
+	}
+
+	var value any
+	switch opts.SortBy {
+	case urlModels.SortByShortCode:
+		return compare("short_code", after.ShortCode)
+	case urlModels.SortByUpdated:
+		value = after.Updated
+	case urlModels.SortByAccessCount:
+		value = after.AccessCount
+	default:
+		value = after.Created
+	}
+	return pbOr(compare(field, value), pbAnd(pbEq(field, value), compare("short_code", after.ShortCode)))
+}
 
-	jsonBody, err := json.Marshal(reqBody)
+func (r *urlRepositoryImpl) listRecords(ctx context.Context, op string, query pbQuery) (*pocketBaseListResponse, error) {
+	encoded, err := query.encode()
 	if err != nil {
-		return nil, serviceErrors.NewInternalError("repository.Update", "failed to marshal request", err)
+		return nil, serviceErrors.NewValidationError(op, "invalid filter value", err)
 	}
+	reqURL := fmt.Sprintf("%s/api/collections/%s/records?%s",
+		r.pb.BaseURL, constants.ShortURLsCollection, encoded)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPatch,
-		fmt.Sprintf("%s/api/collections/%s/records/%s", r.pb.BaseURL, constants.ShortURLsCollection, shortURL.ID),
-		bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
-		return nil, serviceErrors.NewInternalError("repository.Update", "failed to create request", err)
+		return nil, serviceErrors.NewInternalError(op, "failed to create request", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := r.pb.HTTPClient.Do(req)
 	if err != nil {
-		return nil, serviceErrors.NewInternalError("repository.Update", "failed to update record", err)
+		log.Error().Err(err).Str("op", op).Msg("Failed to list short URLs")
+		return nil, pocketBaseTransportError(op, "failed to query records", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, serviceErrors.NewInternalError("repository.Update", "PocketBase error", fmt.Errorf("status %d", resp.StatusCode))
+		log.Error().Int("status", resp.StatusCode).Str("op", op).Msg("PocketBase returned error status")
+		return nil, pocketBaseError(op, constants.ShortURLsCollection, pbCollectionEndpoint, resp)
 	}
 
-	var pbResp pocketBaseRecord
+	var pbResp pocketBaseListResponse
 	if err := json.NewDecoder(resp.Body).Decode(&pbResp); err != nil {
-		return nil, serviceErrors.NewInternalError("repository.Update", "failed to decode response", err)
+		log.Error().Err(err).Msg("Failed to decode response")
+		return nil, serviceErrors.NewInternalError(op, "failed to decode response", err)
 	}
+	return &pbResp, nil
+}
+
+func (r *urlRepositoryImpl) Update(ctx context.Context, shortCode string, update *urlModels.ShortURLUpdate) (*urlModels.ShortURL, error) {
+	log.Debug().Str("short_code", shortCode).Msg("Updating short URL")
 
-	updatedURL := &urlModels.ShortURL{
-		ID:          pbResp.ID,
-		URL:         pbResp.URL,
-		ShortCode:   pbResp.ShortCode,
-		AccessCount: pbResp.AccessCount,
-		Created:     parsePBTime(pbResp.Created),
-		Updated:     parsePBTime(pbResp.Updated),
+	shortURL, err := r.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if shortURL == nil {
+		return nil, serviceErrors.NewNotFoundError("repository.Update", "record not found")
 	}
 
-	log.Info().Str("short_code", shortCode).Str("new_url", newURL).Msg("Short URL updated successfully")
+	reqBody := pocketBaseUpdateRequest{
+		URL:            update.URL,
+		RedirectType:   update.RedirectType,
+		MaxClicks:      update.MaxClicks,
+		FallbackURL:    update.FallbackURL,
+		PasswordHash:   update.PasswordHash,
+		Disabled:       update.Disabled,
+		DisabledReason: update.DisabledReason,
+		DisabledLegal:  update.DisabledLegal,
+	}
+	if update.ExpiresAt != nil || update.ClearExpiresAt {
+		expiresAt := update.ExpiresAt
+		if update.ClearExpiresAt {
+			expiresAt = nil
+		}
+		value := formatPBTime(expiresAt)
+		reqBody.ExpiresAt = &value
+	}
+	if update.ActiveFrom != nil || update.ClearActiveFrom {
+		activeFrom := update.ActiveFrom
+		if update.ClearActiveFrom {
+			activeFrom = nil
+		}
+		value := formatPBTime(activeFrom)
+		reqBody.ActiveFrom = &value
+	}
+	if update.ActiveUntil != nil || update.ClearActiveUntil {
+		activeUntil := update.ActiveUntil
+		if update.ClearActiveUntil {
+			activeUntil = nil
+		}
+		value := formatPBTime(activeUntil)
+		reqBody.ActiveUntil = &value
+	}
+	if update.DeletedAt != nil || update.ClearDeletedAt {
+		deletedAt := update.DeletedAt
+		if update.ClearDeletedAt {
+			deletedAt = nil
+		}
+		value := formatPBTime(deletedAt)
+		reqBody.DeletedAt = &value
+	}
+	if update.Metadata != nil {
+		reqBody.Metadata = &update.Metadata
+	}
+
+	pbResp, err := r.patchRecord(ctx, "repository.Update", shortURL.ID, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedURL := pbResp.toModel()
+
+	log.Info().Str("short_code", shortCode).Str("url", updatedURL.URL).Msg("Short URL updated successfully")
 	return updatedURL, nil
 }
 
@@ -262,20 +473,20 @@ func (r *urlRepositoryImpl) Delete(ctx context.Context, shortCode string) error
 
 	resp, err := r.pb.HTTPClient.Do(req)
 	if err != nil {
-		return serviceErrors.NewInternalError("repository.Delete", "failed to delete record", err)
+		return pocketBaseTransportError("repository.Delete", "failed to delete record", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		return serviceErrors.NewInternalError("repository.Delete", "PocketBase error", fmt.Errorf("status %d", resp.StatusCode))
+		return pocketBaseError("repository.Delete", constants.ShortURLsCollection, pbRecordEndpoint, resp)
 	}
 
 	log.Info().Str("short_code", shortCode).Msg("Short URL deleted successfully")
 	return nil
 }
 
-func (r *urlRepositoryImpl) IncrementAccessCount(ctx context.Context, shortCode string) error {
-	log.Debug().Str("short_code", shortCode).Msg("Incrementing access count")
+func (r *urlRepositoryImpl) IncrementAccessCount(ctx context.Context, shortCode string, delta int64) error {
+	log.Debug().Str("short_code", shortCode).Int64("delta", delta).Msg("Incrementing access count")
 
 	shortURL, err := r.GetByShortCode(ctx, shortCode)
 	if err != nil {
@@ -285,39 +496,101 @@ func (r *urlRepositoryImpl) IncrementAccessCount(ctx context.Context, shortCode
 		return serviceErrors.NewNotFoundError("repository.IncrementAccessCount", "record not found")
 	}
 
-	newCount := shortURL.AccessCount + 1
-	reqBody := pocketBaseUpdateRequest{
-		AccessCount: &newCount,
+	if _, err := r.patchRecord(ctx, "repository.IncrementAccessCount", shortURL.ID, pocketBaseUpdateRequest{AccessCountDelta: &delta}); err != nil {
+		return err
+	}
+
+	log.Debug().Str("short_code", shortCode).Int64("delta", delta).Msg("Access count incremented")
+	return nil
+}
+
+// ConsumeClick increments first and checks the returned count, since
+// PocketBase cannot make the update conditional. The "field+" modifier runs
+// in a transaction, so each caller sees its own post-increment value and
+// exactly MaxClicks callers see one within the limit. Callers over the limit
+// take their increment back.
+func (r *urlRepositoryImpl) ConsumeClick(ctx context.Context, shortCode string) (*urlModels.ShortURL, error) {
+	log.Debug().Str("short_code", shortCode).Msg("Consuming click")
+
+	shortURL, err := r.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if shortURL.ClickLimited() && shortURL.AccessCount >= shortURL.MaxClicks {
+		return nil, serviceErrors.NewGoneError("repository.ConsumeClick", "click limit reached")
 	}
 
+	one := int64(1)
+	pbResp, err := r.patchRecord(ctx, "repository.ConsumeClick", shortURL.ID, pocketBaseUpdateRequest{AccessCountDelta: &one})
+	if err != nil {
+		return nil, err
+	}
+	consumed := pbResp.toModel()
+	if !consumed.ClickLimited() || consumed.AccessCount <= consumed.MaxClicks {
+		return consumed, nil
+	}
+
+	if err := r.returnClick(ctx, shortURL.ID); err != nil {
+		log.Error().Err(err).Str("short_code", shortCode).Msg("Failed to return click over the limit")
+		return nil, err
+	}
+	return nil, serviceErrors.NewGoneError("repository.ConsumeClick", "click limit reached")
+}
+
+// returnClick takes back a click that went over the limit. The transport
+// never retries a PATCH, so a failed attempt is retried here; giving up
+// early would leave access_count above max_clicks for good. It carries on
+// when the caller's context is cancelled for the same reason.
+func (r *urlRepositoryImpl) returnClick(ctx context.Context, id string) error {
+	ctx = context.WithoutCancel(ctx)
+	minusOne := int64(-1)
+
+	var err error
+	for attempt := 0; attempt < pbReturnClickAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * pbReturnClickBackoff)
+		}
+		if _, err = r.patchRecord(ctx, "repository.ConsumeClick", id, pocketBaseUpdateRequest{AccessCountDelta: &minusOne}); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// patchRecord applies body to the record with the given id and returns the
+// updated record.
+func (r *urlRepositoryImpl) patchRecord(ctx context.Context, op, id string, body pocketBaseUpdateRequest) (*pocketBaseRecord, error) {
 	ctx, cancel := context.WithTimeout(ctx, constants.RequestTimeout)
 	defer cancel()
 
-	jsonBody, err := json.Marshal(reqBody)
+	jsonBody, err := json.Marshal(body)
 	if err != nil {
-		return serviceErrors.NewInternalError("repository.IncrementAccessCount", "failed to marshal request", err)
+		return nil, serviceErrors.NewInternalError(op, "failed to marshal request", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPatch,
-		fmt.Sprintf("%s/api/collections/%s/records/%s", r.pb.BaseURL, constants.ShortURLsCollection, shortURL.ID),
+		fmt.Sprintf("%s/api/collections/%s/records/%s", r.pb.BaseURL, constants.ShortURLsCollection, id),
 		bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return serviceErrors.NewInternalError("repository.IncrementAccessCount", "failed to create request", err)
+		return nil, serviceErrors.NewInternalError(op, "failed to create request", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := r.pb.HTTPClient.Do(req)
 	if err != nil {
-		return serviceErrors.NewInternalError("repository.IncrementAccessCount", "failed to update record", err)
+		return nil, pocketBaseTransportError(op, "failed to update record", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return serviceErrors.NewInternalError("repository.IncrementAccessCount", "PocketBase error", fmt.Errorf("status %d", resp.StatusCode))
+		return nil, pocketBaseError(op, constants.ShortURLsCollection, pbRecordEndpoint, resp)
 	}
 
-	log.Info().Str("short_code", shortCode).Int64("new_count", newCount).Msg("Access count incremented")
-	return nil
+	var pbResp pocketBaseRecord
+	if err := json.NewDecoder(resp.Body).Decode(&pbResp); err != nil {
+		return nil, serviceErrors.NewInternalError(op, "failed to decode response", err)
+	}
+	return &pbResp, nil
 }
 
 func (r *urlRepositoryImpl) ExistsByShortCode(ctx context.Context, shortCode string) (bool, error) {
@@ -0,0 +1,629 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+)
+
+func errorCode(err error) serviceErrors.ErrorCode {
+	var serviceErr *serviceErrors.ServiceError
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Code
+	}
+	return 0
+}
+
+// runURLRepositoryConformance exercises the URLRepository contract against a
+// fresh repository returned by newRepo for every subtest.
+func runURLRepositoryConformance(t *testing.T, newRepo func(t *testing.T) URLRepository) {
+	t.Run("CRUD", func(t *testing.T) { testURLRepositoryCRUD(t, newRepo(t)) })
+	t.Run("ReturnsCopies", func(t *testing.T) { testURLRepositoryReturnsCopies(t, newRepo(t)) })
+	t.Run("ConcurrentCreateDetectsDuplicates", func(t *testing.T) { testURLRepositoryConcurrentCreate(t, newRepo(t)) })
+	t.Run("ConcurrentIncrement", func(t *testing.T) { testURLRepositoryConcurrentIncrement(t, newRepo(t)) })
+	t.Run("List", func(t *testing.T) { testURLRepositoryList(t, newRepo(t)) })
+	t.Run("Expiry", func(t *testing.T) { testURLRepositoryExpiry(t, newRepo(t)) })
+	t.Run("ConsumeClickEnforcesLimit", func(t *testing.T) { testURLRepositoryConsumeClick(t, newRepo(t)) })
+	t.Run("PasswordHash", func(t *testing.T) { testURLRepositoryPasswordHash(t, newRepo(t)) })
+	t.Run("ActiveWindow", func(t *testing.T) { testURLRepositoryActiveWindow(t, newRepo(t)) })
+	t.Run("Disabled", func(t *testing.T) { testURLRepositoryDisabled(t, newRepo(t)) })
+	t.Run("Trash", func(t *testing.T) { testURLRepositoryTrash(t, newRepo(t)) })
+	t.Run("Metadata", func(t *testing.T) { testURLRepositoryMetadata(t, newRepo(t)) })
+}
+
+func testURLRepositoryCRUD(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+
+	shortURL := &urlModels.ShortURL{URL: "https://example.com", ShortCode: "abc123"}
+	if err := repo.Create(ctx, shortURL); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if shortURL.ID == "" || shortURL.Created.IsZero() {
+		t.Errorf("Create() did not populate ID and timestamps: %+v", shortURL)
+	}
+
+	got, err := repo.GetByShortCode(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("GetByShortCode() error = %v", err)
+	}
+	if got.URL != "https://example.com" {
+		t.Errorf("GetByShortCode() URL = %v, want %v", got.URL, "https://example.com")
+	}
+
+	newURL := "https://example.org"
+	updated, err := repo.Update(ctx, "abc123", &urlModels.ShortURLUpdate{URL: &newURL})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.URL != newURL {
+		t.Errorf("Update() URL = %v, want %v", updated.URL, newURL)
+	}
+
+	if err := repo.IncrementAccessCount(ctx, "abc123", 5); err != nil {
+		t.Fatalf("IncrementAccessCount() error = %v", err)
+	}
+	if got, _ := repo.GetByShortCode(ctx, "abc123"); got.AccessCount != 5 {
+		t.Errorf("AccessCount = %d, want 5", got.AccessCount)
+	}
+	if err := repo.IncrementAccessCount(ctx, "missing", 1); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("IncrementAccessCount() unknown code error = %v, want not found", err)
+	}
+
+	if err := repo.Delete(ctx, "abc123"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.GetByShortCode(ctx, "abc123"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("GetByShortCode() after delete error = %v, want not found", err)
+	}
+	if err := repo.Delete(ctx, "abc123"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("Delete() twice error = %v, want not found", err)
+	}
+}
+
+func testURLRepositoryReturnsCopies(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "abc123"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, _ := repo.GetByShortCode(ctx, "abc123")
+	got.URL = "https://mutated.example"
+
+	again, _ := repo.GetByShortCode(ctx, "abc123")
+	if again.URL != "https://example.com" {
+		t.Errorf("stored record was mutated through returned pointer: %v", again.URL)
+	}
+}
+
+func testURLRepositoryConcurrentCreate(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+
+	const workers = 50
+	var created, duplicates atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "same"})
+			switch {
+			case err == nil:
+				created.Add(1)
+			case errorCode(err) == serviceErrors.ErrorCodeDuplicate:
+				duplicates.Add(1)
+			default:
+				t.Errorf("Create() unexpected error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created.Load() != 1 || duplicates.Load() != workers-1 {
+		t.Errorf("created = %d, duplicates = %d, want 1 and %d", created.Load(), duplicates.Load(), workers-1)
+	}
+}
+
+func testURLRepositoryConcurrentIncrement(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "abc123"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	const increments = 1000
+	var wg sync.WaitGroup
+	for i := 0; i < increments; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := repo.IncrementAccessCount(ctx, "abc123", 1); err != nil {
+				t.Errorf("IncrementAccessCount() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, _ := repo.GetByShortCode(ctx, "abc123")
+	if got.AccessCount != increments {
+		t.Errorf("AccessCount = %d, want %d", got.AccessCount, increments)
+	}
+}
+
+func listCodes(result *urlModels.ListResult) []string {
+	codes := make([]string, len(result.Items))
+	for i, item := range result.Items {
+		codes[i] = item.ShortCode
+	}
+	return codes
+}
+
+func testURLRepositoryList(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+
+	seed := []struct {
+		code   string
+		url    string
+		clicks int64
+	}{
+		{"aaa", "https://example.com/Shoes", 5},
+		{"bbb", "https://example.org/50%_off", 0},
+		{"ccc", "https://example.com/hats", 5},
+		{"ddd", "https://example.net/shoes", 9},
+		{"eee", "https://example.com/50x_off", 1},
+	}
+	for _, s := range seed {
+		if err := repo.Create(ctx, &urlModels.ShortURL{URL: s.url, ShortCode: s.code}); err != nil {
+			t.Fatalf("Create(%s) error = %v", s.code, err)
+		}
+		if s.clicks > 0 {
+			if err := repo.IncrementAccessCount(ctx, s.code, s.clicks); err != nil {
+				t.Fatalf("IncrementAccessCount(%s) error = %v", s.code, err)
+			}
+		}
+	}
+	// Write-behind repositories sort and filter on stored counts only.
+	if flusher, ok := repo.(interface{ FlushCounters(context.Context) error }); ok {
+		if err := flusher.FlushCounters(ctx); err != nil {
+			t.Fatalf("FlushCounters() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		opts  urlModels.ListOptions
+		want  []string
+		total int
+	}{
+		{
+			name:  "by short code",
+			opts:  urlModels.ListOptions{SortBy: urlModels.SortByShortCode, PerPage: 10},
+			want:  []string{"aaa", "bbb", "ccc", "ddd", "eee"},
+			total: 5,
+		},
+		{
+			name:  "second page",
+			opts:  urlModels.ListOptions{SortBy: urlModels.SortByShortCode, Page: 2, PerPage: 2},
+			want:  []string{"ccc", "ddd"},
+			total: 5,
+		},
+		{
+			name:  "page past the end",
+			opts:  urlModels.ListOptions{SortBy: urlModels.SortByShortCode, Page: 4, PerPage: 2},
+			want:  []string{},
+			total: 5,
+		},
+		{
+			name:  "access count descending with ties by code",
+			opts:  urlModels.ListOptions{SortBy: urlModels.SortByAccessCount, Desc: true, PerPage: 10},
+			want:  []string{"ddd", "ccc", "aaa", "eee", "bbb"},
+			total: 5,
+		},
+		{
+			name: "cursor within a tie",
+			opts: urlModels.ListOptions{
+				SortBy: urlModels.SortByAccessCount, Desc: true, PerPage: 2,
+				After: &urlModels.ListCursor{ShortCode: "ccc", AccessCount: 5},
+			},
+			want:  []string{"aaa", "eee"},
+			total: 5,
+		},
+		{
+			name: "cursor by code",
+			opts: urlModels.ListOptions{
+				SortBy: urlModels.SortByShortCode, PerPage: 10,
+				After: &urlModels.ListCursor{ShortCode: "bbb"},
+			},
+			want:  []string{"ccc", "ddd", "eee"},
+			total: 5,
+		},
+		{
+			name: "url contains is case-insensitive",
+			opts: urlModels.ListOptions{
+				SortBy: urlModels.SortByShortCode, PerPage: 10,
+				Filter: urlModels.ListFilter{URLContains: "SHOES"},
+			},
+			want:  []string{"aaa", "ddd"},
+			total: 2,
+		},
+		{
+			name: "url contains treats wildcards literally",
+			opts: urlModels.ListOptions{
+				SortBy: urlModels.SortByShortCode, PerPage: 10,
+				Filter: urlModels.ListFilter{URLContains: "50%_"},
+			},
+			want:  []string{"bbb"},
+			total: 1,
+		},
+		{
+			name: "minimum access count",
+			opts: urlModels.ListOptions{
+				SortBy: urlModels.SortByShortCode, PerPage: 1,
+				Filter: urlModels.ListFilter{MinAccessCount: 5},
+			},
+			want:  []string{"aaa"},
+			total: 3,
+		},
+		{
+			name: "created window",
+			opts: urlModels.ListOptions{
+				SortBy: urlModels.SortByCreated, PerPage: 10,
+				Filter: urlModels.ListFilter{CreatedAfter: time.Now().Add(-time.Hour), CreatedBefore: time.Now().Add(time.Hour)},
+			},
+			want:  []string{"aaa", "bbb", "ccc", "ddd", "eee"},
+			total: 5,
+		},
+		{
+			name: "created in the future",
+			opts: urlModels.ListOptions{
+				SortBy: urlModels.SortByCreated, PerPage: 10,
+				Filter: urlModels.ListFilter{CreatedAfter: time.Now().Add(time.Hour)},
+			},
+			want:  []string{},
+			total: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.List(ctx, &tt.opts)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			got := listCodes(result)
+			if tt.opts.SortBy == urlModels.SortByCreated {
+				// Records created within one clock tick tie on created.
+				slices.Sort(got)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("List() codes = %v, want %v", got, tt.want)
+			}
+			if result.TotalItems != tt.total {
+				t.Errorf("List() TotalItems = %d, want %d", result.TotalItems, tt.total)
+			}
+		})
+	}
+
+	t.Run("cursor walk visits every item once", func(t *testing.T) {
+		opts := urlModels.ListOptions{SortBy: urlModels.SortByCreated, Desc: true, PerPage: 2}
+		var seen []string
+		for page := 0; page < 5; page++ {
+			result, err := repo.List(ctx, &opts)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			seen = append(seen, listCodes(result)...)
+			if len(result.Items) < opts.PerPage {
+				break
+			}
+			opts.After = urlModels.CursorFor(result.Items[len(result.Items)-1])
+		}
+		slices.Sort(seen)
+		if want := []string{"aaa", "bbb", "ccc", "ddd", "eee"}; !slices.Equal(seen, want) {
+			t.Errorf("cursor walk visited %v, want %v", seen, want)
+		}
+	})
+
+	t.Run("returns access counts", func(t *testing.T) {
+		result, err := repo.List(ctx, &urlModels.ListOptions{SortBy: urlModels.SortByShortCode, PerPage: 1, After: &urlModels.ListCursor{ShortCode: "ccc"}})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(result.Items) != 1 || result.Items[0].AccessCount != 9 {
+			t.Errorf("List() items = %+v, want ddd with 9 clicks", result.Items)
+		}
+	})
+}
+
+func testURLRepositoryExpiry(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	expired, future := now.Add(-time.Hour), now.Add(time.Hour)
+	for code, expiresAt := range map[string]*time.Time{"expired": &expired, "future": &future, "forever": nil} {
+		if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: code, ExpiresAt: expiresAt}); err != nil {
+			t.Fatalf("Create(%s) error = %v", code, err)
+		}
+	}
+
+	got, err := repo.GetByShortCode(ctx, "future")
+	if err != nil {
+		t.Fatalf("GetByShortCode() error = %v", err)
+	}
+	if got.ExpiresAt == nil || !got.ExpiresAt.Equal(future) {
+		t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, future)
+	}
+	if got, _ := repo.GetByShortCode(ctx, "forever"); got.ExpiresAt != nil {
+		t.Errorf("ExpiresAt = %v, want nil", got.ExpiresAt)
+	}
+
+	result, err := repo.List(ctx, &urlModels.ListOptions{SortBy: urlModels.SortByShortCode, PerPage: 10, Filter: urlModels.ListFilter{ExpiredBefore: now}})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if codes := listCodes(result); !slices.Equal(codes, []string{"expired"}) {
+		t.Errorf("List() expired codes = %v, want [expired]", codes)
+	}
+
+	extended := now.Add(2 * time.Hour)
+	updated, err := repo.Update(ctx, "expired", &urlModels.ShortURLUpdate{ExpiresAt: &extended})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.ExpiresAt == nil || !updated.ExpiresAt.Equal(extended) {
+		t.Errorf("Update() ExpiresAt = %v, want %v", updated.ExpiresAt, extended)
+	}
+
+	updated, err = repo.Update(ctx, "future", &urlModels.ShortURLUpdate{ClearExpiresAt: true})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.ExpiresAt != nil {
+		t.Errorf("Update() cleared ExpiresAt = %v, want nil", updated.ExpiresAt)
+	}
+	if got, _ := repo.GetByShortCode(ctx, "future"); got.ExpiresAt != nil {
+		t.Errorf("GetByShortCode() after clear ExpiresAt = %v, want nil", got.ExpiresAt)
+	}
+}
+
+func testURLRepositoryConsumeClick(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+
+	limited := &urlModels.ShortURL{URL: "https://example.com", ShortCode: "once", MaxClicks: 10, FallbackURL: "https://example.com/sold-out"}
+	if err := repo.Create(ctx, limited); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	got, err := repo.GetByShortCode(ctx, "once")
+	if err != nil {
+		t.Fatalf("GetByShortCode() error = %v", err)
+	}
+	if got.MaxClicks != 10 || got.FallbackURL != "https://example.com/sold-out" {
+		t.Errorf("GetByShortCode() = %+v, want the click limit and fallback stored", got)
+	}
+
+	const workers = 50
+	var granted, gone atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			consumed, err := repo.ConsumeClick(ctx, "once")
+			switch {
+			case err == nil:
+				granted.Add(1)
+				if consumed.AccessCount < 1 || consumed.AccessCount > 10 {
+					t.Errorf("ConsumeClick() AccessCount = %d, want 1..10", consumed.AccessCount)
+				}
+			case errorCode(err) == serviceErrors.ErrorCodeGone:
+				gone.Add(1)
+			default:
+				t.Errorf("ConsumeClick() unexpected error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if granted.Load() != 10 || gone.Load() != workers-10 {
+		t.Errorf("granted = %d, gone = %d, want 10 and %d", granted.Load(), gone.Load(), workers-10)
+	}
+	if got, _ := repo.GetByShortCode(ctx, "once"); got.AccessCount != 10 {
+		t.Errorf("AccessCount after exhausting = %d, want 10", got.AccessCount)
+	}
+
+	// Raising the limit makes the link resolve again.
+	more := int64(11)
+	if _, err := repo.Update(ctx, "once", &urlModels.ShortURLUpdate{MaxClicks: &more}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if consumed, err := repo.ConsumeClick(ctx, "once"); err != nil || consumed.AccessCount != 11 {
+		t.Errorf("ConsumeClick() after raising limit = %+v, %v, want AccessCount 11", consumed, err)
+	}
+
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "unlimited"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if consumed, err := repo.ConsumeClick(ctx, "unlimited"); err != nil || consumed.AccessCount != 1 {
+		t.Errorf("ConsumeClick() unlimited = %+v, %v, want AccessCount 1", consumed, err)
+	}
+	if _, err := repo.ConsumeClick(ctx, "missing"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("ConsumeClick() unknown code error = %v, want not found", err)
+	}
+}
+
+func testURLRepositoryPasswordHash(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "locked", PasswordHash: "$2a$10$hash"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got, err := repo.GetByShortCode(ctx, "locked"); err != nil || got.PasswordHash != "$2a$10$hash" {
+		t.Fatalf("GetByShortCode() = %+v, %v, want the password hash stored", got, err)
+	}
+
+	cleared := ""
+	updated, err := repo.Update(ctx, "locked", &urlModels.ShortURLUpdate{PasswordHash: &cleared})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Protected() {
+		t.Errorf("Update() PasswordHash = %q, want it removed", updated.PasswordHash)
+	}
+	if got, _ := repo.GetByShortCode(ctx, "locked"); got.Protected() {
+		t.Errorf("GetByShortCode() after removal PasswordHash = %q, want empty", got.PasswordHash)
+	}
+}
+
+func testURLRepositoryActiveWindow(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	from, until := now.Add(time.Hour), now.Add(2*time.Hour)
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "launch", ActiveFrom: &from, ActiveUntil: &until}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	got, err := repo.GetByShortCode(ctx, "launch")
+	if err != nil {
+		t.Fatalf("GetByShortCode() error = %v", err)
+	}
+	if got.ActiveFrom == nil || !got.ActiveFrom.Equal(from) || got.ActiveUntil == nil || !got.ActiveUntil.Equal(until) {
+		t.Errorf("GetByShortCode() window = %v..%v, want %v..%v", got.ActiveFrom, got.ActiveUntil, from, until)
+	}
+
+	updated, err := repo.Update(ctx, "launch", &urlModels.ShortURLUpdate{ClearActiveFrom: true, ActiveUntil: &from})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.ActiveFrom != nil || updated.ActiveUntil == nil || !updated.ActiveUntil.Equal(from) {
+		t.Errorf("Update() window = %v..%v, want nil..%v", updated.ActiveFrom, updated.ActiveUntil, from)
+	}
+}
+
+func testURLRepositoryDisabled(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "paused"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.IncrementAccessCount(ctx, "paused", 3); err != nil {
+		t.Fatalf("IncrementAccessCount() error = %v", err)
+	}
+
+	disabled, reason, legal := true, "court order", true
+	if _, err := repo.Update(ctx, "paused", &urlModels.ShortURLUpdate{Disabled: &disabled, DisabledReason: &reason, DisabledLegal: &legal}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, err := repo.GetByShortCode(ctx, "paused")
+	if err != nil {
+		t.Fatalf("GetByShortCode() error = %v", err)
+	}
+	if !got.Disabled || got.DisabledReason != reason || !got.DisabledLegal || got.AccessCount != 3 {
+		t.Errorf("GetByShortCode() = disabled %v, reason %q, legal %v, count %d, want disabled with the reason and count kept", got.Disabled, got.DisabledReason, got.DisabledLegal, got.AccessCount)
+	}
+
+	disabled, reason, legal = false, "", false
+	got, err = repo.Update(ctx, "paused", &urlModels.ShortURLUpdate{Disabled: &disabled, DisabledReason: &reason, DisabledLegal: &legal})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got.Disabled || got.DisabledReason != "" || got.DisabledLegal {
+		t.Errorf("Update() = disabled %v, reason %q, legal %v, want enabled", got.Disabled, got.DisabledReason, got.DisabledLegal)
+	}
+}
+
+func testURLRepositoryTrash(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, code := range []string{"live", "recent", "old"} {
+		if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com/" + code, ShortCode: code}); err != nil {
+			t.Fatalf("Create(%s) error = %v", code, err)
+		}
+	}
+	recent, old := now.Add(-time.Minute), now.Add(-time.Hour)
+	if _, err := repo.Update(ctx, "recent", &urlModels.ShortURLUpdate{DeletedAt: &recent}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := repo.Update(ctx, "old", &urlModels.ShortURLUpdate{DeletedAt: &old}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	list := func(filter urlModels.ListFilter) []string {
+		t.Helper()
+		result, err := repo.List(ctx, &urlModels.ListOptions{Filter: filter, SortBy: urlModels.SortByShortCode, PerPage: 10})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		var codes []string
+		for _, item := range result.Items {
+			codes = append(codes, item.ShortCode)
+		}
+		return codes
+	}
+	if got := list(urlModels.ListFilter{}); !slices.Equal(got, []string{"live"}) {
+		t.Errorf("List() = %v, want only the live link", got)
+	}
+	if got := list(urlModels.ListFilter{Trashed: true}); !slices.Equal(got, []string{"old", "recent"}) {
+		t.Errorf("List(trashed) = %v, want [old recent]", got)
+	}
+	if got := list(urlModels.ListFilter{Trashed: true, DeletedBefore: now.Add(-30 * time.Minute)}); !slices.Equal(got, []string{"old"}) {
+		t.Errorf("List(trashed before) = %v, want [old]", got)
+	}
+
+	// A trashed code stays reserved.
+	if exists, err := repo.ExistsByShortCode(ctx, "old"); err != nil || !exists {
+		t.Errorf("ExistsByShortCode(trashed) = %v, %v, want true", exists, err)
+	}
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "old"}); errorCode(err) != serviceErrors.ErrorCodeDuplicate {
+		t.Errorf("Create() over a trashed code error = %v, want duplicate", err)
+	}
+
+	restored, err := repo.Update(ctx, "old", &urlModels.ShortURLUpdate{ClearDeletedAt: true})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Errorf("Update() DeletedAt = %v, want nil after restoring", restored.DeletedAt)
+	}
+}
+
+func testURLRepositoryMetadata(t *testing.T, repo URLRepository) {
+	ctx := context.Background()
+	metadata := map[string]string{"legacyId": "42", "team": "growth"}
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "tagged", Metadata: metadata}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "plain"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repo.GetByShortCode(ctx, "tagged")
+	if err != nil {
+		t.Fatalf("GetByShortCode() error = %v", err)
+	}
+	if !maps.Equal(got.Metadata, metadata) {
+		t.Errorf("GetByShortCode() Metadata = %v, want %v", got.Metadata, metadata)
+	}
+	if got, err := repo.GetByShortCode(ctx, "plain"); err != nil || got.Metadata != nil {
+		t.Errorf("GetByShortCode(plain) Metadata = %v, %v, want nil", got, err)
+	}
+
+	// Updates that leave Metadata nil keep it.
+	url := "https://example.com/moved"
+	if got, err := repo.Update(ctx, "tagged", &urlModels.ShortURLUpdate{URL: &url}); err != nil || !maps.Equal(got.Metadata, metadata) {
+		t.Errorf("Update(url) Metadata = %v, %v, want it kept", got, err)
+	}
+
+	replaced := map[string]string{"team": "platform"}
+	if got, err := repo.Update(ctx, "tagged", &urlModels.ShortURLUpdate{Metadata: replaced}); err != nil || !maps.Equal(got.Metadata, replaced) {
+		t.Errorf("Update(metadata) Metadata = %v, %v, want %v", got, err, replaced)
+	}
+	if got, err := repo.Update(ctx, "tagged", &urlModels.ShortURLUpdate{Metadata: map[string]string{}}); err != nil || got.Metadata != nil {
+		t.Errorf("Update(empty metadata) Metadata = %v, %v, want nil", got, err)
+	}
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/counter"
+	"github.com/rowjay/url-shortening-service/internal/database"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	urlModels "github.com/rowjay/url-shortening-service/internal/models"
+)
+
+func TestPocketBaseURLRepository(t *testing.T) {
+	runURLRepositoryConformance(t, func(t *testing.T) URLRepository {
+		_, pb := newFakePocketBase(t)
+		return NewURLRepository(pb)
+	})
+}
+
+func TestPocketBaseHistoryRepository(t *testing.T) {
+	runHistoryRepositoryConformance(t, func(t *testing.T) HistoryRepository {
+		_, pb := newFakePocketBaseCollection(t, "short_url_history", "url_id", "version")
+		return NewHistoryRepository(pb)
+	})
+}
+
+func TestPocketBaseKeyRepository(t *testing.T) {
+	runKeyRepositoryConformance(t, func(t *testing.T) (URLRepository, KeyRepository) {
+		urls := &fakePocketBase{collection: "short_urls", unique: []string{"short_code"}, records: make(map[string]map[string]any)}
+		keys := &fakePocketBase{collection: "short_code_keys", unique: []string{"short_code"}, records: make(map[string]map[string]any)}
+		mux := http.NewServeMux()
+		mux.Handle("/api/collections/short_urls/", urls)
+		mux.Handle("/api/collections/short_code_keys/", keys)
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		pb := &database.PBClient{BaseURL: server.URL, HTTPClient: server.Client()}
+		return NewURLRepository(pb), NewKeyRepository(pb)
+	})
+}
+
+func TestPocketBaseSequenceRepository(t *testing.T) {
+	runSequenceRepositoryConformance(t, func(t *testing.T) SequenceRepository {
+		_, pb := newFakePocketBaseCollection(t, "short_code_blocks", "first")
+		return NewSequenceRepository(pb)
+	})
+}
+
+func TestPocketBaseConcurrentAccessCountingIsExact(t *testing.T) {
+	ctx := context.Background()
+	fake, pb := newFakePocketBase(t)
+	repo := NewURLRepository(pb)
+
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "abc123"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	aggregator := counter.NewAggregator(repo, time.Millisecond)
+	aggregator.Start()
+
+	const resolves = 3000
+	var wg sync.WaitGroup
+	for i := 0; i < resolves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			aggregator.Increment("abc123")
+		}()
+	}
+	wg.Wait()
+
+	if err := aggregator.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if got := fake.accessCount("abc123"); got != resolves {
+		t.Errorf("access_count = %d, want %d", got, resolves)
+	}
+}
+
+func TestPocketBaseLookupIgnoresFilterInjection(t *testing.T) {
+	ctx := context.Background()
+	_, pb := newFakePocketBase(t)
+	repo := NewURLRepository(pb)
+
+	if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "abc123"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	tests := []struct {
+		code string
+		want serviceErrors.ErrorCode
+	}{
+		{`x" || short_code!="`, serviceErrors.ErrorCodeNotFound},
+		{`abc123" || "1"="1`, serviceErrors.ErrorCodeNotFound},
+		{`x\`, serviceErrors.ErrorCodeValidation},
+	}
+
+	for _, tt := range tests {
+		if got, err := repo.GetByShortCode(ctx, tt.code); errorCode(err) != tt.want {
+			t.Errorf("GetByShortCode(%q) = %+v, %v, want code %d", tt.code, got, err, tt.want)
+		}
+	}
+}
+
+// flakyReturns wraps a fake PocketBase. The first "+1" PATCH it sees lands
+// after a concurrent click took the last one, and the next failures PATCHes
+// taking a click back answer 503.
+type flakyReturns struct {
+	fake     *fakePocketBase
+	raced    bool
+	failures int
+}
+
+func (f *flakyReturns) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPatch {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		switch {
+		case strings.Contains(string(body), `"access_count+":1`) && !f.raced:
+			f.raced = true
+			f.fake.mu.Lock()
+			for _, record := range f.fake.records {
+				record["access_count"] = record["access_count"].(float64) + 1
+			}
+			f.fake.mu.Unlock()
+		case strings.Contains(string(body), `"access_count+":-1`) && f.failures > 0:
+			f.failures--
+			writeFakeError(w, http.StatusServiceUnavailable, "Service unavailable.", nil)
+			return
+		}
+	}
+	f.fake.ServeHTTP(w, r)
+}
+
+func TestPocketBaseConsumeClickRetriesReturningClick(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		wantCode serviceErrors.ErrorCode
+	}{
+		{"transient failure", 2, serviceErrors.ErrorCodeGone},
+		{"persistent failure", pbReturnClickAttempts, serviceErrors.ErrorCodeUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := &fakePocketBase{collection: "short_urls", unique: []string{"short_code"}, records: make(map[string]map[string]any)}
+			server := httptest.NewServer(&flakyReturns{fake: fake, failures: tt.failures})
+			t.Cleanup(server.Close)
+			repo := NewURLRepository(&database.PBClient{BaseURL: server.URL, HTTPClient: server.Client()})
+
+			if err := repo.Create(ctx, &urlModels.ShortURL{URL: "https://example.com", ShortCode: "once", MaxClicks: 1}); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if _, err := repo.ConsumeClick(ctx, "once"); errorCode(err) != tt.wantCode {
+				t.Errorf("ConsumeClick() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			want := int64(1)
+			if tt.wantCode != serviceErrors.ErrorCodeGone {
+				want = 2
+			}
+			if got := fake.accessCount("once"); got != want {
+				t.Errorf("access_count = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+// slowCreates delays record creation and tracks how many run at once.
+type slowCreates struct {
+	http.Handler
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (s *slowCreates) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		s.mu.Lock()
+		s.current++
+		s.max = max(s.max, s.current)
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.current--
+			s.mu.Unlock()
+		}()
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Handler.ServeHTTP(w, r)
+}
+
+func TestPocketBaseKeyRepositoryBoundsReserve(t *testing.T) {
+	urls := &fakePocketBase{collection: "short_urls", unique: []string{"short_code"}, records: make(map[string]map[string]any)}
+	keys := &slowCreates{Handler: &fakePocketBase{collection: "short_code_keys", unique: []string{"short_code"}, records: make(map[string]map[string]any)}}
+	mux := http.NewServeMux()
+	mux.Handle("/api/collections/short_urls/", urls)
+	mux.Handle("/api/collections/short_code_keys/", keys)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	repo := NewKeyRepository(&database.PBClient{BaseURL: server.URL, HTTPClient: server.Client()})
+
+	candidates := make([]string, 3*pbReserveMax)
+	for i := range candidates {
+		candidates[i] = fmt.Sprintf("code%04d", i)
+	}
+	reserved, err := repo.Reserve(context.Background(), candidates)
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if len(reserved) != pbReserveMax {
+		t.Errorf("Reserve() reserved %d codes, want %d", len(reserved), pbReserveMax)
+	}
+	if keys.max < 2 || keys.max > pbReserveConcurrency {
+		t.Errorf("Reserve() ran %d creates at once, want 2..%d", keys.max, pbReserveConcurrency)
+	}
+}
@@ -0,0 +1,17 @@
+package services
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx naming who is making the request. The
+// actor is recorded in the destination history of links it creates or
+// changes.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
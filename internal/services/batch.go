@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rowjay/url-shortening-service/internal/dto"
+	"github.com/rowjay/url-shortening-service/internal/errors"
+)
+
+// Batch item statuses.
+const (
+	BatchCreated   = "created"
+	BatchDeleted   = "deleted"
+	BatchDuplicate = "duplicate"
+	BatchInvalid   = "invalid"
+	BatchNotFound  = "not_found"
+	BatchFailed    = "failed"
+)
+
+func (s *urlServiceImpl) BatchCreateURLs(ctx context.Context, req *dto.BatchCreateRequest) (*dto.BatchCreateResponse, error) {
+	if err := s.validateBatchSize("service.BatchCreateURLs", len(req.Items)); err != nil {
+		return nil, err
+	}
+
+	results := make([]dto.BatchCreateResult, len(req.Items))
+	// A custom code repeated within the batch is a duplicate of its first
+	// occurrence. Catching it here keeps the outcome independent of which
+	// item reaches storage first.
+	seen := make(map[string]bool)
+	var pending []int
+	for i, item := range req.Items {
+		results[i].Index = i
+		if item.CustomCode != nil {
+			if seen[*item.CustomCode] {
+				results[i].Status = BatchDuplicate
+				results[i].Error = "short code appears earlier in the batch"
+				continue
+			}
+			seen[*item.CustomCode] = true
+		}
+		pending = append(pending, i)
+	}
+
+	s.forEachBounded(len(pending), func(n int) {
+		i := pending[n]
+		link, err := s.CreateShortURL(ctx, &req.Items[i])
+		if err != nil {
+			results[i].Status = batchErrorStatus(err)
+			results[i].Error = errorMessage(err)
+			return
+		}
+		results[i].Status = BatchCreated
+		results[i].Link = link
+	})
+
+	resp := &dto.BatchCreateResponse{Items: results}
+	for _, result := range results {
+		switch result.Status {
+		case BatchCreated:
+			resp.Created++
+		case BatchDuplicate:
+			resp.Duplicates++
+		case BatchInvalid:
+			resp.Invalid++
+		default:
+			resp.Failed++
+		}
+	}
+	return resp, nil
+}
+
+func (s *urlServiceImpl) BatchDeleteURLs(ctx context.Context, req *dto.BatchDeleteRequest) (*dto.BatchDeleteResponse, error) {
+	if err := s.validateBatchSize("service.BatchDeleteURLs", len(req.ShortCodes)); err != nil {
+		return nil, err
+	}
+
+	results := make([]dto.BatchDeleteResult, len(req.ShortCodes))
+	// As with creates, a code repeated within the batch is a duplicate of
+	// its first occurrence.
+	seen := make(map[string]bool)
+	var pending []int
+	for i, shortCode := range req.ShortCodes {
+		results[i] = dto.BatchDeleteResult{Index: i, ShortCode: shortCode}
+		if err := s.validator.ValidateShortCode(shortCode); err != nil {
+			results[i].Status = BatchInvalid
+			results[i].Error = errorMessage(err)
+			continue
+		}
+		if seen[shortCode] {
+			results[i].Status = BatchDuplicate
+			results[i].Error = "short code appears earlier in the batch"
+			continue
+		}
+		seen[shortCode] = true
+		pending = append(pending, i)
+	}
+
+	s.forEachBounded(len(pending), func(n int) {
+		i := pending[n]
+		if err := s.DeleteShortURL(ctx, req.ShortCodes[i]); err != nil {
+			results[i].Status = batchErrorStatus(err)
+			results[i].Error = errorMessage(err)
+			return
+		}
+		results[i].Status = BatchDeleted
+	})
+
+	resp := &dto.BatchDeleteResponse{Items: results}
+	for _, result := range results {
+		switch result.Status {
+		case BatchDeleted:
+			resp.Deleted++
+		case BatchNotFound:
+			resp.NotFound++
+		case BatchDuplicate:
+			resp.Duplicates++
+		case BatchInvalid:
+			resp.Invalid++
+		default:
+			resp.Failed++
+		}
+	}
+	return resp, nil
+}
+
+func (s *urlServiceImpl) validateBatchSize(op string, n int) error {
+	if n == 0 {
+		return errors.NewValidationError(op, "batch is empty", nil)
+	}
+	if n > s.batchMaxSize {
+		return errors.NewValidationError(op, fmt.Sprintf("batch can have at most %d items", s.batchMaxSize), nil)
+	}
+	return nil
+}
+
+// forEachBounded calls fn for 0 to n-1, with at most batchConcurrency calls
+// in flight, and returns once all of them have.
+func (s *urlServiceImpl) forEachBounded(n int, fn func(int)) {
+	workers := min(s.batchConcurrency, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// batchErrorStatus maps an error processing a batch item to its status.
+func batchErrorStatus(err error) string {
+	switch {
+	case hasErrorCode(err, errors.ErrorCodeValidation), hasErrorCode(err, errors.ErrorCodeBadRequest):
+		return BatchInvalid
+	case hasErrorCode(err, errors.ErrorCodeDuplicate):
+		return BatchDuplicate
+	case hasErrorCode(err, errors.ErrorCodeNotFound):
+		return BatchNotFound
+	}
+	return BatchFailed
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rowjay/url-shortening-service/internal/dto"
+	"github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rowjay/url-shortening-service/internal/models"
+)
+
+// ConflictPolicy decides what an import does with a row whose custom code
+// is already taken.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip leaves the existing link alone.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite points the existing link at the row's URL and
+	// replaces its metadata.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictFail stops the import at the first conflict. Rows imported
+	// before it are kept.
+	ConflictFail ConflictPolicy = "fail"
+)
+
+// Import row statuses.
+const (
+	ImportCreated  = "created"
+	ImportUpdated  = "updated"
+	ImportSkipped  = "skipped"
+	ImportInvalid  = "invalid"
+	ImportConflict = "conflict"
+	ImportFailed   = "failed"
+)
+
+// ParseConflictPolicy parses a policy name; "" means ConflictSkip.
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(value); policy {
+	case "":
+		return ConflictSkip, nil
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return policy, nil
+	}
+	return "", errors.NewValidationError("service.ParseConflictPolicy", "onConflict must be skip, overwrite or fail", nil)
+}
+
+// ImportSource streams the rows of an import. bulk.Reader satisfies it.
+type ImportSource interface {
+	Next() (*dto.ImportRow, error)
+}
+
+type ImportOptions struct {
+	// DryRun validates every row and reports what would happen without
+	// writing anything.
+	DryRun     bool
+	OnConflict ConflictPolicy
+}
+
+func (s *urlServiceImpl) ImportURLs(ctx context.Context, rows ImportSource, opts ImportOptions, report func(*dto.ImportResult) error) (*dto.ImportSummary, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = ConflictSkip
+	}
+
+	summary := &dto.ImportSummary{DryRun: opts.DryRun}
+	// A dry run writes nothing, so custom codes it would have created are
+	// tracked to report later rows reusing them as conflicts.
+	planned := make(map[string]bool)
+	for {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		row, err := rows.Next()
+		if err == io.EOF {
+			return summary, nil
+		}
+
+		var result *dto.ImportResult
+		switch {
+		case err == nil:
+			result = s.importRow(ctx, row, opts, planned)
+		case row != nil && hasErrorCode(err, errors.ErrorCodeValidation):
+			result = &dto.ImportResult{Line: row.Line, Status: ImportInvalid, ShortCode: row.CustomCode, URL: row.URL, Error: errorMessage(err)}
+		default:
+			return summary, err
+		}
+
+		summary.Rows++
+		switch result.Status {
+		case ImportCreated:
+			summary.Created++
+		case ImportUpdated:
+			summary.Updated++
+		case ImportSkipped:
+			summary.Skipped++
+		case ImportInvalid:
+			summary.Invalid++
+		case ImportConflict:
+			summary.Conflicts++
+		default:
+			summary.Failed++
+		}
+		if err := report(result); err != nil {
+			return summary, err
+		}
+
+		if result.Status == ImportConflict && opts.OnConflict == ConflictFail {
+			summary.Aborted = true
+			return summary, nil
+		}
+	}
+}
+
+// importRow validates row, applies the conflict policy and, unless this is
+// a dry run, creates or overwrites the link.
+func (s *urlServiceImpl) importRow(ctx context.Context, row *dto.ImportRow, opts ImportOptions, planned map[string]bool) *dto.ImportResult {
+	result := &dto.ImportResult{Line: row.Line, ShortCode: row.CustomCode, URL: row.URL}
+	fail := func(status string, err error) *dto.ImportResult {
+		result.Status = status
+		result.Error = errorMessage(err)
+		return result
+	}
+
+	if err := s.validator.ValidateURL(row.URL); err != nil {
+		return fail(ImportInvalid, err)
+	}
+	if row.CustomCode != "" {
+		if err := s.validator.ValidateShortCode(row.CustomCode); err != nil {
+			return fail(ImportInvalid, err)
+		}
+	}
+	if err := s.validator.ValidateMetadata(row.Metadata); err != nil {
+		return fail(ImportInvalid, err)
+	}
+
+	var existing *models.ShortURL
+	if row.CustomCode != "" {
+		shortURL, err := s.repo.GetByShortCode(ctx, row.CustomCode)
+		switch {
+		case err == nil:
+			existing = shortURL
+		case !hasErrorCode(err, errors.ErrorCodeNotFound):
+			return fail(ImportFailed, err)
+		case planned[row.CustomCode]:
+			existing = &models.ShortURL{ShortCode: row.CustomCode}
+		}
+	}
+
+	if existing != nil {
+		switch {
+		case opts.OnConflict == ConflictSkip:
+			result.Status = ImportSkipped
+			return result
+		case opts.OnConflict == ConflictFail:
+			return fail(ImportConflict, errors.NewDuplicateError("service.ImportURLs", "short code already exists"))
+		case existing.Trashed():
+			return fail(ImportConflict, errors.NewDuplicateError("service.ImportURLs", "short code belongs to a link in the trash"))
+		case opts.DryRun:
+			result.Status = ImportUpdated
+			return result
+		}
+
+		// An overwrite replaces the metadata, clearing it when the row has
+		// none.
+		metadata := row.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		if _, err := s.UpdateShortURL(ctx, row.CustomCode, &dto.UpdateURLRequest{URL: row.URL, Metadata: metadata}); err != nil {
+			return fail(importErrorStatus(err), err)
+		}
+		result.Status = ImportUpdated
+		return result
+	}
+
+	if opts.DryRun {
+		if row.CustomCode != "" {
+			planned[row.CustomCode] = true
+		}
+		result.Status = ImportCreated
+		return result
+	}
+
+	req := &dto.CreateURLRequest{URL: row.URL, Metadata: row.Metadata}
+	if row.CustomCode != "" {
+		req.CustomCode = &row.CustomCode
+	}
+	created, err := s.CreateShortURL(ctx, req)
+	if err != nil {
+		return fail(importErrorStatus(err), err)
+	}
+	result.ShortCode = created.ShortCode
+	result.Status = ImportCreated
+	return result
+}
+
+// importErrorStatus maps an error creating or overwriting a row to its
+// status. A duplicate here means another writer took the code after the
+// row was checked.
+func importErrorStatus(err error) string {
+	switch {
+	case hasErrorCode(err, errors.ErrorCodeValidation):
+		return ImportInvalid
+	case hasErrorCode(err, errors.ErrorCodeDuplicate):
+		return ImportConflict
+	}
+	return ImportFailed
+}
+
+func (s *urlServiceImpl) ExportURLs(ctx context.Context, each func(*dto.GetStatsResponse) error) error {
+	opts := &models.ListOptions{SortBy: models.SortByShortCode, PerPage: constants.MaxPageSize}
+	for {
+		result, err := s.repo.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, shortURL := range result.Items {
+			if err := each(s.statsResponse(shortURL)); err != nil {
+				return err
+			}
+		}
+		if len(result.Items) < opts.PerPage {
+			return nil
+		}
+		opts.After = models.CursorFor(result.Items[len(result.Items)-1])
+	}
+}
+
+func hasErrorCode(err error, code errors.ErrorCode) bool {
+	var serviceErr *errors.ServiceError
+	return stderrors.As(err, &serviceErr) && serviceErr.Code == code
+}
+
+// errorMessage is the client-facing part of err.
+func errorMessage(err error) string {
+	var serviceErr *errors.ServiceError
+	if stderrors.As(err, &serviceErr) {
+		return serviceErr.Message
+	}
+	return "internal error"
+}
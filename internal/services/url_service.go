@@ -2,32 +2,162 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/rowjay/url-shortening-service/internal/codegen"
+	"github.com/rowjay/url-shortening-service/internal/constants"
 	"github.com/rowjay/url-shortening-service/internal/dto"
 	"github.com/rowjay/url-shortening-service/internal/errors"
 	"github.com/rowjay/url-shortening-service/internal/models"
 	"github.com/rowjay/url-shortening-service/internal/repository"
-	"github.com/rowjay/url-shortening-service/internal/utils"
+	"github.com/rowjay/url-shortening-service/internal/unlock"
 	"github.com/rowjay/url-shortening-service/internal/validator"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type URLService interface {
 	CreateShortURL(ctx context.Context, req *dto.CreateURLRequest) (*dto.CreateURLResponse, error)
 	GetOriginalURL(ctx context.Context, shortCode string) (*dto.GetURLResponse, error)
 	UpdateShortURL(ctx context.Context, shortCode string, req *dto.UpdateURLRequest) (*dto.UpdateURLResponse, error)
+	// DeleteShortURL moves a link to the trash. It stops resolving but keeps
+	// its short code until it is restored or purged.
 	DeleteShortURL(ctx context.Context, shortCode string) error
 	GetStatistics(ctx context.Context, shortCode string) (*dto.GetStatsResponse, error)
+	ListURLs(ctx context.Context, req *dto.ListURLsRequest) (*dto.ListURLsResponse, error)
+	// UnlockURL resolves a password-protected link for client, which
+	// identifies the caller for attempt rate limiting, and issues an access
+	// token for GetUnlockedURL.
+	UnlockURL(ctx context.Context, shortCode, client string, req *dto.UnlockURLRequest) (*dto.UnlockURLResponse, error)
+	GetUnlockedURL(ctx context.Context, shortCode, accessToken string) (*dto.GetURLResponse, error)
+	// DisableURL stops a link from resolving without deleting it or its
+	// statistics; EnableURL reverses it.
+	DisableURL(ctx context.Context, shortCode string, req *dto.DisableURLRequest) (*dto.GetStatsResponse, error)
+	EnableURL(ctx context.Context, shortCode string) (*dto.GetStatsResponse, error)
+	ListTrash(ctx context.Context, req *dto.ListURLsRequest) (*dto.ListURLsResponse, error)
+	RestoreURL(ctx context.Context, shortCode string) (*dto.GetStatsResponse, error)
+	// GetHistory lists every destination a link has had. RollbackURL makes
+	// the destination of an earlier version current again, recording it as
+	// a new version.
+	GetHistory(ctx context.Context, shortCode string) (*dto.HistoryResponse, error)
+	RollbackURL(ctx context.Context, shortCode string, version int) (*dto.UpdateURLResponse, error)
+	// ImportURLs creates a link for every row of rows, passing the outcome
+	// of each row to report as soon as it is known.
+	ImportURLs(ctx context.Context, rows ImportSource, opts ImportOptions, report func(*dto.ImportResult) error) (*dto.ImportSummary, error)
+	// ExportURLs passes every link outside the trash, with its statistics,
+	// to each in short code order.
+	ExportURLs(ctx context.Context, each func(*dto.GetStatsResponse) error) error
+	// BatchCreateURLs and BatchDeleteURLs apply CreateShortURL and
+	// DeleteShortURL to every item, reporting the outcome per item instead
+	// of failing the whole batch.
+	BatchCreateURLs(ctx context.Context, req *dto.BatchCreateRequest) (*dto.BatchCreateResponse, error)
+	BatchDeleteURLs(ctx context.Context, req *dto.BatchDeleteRequest) (*dto.BatchDeleteResponse, error)
+}
+
+// ClickRecorder accepts resolves of a short code without blocking the
+// request on storage.
+type ClickRecorder interface {
+	RecordClick(ctx context.Context, shortCode string)
+}
+
+// AccessCounter reports accesses recorded but not yet persisted, so
+// statistics stay exact between flushes.
+type AccessCounter interface {
+	Pending(shortCode string) int64
+}
+
+// CodeGenerator produces candidate codes for links created without a custom
+// one. Candidates that are already taken are skipped.
+type CodeGenerator interface {
+	Generate(ctx context.Context) (string, error)
+}
+
+// KeySource hands out codes reserved in storage ahead of time, such as a
+// keypool.Pool. No link uses them yet, so creating one needs no lookup.
+type KeySource interface {
+	Take(ctx context.Context) (string, error)
+}
+
+// Options tunes the service. Zero values fall back to the defaults in
+// constants.
+type Options struct {
+	// UnlockTokenSecret signs access tokens for password-protected links.
+	// When empty a random secret is used, valid for this process only.
+	UnlockTokenSecret   []byte
+	UnlockTokenTTL      time.Duration
+	UnlockMaxAttempts   int
+	UnlockAttemptWindow time.Duration
+	// HideNotYetActive answers links whose activation window has not
+	// started as if they did not exist.
+	HideNotYetActive bool
+	// BatchMaxSize caps the items of a batch request and BatchConcurrency
+	// how many of them are processed at once.
+	BatchMaxSize     int
+	BatchConcurrency int
+	// CodeGenerator generates short codes; by default they are random and
+	// DefaultShortCodeLength characters long.
+	CodeGenerator CodeGenerator
+	// Keys, when set, supplies the short codes instead of CodeGenerator.
+	Keys KeySource
 }
 
 type urlServiceImpl struct {
-	repo      repository.URLRepository
-	validator *validator.URLValidator
+	repo             repository.URLRepository
+	history          repository.HistoryRepository
+	clicks           ClickRecorder
+	counter          AccessCounter
+	validator        *validator.URLValidator
+	codes            CodeGenerator
+	keys             KeySource
+	tokens           *unlock.Signer
+	attempts         *unlock.Limiter
+	hideNotYetActive bool
+	batchMaxSize     int
+	batchConcurrency int
+	now              func() time.Time
 }
 
-func NewURLService(repo repository.URLRepository) URLService {
+func NewURLService(repo repository.URLRepository, history repository.HistoryRepository, clicks ClickRecorder, counter AccessCounter, opts Options) URLService {
+	if opts.UnlockTokenTTL <= 0 {
+		opts.UnlockTokenTTL = constants.DefaultUnlockTokenTTL
+	}
+	if opts.UnlockMaxAttempts <= 0 {
+		opts.UnlockMaxAttempts = constants.DefaultUnlockMaxAttempts
+	}
+	if opts.UnlockAttemptWindow <= 0 {
+		opts.UnlockAttemptWindow = constants.DefaultUnlockWindow
+	}
+	if opts.BatchMaxSize <= 0 {
+		opts.BatchMaxSize = constants.DefaultBatchMaxSize
+	}
+	if opts.BatchConcurrency <= 0 {
+		opts.BatchConcurrency = constants.DefaultBatchConcurrency
+	}
+	if opts.CodeGenerator == nil {
+		opts.CodeGenerator = codegen.NewRandom(constants.DefaultShortCodeLength)
+	}
+
 	return &urlServiceImpl{
-		repo:      repo,
-		validator: validator.NewURLValidator(),
+		repo:             repo,
+		history:          history,
+		clicks:           clicks,
+		counter:          counter,
+		validator:        validator.NewURLValidator(),
+		codes:            opts.CodeGenerator,
+		keys:             opts.Keys,
+		tokens:           unlock.NewSigner(opts.UnlockTokenSecret, opts.UnlockTokenTTL),
+		attempts:         unlock.NewLimiter(opts.UnlockMaxAttempts, opts.UnlockAttemptWindow),
+		hideNotYetActive: opts.HideNotYetActive,
+		batchMaxSize:     opts.BatchMaxSize,
+		batchConcurrency: opts.BatchConcurrency,
+		now:              time.Now,
 	}
 }
 
@@ -36,63 +166,243 @@ func (s *urlServiceImpl) CreateShortURL(ctx context.Context, req *dto.CreateURLR
 		return nil, err
 	}
 
-	var shortCode string
-	if req.CustomCode != nil {
-		if err := s.validator.ValidateShortCode(*req.CustomCode); err != nil {
+	if req.RedirectType != nil {
+		if err := s.validator.ValidateRedirectType(*req.RedirectType); err != nil {
 			return nil, err
 		}
-		
-		exists, err := s.repo.ExistsByShortCode(ctx, *req.CustomCode)
-		if err != nil {
-			return nil, errors.NewInternalError("service.CreateShortURL", "failed to check code existence", err)
-		}
-		if exists {
-			return nil, errors.NewDuplicateError("service.CreateShortURL", "short code already exists")
+	}
+
+	expiresAt, err := s.resolveExpiry("service.CreateShortURL", req.ExpiresAt, req.TTLSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateClickLimit("service.CreateShortURL", req.MaxClicks, req.FallbackURL); err != nil {
+		return nil, err
+	}
+	if req.FallbackURL != nil && *req.FallbackURL != "" && (req.MaxClicks == nil || *req.MaxClicks == 0) {
+		return nil, errors.NewValidationError("service.CreateShortURL", "fallbackUrl requires maxClicks", nil)
+	}
+
+	if err := s.validateWindow("service.CreateShortURL", req.ActiveFrom, req.ActiveUntil, req.ActiveUntil != nil); err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidateMetadata(req.Metadata); err != nil {
+		return nil, err
+	}
+
+	var passwordHash string
+	if req.Password != nil {
+		if passwordHash, err = hashPassword("service.CreateShortURL", *req.Password); err != nil {
+			return nil, err
 		}
-		shortCode = *req.CustomCode
-	} else {
-		var err error
-		shortCode, err = s.generateUniqueShortCode(ctx)
-		if err != nil {
-			return nil, errors.NewInternalError("service.CreateShortURL", "failed to generate short code", err)
+	}
+
+	if req.CustomCode != nil {
+		if err := s.validator.ValidateShortCode(*req.CustomCode); err != nil {
+			return nil, err
 		}
 	}
 
 	shortURL := &models.ShortURL{
-		URL:         req.URL,
-		ShortCode:   shortCode,
-		AccessCount: 0,
+		URL:          req.URL,
+		AccessCount:  0,
+		ExpiresAt:    expiresAt,
+		PasswordHash: passwordHash,
+		ActiveFrom:   utcTime(req.ActiveFrom),
+		ActiveUntil:  utcTime(req.ActiveUntil),
+		Metadata:     models.CloneMetadata(req.Metadata),
+	}
+	if req.RedirectType != nil {
+		shortURL.RedirectType = *req.RedirectType
+	}
+	if req.MaxClicks != nil {
+		shortURL.MaxClicks = *req.MaxClicks
+	}
+	if req.FallbackURL != nil {
+		shortURL.FallbackURL = *req.FallbackURL
 	}
 
-	if err := s.repo.Create(ctx, shortURL); err != nil {
+	if req.CustomCode != nil {
+		// The storage rejects a taken code, so there is nothing to look up
+		// first.
+		shortURL.ShortCode = *req.CustomCode
+		err = s.repo.Create(ctx, shortURL)
+	} else {
+		err = s.createWithGeneratedCode(ctx, shortURL)
+	}
+	if err != nil {
 		return nil, err
 	}
+	s.appendHistory(ctx, &models.DestinationChange{
+		URLID:     shortURL.ID,
+		ShortCode: shortURL.ShortCode,
+		NewURL:    shortURL.URL,
+		Actor:     actorFrom(ctx),
+		ChangedAt: shortURL.Created,
+	})
 
 	return &dto.CreateURLResponse{
-		ID:        shortURL.ID,
-		URL:       shortURL.URL,
-		ShortCode: shortURL.ShortCode,
-		CreatedAt: shortURL.Created,
-		UpdatedAt: shortURL.Updated,
+		ID:                shortURL.ID,
+		URL:               shortURL.URL,
+		ShortCode:         shortURL.ShortCode,
+		RedirectType:      shortURL.RedirectType,
+		CreatedAt:         shortURL.Created,
+		UpdatedAt:         shortURL.Updated,
+		ExpiresAt:         shortURL.ExpiresAt,
+		MaxClicks:         shortURL.MaxClicks,
+		FallbackURL:       shortURL.FallbackURL,
+		PasswordProtected: shortURL.Protected(),
+		ActiveFrom:        shortURL.ActiveFrom,
+		ActiveUntil:       shortURL.ActiveUntil,
+		Metadata:          shortURL.Metadata,
 	}, nil
 }
 
 func (s *urlServiceImpl) GetOriginalURL(ctx context.Context, shortCode string) (*dto.GetURLResponse, error) {
+	shortURL, err := s.lookupResolvable(ctx, "service.GetOriginalURL", shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if shortURL.Protected() {
+		return nil, errors.NewUnauthorizedError("service.GetOriginalURL", "short URL is password protected")
+	}
+	return s.resolve(ctx, shortURL)
+}
+
+func (s *urlServiceImpl) UnlockURL(ctx context.Context, shortCode, client string, req *dto.UnlockURLRequest) (*dto.UnlockURLResponse, error) {
+	const op = "service.UnlockURL"
+
+	shortURL, err := s.lookupResolvable(ctx, op, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !shortURL.Protected() {
+		resp, err := s.resolve(ctx, shortURL)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.UnlockURLResponse{GetURLResponse: *resp}, nil
+	}
+
+	// The attempt is counted before the slow compare, so parallel guesses
+	// cannot all get past the limit.
+	key := shortCode + "|" + client
+	if ok, retryAfter := s.attempts.Take(key); !ok {
+		return nil, errors.NewTooManyRequestsError(op, fmt.Sprintf("too many failed attempts, try again in %s", retryAfter.Round(time.Second)))
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(shortURL.PasswordHash), []byte(req.Password)); err != nil {
+		if !stderrors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			s.attempts.Refund(key)
+			return nil, errors.NewInternalError(op, "failed to verify password", err)
+		}
+		return nil, errors.NewUnauthorizedError(op, "incorrect password")
+	}
+	s.attempts.Reset(key)
+
+	resp, err := s.resolve(ctx, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	token, expiresAt := s.tokens.Issue(shortCode, shortURL.PasswordHash)
+	return &dto.UnlockURLResponse{GetURLResponse: *resp, AccessToken: token, AccessTokenExpiresAt: &expiresAt}, nil
+}
+
+func (s *urlServiceImpl) GetUnlockedURL(ctx context.Context, shortCode, accessToken string) (*dto.GetURLResponse, error) {
+	shortURL, err := s.lookupResolvable(ctx, "service.GetUnlockedURL", shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if shortURL.Protected() && !s.tokens.Verify(accessToken, shortCode, shortURL.PasswordHash) {
+		return nil, errors.NewUnauthorizedError("service.GetUnlockedURL", "access token is invalid or expired")
+	}
+	return s.resolve(ctx, shortURL)
+}
+
+// lookup fetches a short URL that is not in the trash.
+func (s *urlServiceImpl) lookup(ctx context.Context, op, shortCode string) (*models.ShortURL, error) {
 	shortURL, err := s.repo.GetByShortCode(ctx, shortCode)
 	if err != nil {
 		return nil, err
 	}
+	if shortURL.Trashed() {
+		return nil, errors.NewNotFoundError(op, "short URL not found")
+	}
+	return shortURL, nil
+}
 
-	if err := s.repo.IncrementAccessCount(ctx, shortCode); err != nil {
-		return nil, errors.NewInternalError("service.GetOriginalURL", "failed to increment access count", err)
+// lookupResolvable fetches a short URL that may be resolved now.
+func (s *urlServiceImpl) lookupResolvable(ctx context.Context, op, shortCode string) (*models.ShortURL, error) {
+	shortURL, err := s.lookup(ctx, op, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	switch {
+	case shortURL.Disabled && shortURL.DisabledLegal:
+		return nil, errors.NewUnavailableForLegalReasonsError(op, "short URL is unavailable for legal reasons")
+	case shortURL.Disabled:
+		return nil, errors.NewNotFoundError(op, "short URL is disabled")
+	case shortURL.Expired(now):
+		return nil, errors.NewGoneError(op, "short URL has expired")
+	case shortURL.WindowClosed(now):
+		return nil, errors.NewGoneError(op, "short URL is no longer active")
+	case shortURL.NotYetActive(now) && s.hideNotYetActive:
+		return nil, errors.NewNotFoundError(op, "short URL not found")
+	case shortURL.NotYetActive(now):
+		return nil, errors.NewNotYetActiveError(op, "short URL is not active yet")
+	}
+	return shortURL, nil
+}
+
+// resolve counts one access to shortURL and returns where it redirects.
+func (s *urlServiceImpl) resolve(ctx context.Context, shortURL *models.ShortURL) (*dto.GetURLResponse, error) {
+	shortCode := shortURL.ShortCode
+
+	// Click-limited links are counted synchronously and atomically; all
+	// others go through the asynchronous click pipeline.
+	if shortURL.ClickLimited() {
+		consumed, err := s.repo.ConsumeClick(ctx, shortCode)
+		var serviceErr *errors.ServiceError
+		switch {
+		case err == nil:
+			shortURL = consumed
+		case stderrors.As(err, &serviceErr) && serviceErr.Code == errors.ErrorCodeGone && shortURL.FallbackURL != "":
+			return &dto.GetURLResponse{
+				ID:                shortURL.ID,
+				URL:               shortURL.FallbackURL,
+				ShortCode:         shortURL.ShortCode,
+				AccessCount:       shortURL.AccessCount,
+				RedirectType:      http.StatusFound,
+				CreatedAt:         shortURL.Created,
+				UpdatedAt:         shortURL.Updated,
+				ExpiresAt:         shortURL.ExpiresAt,
+				MaxClicks:         shortURL.MaxClicks,
+				FallbackURL:       shortURL.FallbackURL,
+				PasswordProtected: shortURL.Protected(),
+				ActiveFrom:        shortURL.ActiveFrom,
+				ActiveUntil:       shortURL.ActiveUntil,
+			}, nil
+		default:
+			return nil, err
+		}
+	} else {
+		s.clicks.RecordClick(ctx, shortCode)
 	}
 
 	return &dto.GetURLResponse{
-		ID:        shortURL.ID,
-		URL:       shortURL.URL,
-		ShortCode: shortURL.ShortCode,
-		CreatedAt: shortURL.Created,
-		UpdatedAt: shortURL.Updated,
+		ID:                shortURL.ID,
+		URL:               shortURL.URL,
+		ShortCode:         shortURL.ShortCode,
+		RedirectType:      shortURL.RedirectType,
+		CreatedAt:         shortURL.Created,
+		UpdatedAt:         shortURL.Updated,
+		ExpiresAt:         shortURL.ExpiresAt,
+		MaxClicks:         shortURL.MaxClicks,
+		FallbackURL:       shortURL.FallbackURL,
+		PasswordProtected: shortURL.Protected(),
+		ActiveFrom:        shortURL.ActiveFrom,
+		ActiveUntil:       shortURL.ActiveUntil,
 	}, nil
 }
 
@@ -101,54 +411,589 @@ func (s *urlServiceImpl) UpdateShortURL(ctx context.Context, shortCode string, r
 		return nil, err
 	}
 
-	updatedURL, err := s.repo.Update(ctx, shortCode, req.URL)
+	if req.RedirectType != nil {
+		if err := s.validator.ValidateRedirectType(*req.RedirectType); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.validateClickLimit("service.UpdateShortURL", req.MaxClicks, req.FallbackURL); err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidateMetadata(req.Metadata); err != nil {
+		return nil, err
+	}
+	current, err := s.lookup(ctx, "service.UpdateShortURL", shortCode)
 	if err != nil {
 		return nil, err
 	}
 
-	return &dto.UpdateURLResponse{
-		ID:          updatedURL.ID,
-		URL:         updatedURL.URL,
-		ShortCode:   updatedURL.ShortCode,
-		AccessCount: updatedURL.AccessCount,
-		CreatedAt:   updatedURL.Created,
-		UpdatedAt:   updatedURL.Updated,
-	}, nil
+	update := &models.ShortURLUpdate{
+		URL:          &req.URL,
+		RedirectType: req.RedirectType,
+		MaxClicks:    req.MaxClicks,
+		FallbackURL:  req.FallbackURL,
+		Metadata:     req.Metadata,
+	}
+	if err := s.applyWindowUpdate(current, req, update); err != nil {
+		return nil, err
+	}
+	if req.Password != nil {
+		passwordHash, err := hashPassword("service.UpdateShortURL", *req.Password)
+		if err != nil {
+			return nil, err
+		}
+		update.PasswordHash = &passwordHash
+	}
+	if req.ClearExpiry {
+		if req.ExpiresAt != nil || req.TTLSeconds != nil {
+			return nil, errors.NewValidationError("service.UpdateShortURL", "clearExpiry cannot be combined with expiresAt or ttlSeconds", nil)
+		}
+		update.ClearExpiresAt = true
+	} else {
+		var err error
+		if update.ExpiresAt, err = s.resolveExpiry("service.UpdateShortURL", req.ExpiresAt, req.TTLSeconds); err != nil {
+			return nil, err
+		}
+	}
+
+	updatedURL, err := s.repo.Update(ctx, shortCode, update)
+	if err != nil {
+		return nil, err
+	}
+	s.recordDestinationChange(ctx, current, updatedURL)
+
+	return updateResponse(updatedURL), nil
+}
+
+func updateResponse(shortURL *models.ShortURL) *dto.UpdateURLResponse {
+	resp := &dto.UpdateURLResponse{
+		ID:                shortURL.ID,
+		URL:               shortURL.URL,
+		ShortCode:         shortURL.ShortCode,
+		AccessCount:       shortURL.AccessCount,
+		RedirectType:      shortURL.RedirectType,
+		CreatedAt:         shortURL.Created,
+		UpdatedAt:         shortURL.Updated,
+		ExpiresAt:         shortURL.ExpiresAt,
+		MaxClicks:         shortURL.MaxClicks,
+		FallbackURL:       shortURL.FallbackURL,
+		PasswordProtected: shortURL.Protected(),
+		ActiveFrom:        shortURL.ActiveFrom,
+		ActiveUntil:       shortURL.ActiveUntil,
+		Metadata:          shortURL.Metadata,
+	}
+	// A rollback would otherwise reveal a protected link's destination.
+	if shortURL.Protected() {
+		resp.URL, resp.FallbackURL = "", ""
+	}
+	return resp
+}
+
+func (s *urlServiceImpl) GetHistory(ctx context.Context, shortCode string) (*dto.HistoryResponse, error) {
+	shortURL, err := s.lookup(ctx, "service.GetHistory", shortCode)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := s.versions(ctx, "service.GetHistory", shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.HistoryResponse{
+		ShortCode: shortURL.ShortCode,
+		Versions:  make([]dto.DestinationVersion, 0, len(changes)),
+	}
+	for _, change := range changes {
+		version := dto.DestinationVersion{
+			Version:   change.Version,
+			OldURL:    change.OldURL,
+			NewURL:    change.NewURL,
+			Actor:     change.Actor,
+			ChangedAt: change.ChangedAt,
+		}
+		// Past destinations of a protected link are as private as the
+		// current one.
+		if shortURL.Protected() {
+			version.OldURL, version.NewURL = "", ""
+		}
+		response.Versions = append(response.Versions, version)
+	}
+	return response, nil
+}
+
+func (s *urlServiceImpl) RollbackURL(ctx context.Context, shortCode string, version int) (*dto.UpdateURLResponse, error) {
+	current, err := s.lookup(ctx, "service.RollbackURL", shortCode)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := s.versions(ctx, "service.RollbackURL", current)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *models.DestinationChange
+	for _, change := range changes {
+		if change.Version == version {
+			target = change
+			break
+		}
+	}
+	if target == nil {
+		return nil, errors.NewNotFoundError("service.RollbackURL", "history version not found")
+	}
+	// The destination passed validation when it was set, but the rules may
+	// have tightened since.
+	if err := s.validator.ValidateURL(target.NewURL); err != nil {
+		return nil, err
+	}
+	if target.NewURL == current.URL {
+		return updateResponse(current), nil
+	}
+
+	updatedURL, err := s.repo.Update(ctx, shortCode, &models.ShortURLUpdate{URL: &target.NewURL})
+	if err != nil {
+		return nil, err
+	}
+	s.recordDestinationChange(ctx, current, updatedURL)
+
+	return updateResponse(updatedURL), nil
+}
+
+// versions returns the destination history of shortURL. Links created before
+// history was recorded have none stored, so their current destination is
+// reported as version 1.
+func (s *urlServiceImpl) versions(ctx context.Context, op string, shortURL *models.ShortURL) ([]*models.DestinationChange, error) {
+	changes, err := s.history.List(ctx, shortURL.ID)
+	if err != nil {
+		return nil, errors.NewInternalError(op, "failed to load destination history", err)
+	}
+	if len(changes) == 0 {
+		changes = []*models.DestinationChange{baselineChange(shortURL)}
+	}
+	return changes, nil
+}
+
+func baselineChange(shortURL *models.ShortURL) *models.DestinationChange {
+	return &models.DestinationChange{
+		URLID:     shortURL.ID,
+		ShortCode: shortURL.ShortCode,
+		Version:   1,
+		NewURL:    shortURL.URL,
+		ChangedAt: shortURL.Created,
+	}
+}
+
+// recordDestinationChange appends a history version when an update moved the
+// link from before's destination to after's.
+func (s *urlServiceImpl) recordDestinationChange(ctx context.Context, before, after *models.ShortURL) {
+	if before.URL == after.URL {
+		return
+	}
+
+	changes, err := s.history.List(ctx, before.ID)
+	if err != nil {
+		log.Warn().Err(err).Str("short_code", before.ShortCode).Msg("Failed to record destination change")
+		return
+	}
+	if len(changes) == 0 {
+		// Keep the destination the link had before history was recorded so
+		// it can be rolled back to.
+		s.appendHistory(ctx, baselineChange(before))
+	}
+
+	s.appendHistory(ctx, &models.DestinationChange{
+		URLID:     after.ID,
+		ShortCode: after.ShortCode,
+		OldURL:    before.URL,
+		NewURL:    after.URL,
+		Actor:     actorFrom(ctx),
+		ChangedAt: s.now().UTC(),
+	})
+}
+
+// appendHistory stores change. The destination has already changed by the
+// time it is called, so a failure is logged rather than failing the request.
+func (s *urlServiceImpl) appendHistory(ctx context.Context, change *models.DestinationChange) {
+	if err := s.history.Append(ctx, change); err != nil {
+		log.Warn().Err(err).Str("short_code", change.ShortCode).Msg("Failed to record destination change")
+	}
 }
 
 func (s *urlServiceImpl) DeleteShortURL(ctx context.Context, shortCode string) error {
-	return s.repo.Delete(ctx, shortCode)
+	if _, err := s.lookup(ctx, "service.DeleteShortURL", shortCode); err != nil {
+		return err
+	}
+	deletedAt := s.now().UTC()
+	_, err := s.repo.Update(ctx, shortCode, &models.ShortURLUpdate{DeletedAt: &deletedAt})
+	return err
 }
 
-func (s *urlServiceImpl) GetStatistics(ctx context.Context, shortCode string) (*dto.GetStatsResponse, error) {
+func (s *urlServiceImpl) RestoreURL(ctx context.Context, shortCode string) (*dto.GetStatsResponse, error) {
 	shortURL, err := s.repo.GetByShortCode(ctx, shortCode)
 	if err != nil {
 		return nil, err
 	}
+	if !shortURL.Trashed() {
+		return nil, errors.NewNotFoundError("service.RestoreURL", "short URL is not in the trash")
+	}
 
-	return &dto.GetStatsResponse{
-		ID:          shortURL.ID,
-		URL:         shortURL.URL,
-		ShortCode:   shortURL.ShortCode,
-		AccessCount: shortURL.AccessCount,
-		CreatedAt:   shortURL.Created,
-		UpdatedAt:   shortURL.Updated,
-	}, nil
+	restored, err := s.repo.Update(ctx, shortCode, &models.ShortURLUpdate{ClearDeletedAt: true})
+	if err != nil {
+		return nil, err
+	}
+	return s.statsResponse(restored), nil
 }
 
-func (s *urlServiceImpl) generateUniqueShortCode(ctx context.Context) (string, error) {
-	for i := 0; i < 10; i++ {
-		code, err := utils.GenerateShortCode(6)
+func (s *urlServiceImpl) GetStatistics(ctx context.Context, shortCode string) (*dto.GetStatsResponse, error) {
+	shortURL, err := s.lookup(ctx, "service.GetStatistics", shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.statsResponse(shortURL), nil
+}
+
+// statsResponse describes shortURL for management views, including accesses
+// not yet flushed to storage.
+func (s *urlServiceImpl) statsResponse(shortURL *models.ShortURL) *dto.GetStatsResponse {
+	resp := &dto.GetStatsResponse{
+		ID:                shortURL.ID,
+		URL:               shortURL.URL,
+		ShortCode:         shortURL.ShortCode,
+		AccessCount:       shortURL.AccessCount + s.counter.Pending(shortURL.ShortCode),
+		RedirectType:      shortURL.RedirectType,
+		CreatedAt:         shortURL.Created,
+		UpdatedAt:         shortURL.Updated,
+		ExpiresAt:         shortURL.ExpiresAt,
+		MaxClicks:         shortURL.MaxClicks,
+		FallbackURL:       shortURL.FallbackURL,
+		PasswordProtected: shortURL.Protected(),
+		ActiveFrom:        shortURL.ActiveFrom,
+		ActiveUntil:       shortURL.ActiveUntil,
+		Enabled:           !shortURL.Disabled,
+		DisabledReason:    shortURL.DisabledReason,
+		DisabledLegal:     shortURL.DisabledLegal,
+		DeletedAt:         shortURL.DeletedAt,
+		Metadata:          shortURL.Metadata,
+	}
+	// Only unlocking reveals where a protected link leads.
+	if shortURL.Protected() {
+		resp.URL, resp.FallbackURL = "", ""
+	}
+	return resp
+}
+
+func (s *urlServiceImpl) DisableURL(ctx context.Context, shortCode string, req *dto.DisableURLRequest) (*dto.GetStatsResponse, error) {
+	if len(req.Reason) > constants.MaxDisabledReasonLength {
+		return nil, errors.NewValidationError("service.DisableURL", fmt.Sprintf("reason must be at most %d bytes", constants.MaxDisabledReasonLength), nil)
+	}
+	if _, err := s.lookup(ctx, "service.DisableURL", shortCode); err != nil {
+		return nil, err
+	}
+
+	disabled := true
+	shortURL, err := s.repo.Update(ctx, shortCode, &models.ShortURLUpdate{
+		Disabled:       &disabled,
+		DisabledReason: &req.Reason,
+		DisabledLegal:  &req.Legal,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.statsResponse(shortURL), nil
+}
+
+func (s *urlServiceImpl) EnableURL(ctx context.Context, shortCode string) (*dto.GetStatsResponse, error) {
+	if _, err := s.lookup(ctx, "service.EnableURL", shortCode); err != nil {
+		return nil, err
+	}
+	disabled, reason, legal := false, "", false
+	shortURL, err := s.repo.Update(ctx, shortCode, &models.ShortURLUpdate{
+		Disabled:       &disabled,
+		DisabledReason: &reason,
+		DisabledLegal:  &legal,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.statsResponse(shortURL), nil
+}
+
+// resolveExpiry turns the absolute or relative expiry of a request into a
+// timestamp. It returns nil when neither is set.
+func (s *urlServiceImpl) resolveExpiry(op string, expiresAt *time.Time, ttlSeconds *int64) (*time.Time, error) {
+	now := s.now()
+	switch {
+	case expiresAt != nil && ttlSeconds != nil:
+		return nil, errors.NewValidationError(op, "expiresAt and ttlSeconds are mutually exclusive", nil)
+	case ttlSeconds != nil:
+		if *ttlSeconds <= 0 || *ttlSeconds > constants.MaxTTLSeconds {
+			return nil, errors.NewValidationError(op, fmt.Sprintf("ttlSeconds must be between 1 and %d", constants.MaxTTLSeconds), nil)
+		}
+		resolved := now.Add(time.Duration(*ttlSeconds) * time.Second).UTC()
+		return &resolved, nil
+	case expiresAt != nil:
+		if !expiresAt.After(now) {
+			return nil, errors.NewValidationError(op, "expiresAt must be in the future", nil)
+		}
+		resolved := expiresAt.UTC()
+		return &resolved, nil
+	}
+	return nil, nil
+}
+
+func (s *urlServiceImpl) validateClickLimit(op string, maxClicks *int64, fallbackURL *string) error {
+	if maxClicks != nil && *maxClicks < 0 {
+		return errors.NewValidationError(op, "maxClicks must not be negative", nil)
+	}
+	if fallbackURL != nil && *fallbackURL != "" {
+		if err := s.validator.ValidateURL(*fallbackURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateWindow checks an activation window. A newly set activeUntil must
+// lie in the future, so links cannot be scheduled already closed.
+func (s *urlServiceImpl) validateWindow(op string, activeFrom, activeUntil *time.Time, untilChanged bool) error {
+	if untilChanged && activeUntil != nil && !activeUntil.After(s.now()) {
+		return errors.NewValidationError(op, "activeUntil must be in the future", nil)
+	}
+	if activeFrom != nil && activeUntil != nil && !activeUntil.After(*activeFrom) {
+		return errors.NewValidationError(op, "activeUntil must be after activeFrom", nil)
+	}
+	return nil
+}
+
+// applyWindowUpdate copies the window changes of req into update. The
+// resulting window is validated against current, the link being updated.
+func (s *urlServiceImpl) applyWindowUpdate(current *models.ShortURL, req *dto.UpdateURLRequest, update *models.ShortURLUpdate) error {
+	const op = "service.UpdateShortURL"
+
+	if req.ClearActiveFrom && req.ActiveFrom != nil {
+		return errors.NewValidationError(op, "clearActiveFrom cannot be combined with activeFrom", nil)
+	}
+	if req.ClearActiveUntil && req.ActiveUntil != nil {
+		return errors.NewValidationError(op, "clearActiveUntil cannot be combined with activeUntil", nil)
+	}
+	if req.ActiveFrom == nil && req.ActiveUntil == nil && !req.ClearActiveFrom && !req.ClearActiveUntil {
+		return nil
+	}
+
+	activeFrom, activeUntil := current.ActiveFrom, current.ActiveUntil
+	switch {
+	case req.ClearActiveFrom:
+		activeFrom = nil
+	case req.ActiveFrom != nil:
+		activeFrom = req.ActiveFrom
+	}
+	switch {
+	case req.ClearActiveUntil:
+		activeUntil = nil
+	case req.ActiveUntil != nil:
+		activeUntil = req.ActiveUntil
+	}
+	if err := s.validateWindow(op, activeFrom, activeUntil, req.ActiveUntil != nil); err != nil {
+		return err
+	}
+
+	update.ActiveFrom, update.ClearActiveFrom = utcTime(req.ActiveFrom), req.ClearActiveFrom
+	update.ActiveUntil, update.ClearActiveUntil = utcTime(req.ActiveUntil), req.ClearActiveUntil
+	return nil
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// hashPassword validates and hashes a link password. An empty password
+// hashes to "", which leaves or makes the link unprotected.
+func hashPassword(op, password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) < constants.MinPasswordLength || len(password) > constants.MaxPasswordLength {
+		return "", errors.NewValidationError(op, fmt.Sprintf("password must be between %d and %d bytes", constants.MinPasswordLength, constants.MaxPasswordLength), nil)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.NewInternalError(op, "failed to hash password", err)
+	}
+	return string(hash), nil
+}
+
+// listSortFields maps the sort names accepted by the API to model fields.
+var listSortFields = map[string]models.SortField{
+	"created":     models.SortByCreated,
+	"updated":     models.SortByUpdated,
+	"accessCount": models.SortByAccessCount,
+	"shortCode":   models.SortByShortCode,
+}
+
+// listCursor is the decoded form of the opaque nextCursor token. It records
+// the sort it was issued for so it cannot be replayed against another one.
+type listCursor struct {
+	Sort        string    `json:"s"`
+	ShortCode   string    `json:"c"`
+	Created     time.Time `json:"cr"`
+	Updated     time.Time `json:"up"`
+	AccessCount int64     `json:"n"`
+}
+
+func (s *urlServiceImpl) ListURLs(ctx context.Context, req *dto.ListURLsRequest) (*dto.ListURLsResponse, error) {
+	return s.list(ctx, "service.ListURLs", req, false)
+}
+
+func (s *urlServiceImpl) ListTrash(ctx context.Context, req *dto.ListURLsRequest) (*dto.ListURLsResponse, error) {
+	return s.list(ctx, "service.ListTrash", req, true)
+}
+
+func (s *urlServiceImpl) list(ctx context.Context, op string, req *dto.ListURLsRequest, trashed bool) (*dto.ListURLsResponse, error) {
+	opts := models.ListOptions{
+		Page:    max(req.Page, 1),
+		PerPage: req.PerPage,
+		Filter: models.ListFilter{
+			URLContains:    req.URLContains,
+			MinAccessCount: req.MinAccessCount,
+			Trashed:        trashed,
+		},
+	}
+	switch {
+	case req.PerPage == 0:
+		opts.PerPage = constants.DefaultPageSize
+	case req.PerPage < 0 || req.PerPage > constants.MaxPageSize:
+		return nil, errors.NewValidationError(op, fmt.Sprintf("perPage must be between 1 and %d", constants.MaxPageSize), nil)
+	}
+	if req.Page < 0 {
+		return nil, errors.NewValidationError(op, "page must be positive", nil)
+	}
+	if req.MinAccessCount < 0 {
+		return nil, errors.NewValidationError(op, "minAccessCount must not be negative", nil)
+	}
+
+	sort := req.Sort
+	if sort == "" {
+		sort = "-created"
+	}
+	field, ok := listSortFields[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return nil, errors.NewValidationError(op, "unsupported sort field", nil)
+	}
+	opts.SortBy, opts.Desc = field, strings.HasPrefix(sort, "-")
+
+	var err error
+	if opts.Filter.CreatedAfter, err = parseListTime(req.CreatedAfter); err != nil {
+		return nil, errors.NewValidationError(op, "createdAfter must be an RFC 3339 timestamp", err)
+	}
+	if opts.Filter.CreatedBefore, err = parseListTime(req.CreatedBefore); err != nil {
+		return nil, errors.NewValidationError(op, "createdBefore must be an RFC 3339 timestamp", err)
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodeListCursor(req.Cursor)
 		if err != nil {
-			return "", errors.NewInternalError("service.generateUniqueShortCode", "failed to generate short code", err)
+			return nil, errors.NewValidationError(op, "invalid cursor", err)
 		}
-		exists, err := s.repo.ExistsByShortCode(ctx, code)
+		if cursor.Sort != sort {
+			return nil, errors.NewValidationError(op, "cursor was issued for a different sort", nil)
+		}
+		opts.After = &models.ListCursor{
+			ShortCode:   cursor.ShortCode,
+			Created:     cursor.Created,
+			Updated:     cursor.Updated,
+			AccessCount: cursor.AccessCount,
+		}
+	}
+
+	result, err := s.repo.List(ctx, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ListURLsResponse{
+		Items:      make([]dto.GetStatsResponse, len(result.Items)),
+		PerPage:    opts.PerPage,
+		TotalItems: result.TotalItems,
+		TotalPages: (result.TotalItems + opts.PerPage - 1) / opts.PerPage,
+	}
+	if opts.After == nil {
+		resp.Page = opts.Page
+	}
+	for i, shortURL := range result.Items {
+		resp.Items[i] = *s.statsResponse(shortURL)
+	}
+
+	// The cursor carries the stored count the repository sorted by, not the
+	// count shown above, which may include pending accesses.
+	if len(result.Items) == opts.PerPage {
+		last := result.Items[len(result.Items)-1]
+		resp.NextCursor = encodeListCursor(&listCursor{
+			Sort:        sort,
+			ShortCode:   last.ShortCode,
+			Created:     last.Created,
+			Updated:     last.Updated,
+			AccessCount: last.AccessCount,
+		})
+	}
+	return resp, nil
+}
+
+func parseListTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func encodeListCursor(cursor *listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(token string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// createWithGeneratedCode creates shortURL under the next free code, trying
+// another one when a link took the code first.
+func (s *urlServiceImpl) createWithGeneratedCode(ctx context.Context, shortURL *models.ShortURL) error {
+	const op = "service.CreateShortURL"
+	for i := 0; i < 10; i++ {
+		code, err := s.nextShortCode(ctx)
 		if err != nil {
-			return "", err
+			return errors.NewInternalError(op, "failed to generate short code", err)
 		}
-		if !exists {
-			return code, nil
+		if slices.Contains(constants.ReservedShortCodes, code) {
+			continue
 		}
+		shortURL.ShortCode = code
+		err = s.repo.Create(ctx, shortURL)
+		if !hasErrorCode(err, errors.ErrorCodeDuplicate) {
+			return err
+		}
+	}
+	return errors.NewInternalError(op, "failed to generate unique code after 10 attempts", nil)
+}
+
+// nextShortCode takes a reserved code from the key source, or generates a
+// candidate when there is none.
+func (s *urlServiceImpl) nextShortCode(ctx context.Context) (string, error) {
+	if s.keys != nil {
+		return s.keys.Take(ctx)
 	}
-	return "", errors.NewInternalError("service.generateUniqueShortCode", "failed to generate unique code after 10 attempts", nil)
+	return s.codes.Generate(ctx)
 }
@@ -0,0 +1,1340 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rowjay/url-shortening-service/internal/clicks"
+	"github.com/rowjay/url-shortening-service/internal/codegen"
+	"github.com/rowjay/url-shortening-service/internal/constants"
+	"github.com/rowjay/url-shortening-service/internal/counter"
+	"github.com/rowjay/url-shortening-service/internal/dto"
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rowjay/url-shortening-service/internal/keypool"
+	"github.com/rowjay/url-shortening-service/internal/models"
+	"github.com/rowjay/url-shortening-service/internal/repository"
+)
+
+func errorCode(err error) serviceErrors.ErrorCode {
+	var serviceErr *serviceErrors.ServiceError
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Code
+	}
+	return 0
+}
+
+// directClicks records clicks straight into the aggregator so tests can
+// assert on counts without waiting for a queue to drain.
+type directClicks struct {
+	*counter.Aggregator
+}
+
+func (d directClicks) RecordClick(ctx context.Context, shortCode string) {
+	d.Increment(shortCode)
+}
+
+func newTestService() URLService {
+	repo := repository.NewMemoryURLRepository()
+	aggregator := counter.NewAggregator(repo, time.Hour)
+	return NewURLService(repo, repository.NewMemoryHistoryRepository(), directClicks{aggregator}, aggregator, Options{})
+}
+
+func stringPtr(s string) *string { return &s }
+
+func intPtr(i int) *int { return &i }
+
+func int64Ptr(i int64) *int64 { return &i }
+
+func TestCreateShortURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      dto.CreateURLRequest
+		wantCode serviceErrors.ErrorCode
+	}{
+		{"Generated code", dto.CreateURLRequest{URL: "https://example.com"}, 0},
+		{"Custom code", dto.CreateURLRequest{URL: "https://example.com", CustomCode: stringPtr("golang")}, 0},
+		{"Redirect type", dto.CreateURLRequest{URL: "https://example.com", RedirectType: intPtr(301)}, 0},
+		{"Invalid scheme", dto.CreateURLRequest{URL: "ftp://example.com"}, serviceErrors.ErrorCodeValidation},
+		{"Blocked domain", dto.CreateURLRequest{URL: "https://malware.com/x"}, serviceErrors.ErrorCodeValidation},
+		{"Short custom code", dto.CreateURLRequest{URL: "https://example.com", CustomCode: stringPtr("ab")}, serviceErrors.ErrorCodeValidation},
+		{"Reserved custom code", dto.CreateURLRequest{URL: "https://example.com", CustomCode: stringPtr("health")}, serviceErrors.ErrorCodeValidation},
+		{"Invalid redirect type", dto.CreateURLRequest{URL: "https://example.com", RedirectType: intPtr(303)}, serviceErrors.ErrorCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService()
+
+			got, err := svc.CreateShortURL(context.Background(), &tt.req)
+			if errorCode(err) != tt.wantCode {
+				t.Fatalf("CreateShortURL() error = %v, want code %v", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+
+			if got.URL != tt.req.URL || got.ShortCode == "" || got.ID == "" {
+				t.Errorf("CreateShortURL() = %+v, missing fields", got)
+			}
+			if tt.req.CustomCode != nil && got.ShortCode != *tt.req.CustomCode {
+				t.Errorf("CreateShortURL() ShortCode = %v, want %v", got.ShortCode, *tt.req.CustomCode)
+			}
+			if tt.req.RedirectType != nil && got.RedirectType != *tt.req.RedirectType {
+				t.Errorf("CreateShortURL() RedirectType = %v, want %v", got.RedirectType, *tt.req.RedirectType)
+			}
+		})
+	}
+}
+
+func TestCreateShortURLDuplicateCustomCode(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	req := &dto.CreateURLRequest{URL: "https://example.com", CustomCode: stringPtr("golang")}
+	if _, err := svc.CreateShortURL(ctx, req); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	if _, err := svc.CreateShortURL(ctx, req); errorCode(err) != serviceErrors.ErrorCodeDuplicate {
+		t.Errorf("CreateShortURL() duplicate error = %v, want duplicate", err)
+	}
+}
+
+func TestGetOriginalURLCountsAccess(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	created, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.GetOriginalURL(ctx, created.ShortCode); err != nil {
+			t.Fatalf("GetOriginalURL() error = %v", err)
+		}
+	}
+
+	stats, err := svc.GetStatistics(ctx, created.ShortCode)
+	if err != nil {
+		t.Fatalf("GetStatistics() error = %v", err)
+	}
+	if stats.AccessCount != 3 {
+		t.Errorf("GetStatistics() AccessCount = %d, want 3", stats.AccessCount)
+	}
+
+	if _, err := svc.GetOriginalURL(ctx, "missing"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("GetOriginalURL() unknown code error = %v, want not found", err)
+	}
+}
+
+func TestUpdateAndDeleteShortURL(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	created, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+
+	if _, err := svc.UpdateShortURL(ctx, created.ShortCode, &dto.UpdateURLRequest{URL: "javascript:alert(1)"}); errorCode(err) != serviceErrors.ErrorCodeValidation {
+		t.Errorf("UpdateShortURL() invalid URL error = %v, want validation", err)
+	}
+
+	updated, err := svc.UpdateShortURL(ctx, created.ShortCode, &dto.UpdateURLRequest{URL: "https://example.org", RedirectType: intPtr(308)})
+	if err != nil {
+		t.Fatalf("UpdateShortURL() error = %v", err)
+	}
+	if updated.URL != "https://example.org" || updated.RedirectType != 308 {
+		t.Errorf("UpdateShortURL() = %+v", updated)
+	}
+
+	if err := svc.DeleteShortURL(ctx, created.ShortCode); err != nil {
+		t.Fatalf("DeleteShortURL() error = %v", err)
+	}
+	if _, err := svc.GetStatistics(ctx, created.ShortCode); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("GetStatistics() after delete error = %v, want not found", err)
+	}
+}
+
+func TestGetOriginalURLConcurrentAccessIsExact(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository()
+	accessCounter := counter.NewAggregator(repo, time.Millisecond)
+	accessCounter.Start()
+	clickQueue := clicks.NewQueue(accessCounter, clicks.Options{QueueSize: 64, Workers: 4, Overflow: clicks.OverflowBlock})
+	clickQueue.Start()
+	svc := NewURLService(repo, repository.NewMemoryHistoryRepository(), clickQueue, accessCounter, Options{})
+
+	created, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+
+	const resolves = 5000
+	var wg sync.WaitGroup
+	for i := 0; i < resolves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.GetOriginalURL(ctx, created.ShortCode); err != nil {
+				t.Errorf("GetOriginalURL() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := clickQueue.Stop(ctx); err != nil {
+		t.Fatalf("clickQueue.Stop() error = %v", err)
+	}
+	if stats := clickQueue.Stats(); stats.Dropped != 0 || stats.Processed != resolves {
+		t.Errorf("click queue stats = %+v, want %d processed and none dropped", stats, resolves)
+	}
+	if err := accessCounter.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	stored, err := repo.GetByShortCode(ctx, created.ShortCode)
+	if err != nil {
+		t.Fatalf("GetByShortCode() error = %v", err)
+	}
+	if stored.AccessCount != resolves {
+		t.Errorf("persisted AccessCount = %d, want %d", stored.AccessCount, resolves)
+	}
+}
+
+func TestListURLs(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	for _, code := range []string{"alpha1", "bravo2", "charlie3", "delta4", "echo5"} {
+		if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/" + code, CustomCode: stringPtr(code)}); err != nil {
+			t.Fatalf("CreateShortURL(%s) error = %v", code, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		svc.GetOriginalURL(ctx, "delta4")
+	}
+
+	first, err := svc.ListURLs(ctx, &dto.ListURLsRequest{Sort: "shortCode", PerPage: 2})
+	if err != nil {
+		t.Fatalf("ListURLs() error = %v", err)
+	}
+	if first.TotalItems != 5 || first.TotalPages != 3 || first.Page != 1 || len(first.Items) != 2 {
+		t.Fatalf("ListURLs() = %+v, want 2 of 5 items on page 1 of 3", first)
+	}
+	if first.NextCursor == "" {
+		t.Fatal("ListURLs() NextCursor is empty on a full page")
+	}
+
+	var codes []string
+	cursor := first.NextCursor
+	for cursor != "" {
+		page, err := svc.ListURLs(ctx, &dto.ListURLsRequest{Sort: "shortCode", PerPage: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListURLs() with cursor error = %v", err)
+		}
+		for _, item := range page.Items {
+			codes = append(codes, item.ShortCode)
+			if item.ShortCode == "delta4" && item.AccessCount != 2 {
+				t.Errorf("delta4 AccessCount = %d, want 2 including pending accesses", item.AccessCount)
+			}
+		}
+		cursor = page.NextCursor
+	}
+	if want := []string{"charlie3", "delta4", "echo5"}; !slices.Equal(codes, want) {
+		t.Errorf("cursor pages = %v, want %v", codes, want)
+	}
+
+	filtered, err := svc.ListURLs(ctx, &dto.ListURLsRequest{URLContains: "ECHO", CreatedAfter: time.Now().Add(-time.Hour).Format(time.RFC3339)})
+	if err != nil {
+		t.Fatalf("ListURLs() filtered error = %v", err)
+	}
+	if filtered.TotalItems != 1 || filtered.Items[0].ShortCode != "echo5" || filtered.NextCursor != "" {
+		t.Errorf("ListURLs() filtered = %+v, want only echo5", filtered)
+	}
+}
+
+func TestListURLsRejectsInvalidParameters(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	page, err := svc.ListURLs(ctx, &dto.ListURLsRequest{Sort: "shortCode", PerPage: 1})
+	if err != nil {
+		t.Fatalf("ListURLs() error = %v", err)
+	}
+	if page.NextCursor != "" {
+		t.Fatalf("ListURLs() on an empty store returned cursor %q", page.NextCursor)
+	}
+	otherSort := encodeListCursor(&listCursor{Sort: "-created", ShortCode: "abc"})
+
+	tests := []struct {
+		name string
+		req  dto.ListURLsRequest
+	}{
+		{"perPage too large", dto.ListURLsRequest{PerPage: 1000}},
+		{"negative page", dto.ListURLsRequest{Page: -1}},
+		{"unknown sort", dto.ListURLsRequest{Sort: "url"}},
+		{"bad timestamp", dto.ListURLsRequest{CreatedBefore: "yesterday"}},
+		{"negative min access count", dto.ListURLsRequest{MinAccessCount: -1}},
+		{"garbage cursor", dto.ListURLsRequest{Cursor: "%%%"}},
+		{"cursor for another sort", dto.ListURLsRequest{Sort: "shortCode", Cursor: otherSort}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.ListURLs(ctx, &tt.req); errorCode(err) != serviceErrors.ErrorCodeValidation {
+				t.Errorf("ListURLs() error = %v, want validation error", err)
+			}
+		})
+	}
+}
+
+func TestShortURLExpiry(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+	now := time.Now()
+	svc.(*urlServiceImpl).now = func() time.Time { return now }
+
+	ttl := int64(60)
+	created, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com", CustomCode: stringPtr("campaign"), TTLSeconds: &ttl})
+	if err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	if want := now.Add(time.Minute); created.ExpiresAt == nil || !created.ExpiresAt.Equal(want) {
+		t.Fatalf("ExpiresAt = %v, want %v", created.ExpiresAt, want)
+	}
+
+	if _, err := svc.GetOriginalURL(ctx, "campaign"); err != nil {
+		t.Errorf("GetOriginalURL() before expiry error = %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := svc.GetOriginalURL(ctx, "campaign"); errorCode(err) != serviceErrors.ErrorCodeGone {
+		t.Errorf("GetOriginalURL() at expiry error = %v, want gone", err)
+	}
+	stats, err := svc.GetStatistics(ctx, "campaign")
+	if err != nil {
+		t.Fatalf("GetStatistics() of expired link error = %v", err)
+	}
+	if stats.AccessCount != 1 {
+		t.Errorf("AccessCount = %d, want 1; expired resolves must not count", stats.AccessCount)
+	}
+
+	updated, err := svc.UpdateShortURL(ctx, "campaign", &dto.UpdateURLRequest{URL: "https://example.com", ClearExpiry: true})
+	if err != nil {
+		t.Fatalf("UpdateShortURL() error = %v", err)
+	}
+	if updated.ExpiresAt != nil {
+		t.Errorf("ExpiresAt after clearing = %v, want nil", updated.ExpiresAt)
+	}
+	if _, err := svc.GetOriginalURL(ctx, "campaign"); err != nil {
+		t.Errorf("GetOriginalURL() after clearing expiry error = %v", err)
+	}
+}
+
+func TestShortURLExpiryValidation(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	zero, tooLong := int64(0), int64(constants.MaxTTLSeconds+1)
+
+	tests := []struct {
+		name string
+		req  dto.CreateURLRequest
+	}{
+		{"expiry in the past", dto.CreateURLRequest{URL: "https://example.com", ExpiresAt: &past}},
+		{"zero ttl", dto.CreateURLRequest{URL: "https://example.com", TTLSeconds: &zero}},
+		{"ttl too long", dto.CreateURLRequest{URL: "https://example.com", TTLSeconds: &tooLong}},
+		{"both set", dto.CreateURLRequest{URL: "https://example.com", ExpiresAt: &future, TTLSeconds: int64Ptr(60)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.CreateShortURL(ctx, &tt.req); errorCode(err) != serviceErrors.ErrorCodeValidation {
+				t.Errorf("CreateShortURL() error = %v, want validation error", err)
+			}
+		})
+	}
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com", CustomCode: stringPtr("keeper")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	_, err := svc.UpdateShortURL(ctx, "keeper", &dto.UpdateURLRequest{URL: "https://example.com", ClearExpiry: true, ExpiresAt: &future})
+	if errorCode(err) != serviceErrors.ErrorCodeValidation {
+		t.Errorf("UpdateShortURL() with clearExpiry and expiresAt error = %v, want validation error", err)
+	}
+}
+
+func TestClickLimitedLinks(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/secret", CustomCode: stringPtr("oneshot"), MaxClicks: int64Ptr(3)}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+
+	const workers = 20
+	var resolved sync.WaitGroup
+	var mu sync.Mutex
+	counts := map[serviceErrors.ErrorCode]int{}
+	for i := 0; i < workers; i++ {
+		resolved.Add(1)
+		go func() {
+			defer resolved.Done()
+			_, err := svc.GetOriginalURL(ctx, "oneshot")
+			mu.Lock()
+			counts[errorCode(err)]++
+			mu.Unlock()
+		}()
+	}
+	resolved.Wait()
+
+	if counts[0] != 3 || counts[serviceErrors.ErrorCodeGone] != workers-3 {
+		t.Errorf("resolves by error code = %v, want 3 successes and %d gone", counts, workers-3)
+	}
+	stats, err := svc.GetStatistics(ctx, "oneshot")
+	if err != nil {
+		t.Fatalf("GetStatistics() error = %v", err)
+	}
+	if stats.AccessCount != 3 {
+		t.Errorf("AccessCount = %d, want 3", stats.AccessCount)
+	}
+
+	fallback := "https://example.com/expired-offer"
+	if _, err := svc.UpdateShortURL(ctx, "oneshot", &dto.UpdateURLRequest{URL: "https://example.com/secret", FallbackURL: &fallback}); err != nil {
+		t.Fatalf("UpdateShortURL() error = %v", err)
+	}
+	resp, err := svc.GetOriginalURL(ctx, "oneshot")
+	if err != nil {
+		t.Fatalf("GetOriginalURL() with fallback error = %v", err)
+	}
+	if resp.URL != fallback || resp.RedirectType != http.StatusFound {
+		t.Errorf("GetOriginalURL() = %s (%d), want fallback %s with 302", resp.URL, resp.RedirectType, fallback)
+	}
+
+	if _, err := svc.UpdateShortURL(ctx, "oneshot", &dto.UpdateURLRequest{URL: "https://example.com/secret", MaxClicks: int64Ptr(0)}); err != nil {
+		t.Fatalf("UpdateShortURL() error = %v", err)
+	}
+	if resp, err := svc.GetOriginalURL(ctx, "oneshot"); err != nil || resp.URL != "https://example.com/secret" {
+		t.Errorf("GetOriginalURL() after removing limit = %+v, %v, want the destination", resp, err)
+	}
+}
+
+func TestClickLimitValidation(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	tests := []struct {
+		name string
+		req  dto.CreateURLRequest
+	}{
+		{"negative limit", dto.CreateURLRequest{URL: "https://example.com", MaxClicks: int64Ptr(-1)}},
+		{"fallback without limit", dto.CreateURLRequest{URL: "https://example.com", FallbackURL: stringPtr("https://example.org")}},
+		{"invalid fallback", dto.CreateURLRequest{URL: "https://example.com", MaxClicks: int64Ptr(1), FallbackURL: stringPtr("javascript:alert(1)")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.CreateShortURL(ctx, &tt.req); errorCode(err) != serviceErrors.ErrorCodeValidation {
+				t.Errorf("CreateShortURL() error = %v, want validation error", err)
+			}
+		})
+	}
+}
+
+func TestPasswordProtectedLinks(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	created, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/internal", CustomCode: stringPtr("intdocs"), Password: stringPtr("hunter22")})
+	if err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	if !created.PasswordProtected {
+		t.Error("CreateShortURL() PasswordProtected = false, want true")
+	}
+
+	if _, err := svc.GetOriginalURL(ctx, "intdocs"); errorCode(err) != serviceErrors.ErrorCodeUnauthorized {
+		t.Errorf("GetOriginalURL() error = %v, want unauthorized", err)
+	}
+	if _, err := svc.UnlockURL(ctx, "intdocs", "client", &dto.UnlockURLRequest{Password: "wrong"}); errorCode(err) != serviceErrors.ErrorCodeUnauthorized {
+		t.Errorf("UnlockURL() with wrong password error = %v, want unauthorized", err)
+	}
+
+	unlocked, err := svc.UnlockURL(ctx, "intdocs", "client", &dto.UnlockURLRequest{Password: "hunter22"})
+	if err != nil {
+		t.Fatalf("UnlockURL() error = %v", err)
+	}
+	if unlocked.URL != "https://example.com/internal" || unlocked.AccessToken == "" {
+		t.Fatalf("UnlockURL() = %+v, want the destination and an access token", unlocked)
+	}
+
+	if resp, err := svc.GetUnlockedURL(ctx, "intdocs", unlocked.AccessToken); err != nil || resp.URL != "https://example.com/internal" {
+		t.Errorf("GetUnlockedURL() = %+v, %v, want the destination", resp, err)
+	}
+	if _, err := svc.GetUnlockedURL(ctx, "intdocs", "bogus"); errorCode(err) != serviceErrors.ErrorCodeUnauthorized {
+		t.Errorf("GetUnlockedURL() with bogus token error = %v, want unauthorized", err)
+	}
+
+	stats, err := svc.GetStatistics(ctx, "intdocs")
+	if err != nil {
+		t.Fatalf("GetStatistics() error = %v", err)
+	}
+	if stats.AccessCount != 2 || !stats.PasswordProtected {
+		t.Errorf("GetStatistics() = %+v, want 2 accesses and protected", stats)
+	}
+
+	// Changing the password revokes issued tokens; clearing it opens the link.
+	if _, err := svc.UpdateShortURL(ctx, "intdocs", &dto.UpdateURLRequest{URL: "https://example.com/internal", Password: stringPtr("correct horse")}); err != nil {
+		t.Fatalf("UpdateShortURL() error = %v", err)
+	}
+	if _, err := svc.GetUnlockedURL(ctx, "intdocs", unlocked.AccessToken); errorCode(err) != serviceErrors.ErrorCodeUnauthorized {
+		t.Errorf("GetUnlockedURL() after password change error = %v, want unauthorized", err)
+	}
+	if _, err := svc.UpdateShortURL(ctx, "intdocs", &dto.UpdateURLRequest{URL: "https://example.com/internal", Password: stringPtr("")}); err != nil {
+		t.Fatalf("UpdateShortURL() error = %v", err)
+	}
+	if _, err := svc.GetOriginalURL(ctx, "intdocs"); err != nil {
+		t.Errorf("GetOriginalURL() after removing password error = %v", err)
+	}
+}
+
+func TestProtectedLinksHideTheirDestination(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/v1", CustomCode: stringPtr("secret")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	if _, err := svc.UpdateShortURL(ctx, "secret", &dto.UpdateURLRequest{URL: "https://example.com/v2", Password: stringPtr("hunter22")}); err != nil {
+		t.Fatalf("UpdateShortURL() error = %v", err)
+	}
+
+	stats, err := svc.GetStatistics(ctx, "secret")
+	if err != nil || stats.URL != "" || !stats.PasswordProtected {
+		t.Errorf("GetStatistics() = %+v, %v, want protected without the destination", stats, err)
+	}
+	list, err := svc.ListURLs(ctx, &dto.ListURLsRequest{})
+	if err != nil || len(list.Items) != 1 || list.Items[0].URL != "" {
+		t.Errorf("ListURLs() = %+v, %v, want the link without its destination", list, err)
+	}
+	history, err := svc.GetHistory(ctx, "secret")
+	if err != nil || len(history.Versions) != 2 {
+		t.Fatalf("GetHistory() = %+v, %v, want 2 versions", history, err)
+	}
+	for _, version := range history.Versions {
+		if version.OldURL != "" || version.NewURL != "" {
+			t.Errorf("GetHistory() version %d = %+v, want no destinations", version.Version, version)
+		}
+	}
+	if rolledBack, err := svc.RollbackURL(ctx, "secret", 1); err != nil || rolledBack.URL != "" {
+		t.Errorf("RollbackURL() = %+v, %v, want no destination", rolledBack, err)
+	}
+	err = svc.ExportURLs(ctx, func(link *dto.GetStatsResponse) error {
+		if link.URL != "" {
+			t.Errorf("ExportURLs() = %+v, want no destination", link)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportURLs() error = %v", err)
+	}
+}
+
+func TestUnlockURLRateLimitsFailedAttempts(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com", CustomCode: stringPtr("vault"), Password: stringPtr("opensesame")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+
+	for i := 0; i < constants.DefaultUnlockMaxAttempts; i++ {
+		if _, err := svc.UnlockURL(ctx, "vault", "attacker", &dto.UnlockURLRequest{Password: "guess"}); errorCode(err) != serviceErrors.ErrorCodeUnauthorized {
+			t.Fatalf("UnlockURL() attempt %d error = %v, want unauthorized", i+1, err)
+		}
+	}
+
+	// Even the right password is refused while locked out, but only for the
+	// client that kept guessing.
+	if _, err := svc.UnlockURL(ctx, "vault", "attacker", &dto.UnlockURLRequest{Password: "opensesame"}); errorCode(err) != serviceErrors.ErrorCodeTooManyRequests {
+		t.Errorf("UnlockURL() while locked out error = %v, want too many requests", err)
+	}
+	if _, err := svc.UnlockURL(ctx, "vault", "colleague", &dto.UnlockURLRequest{Password: "opensesame"}); err != nil {
+		t.Errorf("UnlockURL() from another client error = %v", err)
+	}
+}
+
+func TestUnlockURLCountsConcurrentGuesses(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com", CustomCode: stringPtr("vault"), Password: stringPtr("opensesame")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		compared int
+	)
+	for i := 0; i < 4*constants.DefaultUnlockMaxAttempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.UnlockURL(ctx, "vault", "attacker", &dto.UnlockURLRequest{Password: "guess"})
+			if errorCode(err) == serviceErrors.ErrorCodeUnauthorized {
+				mu.Lock()
+				compared++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if compared != constants.DefaultUnlockMaxAttempts {
+		t.Errorf("parallel guesses checked %d passwords, want %d", compared, constants.DefaultUnlockMaxAttempts)
+	}
+}
+
+func TestPasswordValidation(t *testing.T) {
+	svc := newTestService()
+
+	for _, password := range []string{"abc", strings.Repeat("x", constants.MaxPasswordLength+1)} {
+		if _, err := svc.CreateShortURL(context.Background(), &dto.CreateURLRequest{URL: "https://example.com", Password: &password}); errorCode(err) != serviceErrors.ErrorCodeValidation {
+			t.Errorf("CreateShortURL() with %d byte password error = %v, want validation error", len(password), err)
+		}
+	}
+}
+
+func TestScheduledActivationWindow(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository()
+	aggregator := counter.NewAggregator(repo, time.Hour)
+	svc := NewURLService(repo, repository.NewMemoryHistoryRepository(), directClicks{aggregator}, aggregator, Options{}).(*urlServiceImpl)
+
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	launch, end := now.Add(time.Hour), now.Add(2*time.Hour)
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/launch", CustomCode: stringPtr("launch"), ActiveFrom: &launch, ActiveUntil: &end}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+
+	if _, err := svc.GetOriginalURL(ctx, "launch"); errorCode(err) != serviceErrors.ErrorCodeNotYetActive {
+		t.Errorf("GetOriginalURL() before the window error = %v, want not yet active", err)
+	}
+	svc.hideNotYetActive = true
+	if _, err := svc.GetOriginalURL(ctx, "launch"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("GetOriginalURL() before the window, hidden, error = %v, want not found", err)
+	}
+
+	now = launch
+	if _, err := svc.GetOriginalURL(ctx, "launch"); err != nil {
+		t.Errorf("GetOriginalURL() inside the window error = %v", err)
+	}
+
+	now = end
+	if _, err := svc.GetOriginalURL(ctx, "launch"); errorCode(err) != serviceErrors.ErrorCodeGone {
+		t.Errorf("GetOriginalURL() after the window error = %v, want gone", err)
+	}
+
+	stats, err := svc.GetStatistics(ctx, "launch")
+	if err != nil {
+		t.Fatalf("GetStatistics() error = %v", err)
+	}
+	if stats.ActiveFrom == nil || !stats.ActiveFrom.Equal(launch) || stats.ActiveUntil == nil || !stats.ActiveUntil.Equal(end) {
+		t.Errorf("GetStatistics() window = %v..%v, want %v..%v", stats.ActiveFrom, stats.ActiveUntil, launch, end)
+	}
+
+	// Reopening the end of the window brings the link back.
+	updated, err := svc.UpdateShortURL(ctx, "launch", &dto.UpdateURLRequest{URL: "https://example.com/launch", ClearActiveUntil: true})
+	if err != nil {
+		t.Fatalf("UpdateShortURL() error = %v", err)
+	}
+	if updated.ActiveUntil != nil || updated.ActiveFrom == nil {
+		t.Errorf("UpdateShortURL() window = %v..%v, want %v..nil", updated.ActiveFrom, updated.ActiveUntil, launch)
+	}
+	if _, err := svc.GetOriginalURL(ctx, "launch"); err != nil {
+		t.Errorf("GetOriginalURL() after reopening error = %v", err)
+	}
+}
+
+func TestActivationWindowValidation(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	past, soon, later := time.Now().Add(-time.Hour), time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)
+	tests := []struct {
+		name string
+		req  dto.CreateURLRequest
+	}{
+		{"until in the past", dto.CreateURLRequest{URL: "https://example.com", ActiveUntil: &past}},
+		{"until before from", dto.CreateURLRequest{URL: "https://example.com", ActiveFrom: &later, ActiveUntil: &soon}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.CreateShortURL(ctx, &tt.req); errorCode(err) != serviceErrors.ErrorCodeValidation {
+				t.Errorf("CreateShortURL() error = %v, want validation error", err)
+			}
+		})
+	}
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com", CustomCode: stringPtr("window"), ActiveUntil: &soon}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	// The stored end of the window still applies when only the start changes.
+	if _, err := svc.UpdateShortURL(ctx, "window", &dto.UpdateURLRequest{URL: "https://example.com", ActiveFrom: &later}); errorCode(err) != serviceErrors.ErrorCodeValidation {
+		t.Errorf("UpdateShortURL() start after stored end error = %v, want validation error", err)
+	}
+}
+
+// lookupCountingRepo counts reads by short code.
+type lookupCountingRepo struct {
+	repository.URLRepository
+	lookups int
+}
+
+func (r *lookupCountingRepo) GetByShortCode(ctx context.Context, shortCode string) (*models.ShortURL, error) {
+	r.lookups++
+	return r.URLRepository.GetByShortCode(ctx, shortCode)
+}
+
+func TestUpdateActivationWindowReadsLinkOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := &lookupCountingRepo{URLRepository: repository.NewMemoryURLRepository()}
+	aggregator := counter.NewAggregator(repo, time.Hour)
+	svc := NewURLService(repo, repository.NewMemoryHistoryRepository(), directClicks{aggregator}, aggregator, Options{})
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com", CustomCode: stringPtr("window")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	repo.lookups = 0
+	later := time.Now().Add(time.Hour)
+	if _, err := svc.UpdateShortURL(ctx, "window", &dto.UpdateURLRequest{URL: "https://example.com", ActiveUntil: &later}); err != nil {
+		t.Fatalf("UpdateShortURL() error = %v", err)
+	}
+	if repo.lookups != 1 {
+		t.Errorf("UpdateShortURL() read the link %d times, want 1", repo.lookups)
+	}
+}
+
+func TestDisableAndEnableURL(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/paused", CustomCode: stringPtr("paused")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	if _, err := svc.GetOriginalURL(ctx, "paused"); err != nil {
+		t.Fatalf("GetOriginalURL() error = %v", err)
+	}
+
+	stats, err := svc.DisableURL(ctx, "paused", &dto.DisableURLRequest{Reason: "phishing report"})
+	if err != nil {
+		t.Fatalf("DisableURL() error = %v", err)
+	}
+	if stats.Enabled || stats.DisabledReason != "phishing report" || stats.AccessCount != 1 {
+		t.Errorf("DisableURL() = enabled %v, reason %q, count %d, want disabled with stats kept", stats.Enabled, stats.DisabledReason, stats.AccessCount)
+	}
+	if _, err := svc.GetOriginalURL(ctx, "paused"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("GetOriginalURL() while disabled error = %v, want not found", err)
+	}
+
+	if _, err := svc.DisableURL(ctx, "paused", &dto.DisableURLRequest{Reason: "takedown notice", Legal: true}); err != nil {
+		t.Fatalf("DisableURL() error = %v", err)
+	}
+	if _, err := svc.GetOriginalURL(ctx, "paused"); errorCode(err) != serviceErrors.ErrorCodeUnavailableForLegalReasons {
+		t.Errorf("GetOriginalURL() while legally disabled error = %v, want unavailable for legal reasons", err)
+	}
+
+	stats, err = svc.EnableURL(ctx, "paused")
+	if err != nil {
+		t.Fatalf("EnableURL() error = %v", err)
+	}
+	if !stats.Enabled || stats.DisabledReason != "" || stats.DisabledLegal {
+		t.Errorf("EnableURL() = enabled %v, reason %q, legal %v, want enabled and cleared", stats.Enabled, stats.DisabledReason, stats.DisabledLegal)
+	}
+	if _, err := svc.GetOriginalURL(ctx, "paused"); err != nil {
+		t.Errorf("GetOriginalURL() after enabling error = %v", err)
+	}
+
+	if _, err := svc.DisableURL(ctx, "paused", &dto.DisableURLRequest{Reason: strings.Repeat("x", 501)}); errorCode(err) != serviceErrors.ErrorCodeValidation {
+		t.Errorf("DisableURL() with a long reason error = %v, want validation error", err)
+	}
+	if _, err := svc.EnableURL(ctx, "missing"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("EnableURL() on a missing code error = %v, want not found", err)
+	}
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/oops", CustomCode: stringPtr("oops")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	if _, err := svc.GetOriginalURL(ctx, "oops"); err != nil {
+		t.Fatalf("GetOriginalURL() error = %v", err)
+	}
+	if err := svc.DeleteShortURL(ctx, "oops"); err != nil {
+		t.Fatalf("DeleteShortURL() error = %v", err)
+	}
+
+	if _, err := svc.GetOriginalURL(ctx, "oops"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("GetOriginalURL() on a trashed link error = %v, want not found", err)
+	}
+	if _, err := svc.GetStatistics(ctx, "oops"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("GetStatistics() on a trashed link error = %v, want not found", err)
+	}
+	if _, err := svc.UpdateShortURL(ctx, "oops", &dto.UpdateURLRequest{URL: "https://example.com/other"}); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("UpdateShortURL() on a trashed link error = %v, want not found", err)
+	}
+	if err := svc.DeleteShortURL(ctx, "oops"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("DeleteShortURL() twice error = %v, want not found", err)
+	}
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/other", CustomCode: stringPtr("oops")}); errorCode(err) != serviceErrors.ErrorCodeDuplicate {
+		t.Errorf("CreateShortURL() reusing a trashed code error = %v, want duplicate", err)
+	}
+
+	listed, err := svc.ListURLs(ctx, &dto.ListURLsRequest{})
+	if err != nil {
+		t.Fatalf("ListURLs() error = %v", err)
+	}
+	if listed.TotalItems != 0 {
+		t.Errorf("ListURLs() total = %d, want trashed links excluded", listed.TotalItems)
+	}
+	trash, err := svc.ListTrash(ctx, &dto.ListURLsRequest{})
+	if err != nil {
+		t.Fatalf("ListTrash() error = %v", err)
+	}
+	if len(trash.Items) != 1 || trash.Items[0].ShortCode != "oops" || trash.Items[0].DeletedAt == nil {
+		t.Fatalf("ListTrash() = %+v, want the deleted link with deletedAt", trash.Items)
+	}
+
+	restored, err := svc.RestoreURL(ctx, "oops")
+	if err != nil {
+		t.Fatalf("RestoreURL() error = %v", err)
+	}
+	if restored.DeletedAt != nil || restored.AccessCount != 1 {
+		t.Errorf("RestoreURL() = deletedAt %v, count %d, want restored with stats kept", restored.DeletedAt, restored.AccessCount)
+	}
+	if _, err := svc.GetOriginalURL(ctx, "oops"); err != nil {
+		t.Errorf("GetOriginalURL() after restoring error = %v", err)
+	}
+	if _, err := svc.RestoreURL(ctx, "oops"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("RestoreURL() on a live link error = %v, want not found", err)
+	}
+}
+
+func TestDestinationHistoryAndRollback(t *testing.T) {
+	ctx := WithActor(context.Background(), "alice")
+	svc := newTestService()
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/v1", CustomCode: stringPtr("docs")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	for _, url := range []string{"https://example.com/v2", "https://example.com/v2", "https://example.com/v3"} {
+		if _, err := svc.UpdateShortURL(WithActor(context.Background(), "bob"), "docs", &dto.UpdateURLRequest{URL: url}); err != nil {
+			t.Fatalf("UpdateShortURL(%s) error = %v", url, err)
+		}
+	}
+
+	history, err := svc.GetHistory(ctx, "docs")
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	want := []dto.DestinationVersion{
+		{Version: 1, NewURL: "https://example.com/v1", Actor: "alice"},
+		{Version: 2, OldURL: "https://example.com/v1", NewURL: "https://example.com/v2", Actor: "bob"},
+		{Version: 3, OldURL: "https://example.com/v2", NewURL: "https://example.com/v3", Actor: "bob"},
+	}
+	if len(history.Versions) != len(want) {
+		t.Fatalf("GetHistory() = %+v, want %d versions; unchanged destinations are not recorded", history.Versions, len(want))
+	}
+	for i, got := range history.Versions {
+		if got.Version != want[i].Version || got.OldURL != want[i].OldURL || got.NewURL != want[i].NewURL || got.Actor != want[i].Actor || got.ChangedAt.IsZero() {
+			t.Errorf("GetHistory() version %d = %+v, want %+v", i+1, got, want[i])
+		}
+	}
+
+	rolledBack, err := svc.RollbackURL(WithActor(context.Background(), "carol"), "docs", 1)
+	if err != nil {
+		t.Fatalf("RollbackURL() error = %v", err)
+	}
+	if rolledBack.URL != "https://example.com/v1" {
+		t.Errorf("RollbackURL() URL = %q, want the version 1 destination", rolledBack.URL)
+	}
+	resolved, err := svc.GetOriginalURL(ctx, "docs")
+	if err != nil || resolved.URL != "https://example.com/v1" {
+		t.Errorf("GetOriginalURL() after rollback = %+v, %v, want the version 1 destination", resolved, err)
+	}
+	history, err = svc.GetHistory(ctx, "docs")
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if latest := history.Versions[len(history.Versions)-1]; latest.Version != 4 || latest.OldURL != "https://example.com/v3" || latest.NewURL != "https://example.com/v1" || latest.Actor != "carol" {
+		t.Errorf("GetHistory() after rollback latest = %+v, want version 4 back to v1 by carol", latest)
+	}
+
+	if _, err := svc.RollbackURL(ctx, "docs", 9); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("RollbackURL() to a missing version error = %v, want not found", err)
+	}
+	if _, err := svc.RollbackURL(ctx, "missing", 1); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("RollbackURL() of a missing link error = %v, want not found", err)
+	}
+}
+
+func TestRollbackRevalidatesDestination(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository()
+	history := repository.NewMemoryHistoryRepository()
+	aggregator := counter.NewAggregator(repo, time.Hour)
+	svc := NewURLService(repo, history, directClicks{aggregator}, aggregator, Options{})
+
+	// A link stored before history was recorded, pointing somewhere the
+	// validator now rejects.
+	if err := repo.Create(ctx, &models.ShortURL{URL: "https://malware.com/x", ShortCode: "legacy"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := svc.UpdateShortURL(ctx, "legacy", &dto.UpdateURLRequest{URL: "https://example.com/safe"}); err != nil {
+		t.Fatalf("UpdateShortURL() error = %v", err)
+	}
+
+	versions, err := svc.GetHistory(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(versions.Versions) != 2 || versions.Versions[0].NewURL != "https://malware.com/x" {
+		t.Fatalf("GetHistory() = %+v, want the pre-history destination kept as version 1", versions.Versions)
+	}
+
+	if _, err := svc.RollbackURL(ctx, "legacy", 1); errorCode(err) != serviceErrors.ErrorCodeValidation {
+		t.Errorf("RollbackURL() to a blocked destination error = %v, want validation", err)
+	}
+	resolved, err := svc.GetOriginalURL(ctx, "legacy")
+	if err != nil || resolved.URL != "https://example.com/safe" {
+		t.Errorf("GetOriginalURL() after a rejected rollback = %+v, %v, want the current destination kept", resolved, err)
+	}
+}
+
+// sliceRows is an ImportSource over fixed rows; a nil row stands for one
+// that failed to parse.
+type sliceRows struct {
+	rows []*dto.ImportRow
+}
+
+func (s *sliceRows) Next() (*dto.ImportRow, error) {
+	if len(s.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	if row.URL == "" && row.CustomCode == "" {
+		return row, serviceErrors.NewValidationError("test.Next", "malformed row", nil)
+	}
+	return row, nil
+}
+
+func importRows() []*dto.ImportRow {
+	return []*dto.ImportRow{
+		{Line: 2, URL: "https://example.com/new", CustomCode: "fresh", Metadata: map[string]string{"legacyId": "1"}},
+		{Line: 3, URL: "https://example.com/generated"},
+		{Line: 4, URL: "https://example.com/replacement", CustomCode: "taken"},
+		{Line: 5, URL: "ftp://example.com"},
+		{Line: 6},
+		{Line: 7, URL: "https://example.com/again", CustomCode: "fresh"},
+	}
+}
+
+func TestImportURLs(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       ImportOptions
+		want       []string
+		wantTaken  string
+		wantFresh  bool
+		wantStored int
+	}{
+		{
+			name:       "skip",
+			opts:       ImportOptions{OnConflict: ConflictSkip},
+			want:       []string{ImportCreated, ImportCreated, ImportSkipped, ImportInvalid, ImportInvalid, ImportSkipped},
+			wantTaken:  "https://example.com/original",
+			wantFresh:  true,
+			wantStored: 3,
+		},
+		{
+			name:       "overwrite",
+			opts:       ImportOptions{OnConflict: ConflictOverwrite},
+			want:       []string{ImportCreated, ImportCreated, ImportUpdated, ImportInvalid, ImportInvalid, ImportUpdated},
+			wantTaken:  "https://example.com/replacement",
+			wantFresh:  true,
+			wantStored: 3,
+		},
+		{
+			name:       "fail",
+			opts:       ImportOptions{OnConflict: ConflictFail},
+			want:       []string{ImportCreated, ImportCreated, ImportConflict},
+			wantTaken:  "https://example.com/original",
+			wantFresh:  true,
+			wantStored: 3,
+		},
+		{
+			name:       "dry run",
+			opts:       ImportOptions{DryRun: true, OnConflict: ConflictOverwrite},
+			want:       []string{ImportCreated, ImportCreated, ImportUpdated, ImportInvalid, ImportInvalid, ImportUpdated},
+			wantTaken:  "https://example.com/original",
+			wantStored: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := newTestService()
+			if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/original", CustomCode: stringPtr("taken"), Metadata: map[string]string{"old": "yes"}}); err != nil {
+				t.Fatalf("CreateShortURL() error = %v", err)
+			}
+
+			var got []string
+			summary, err := svc.ImportURLs(ctx, &sliceRows{rows: importRows()}, tt.opts, func(result *dto.ImportResult) error {
+				got = append(got, result.Status)
+				if result.Status == ImportInvalid && result.Error == "" {
+					t.Errorf("line %d is invalid without an error", result.Line)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ImportURLs() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ImportURLs() statuses = %v, want %v", got, tt.want)
+			}
+			if summary.Rows != len(tt.want) || summary.DryRun != tt.opts.DryRun || summary.Aborted != (tt.opts.OnConflict == ConflictFail) {
+				t.Errorf("ImportURLs() summary = %+v", summary)
+			}
+
+			taken, err := svc.GetStatistics(ctx, "taken")
+			if err != nil {
+				t.Fatalf("GetStatistics(taken) error = %v", err)
+			}
+			if taken.URL != tt.wantTaken {
+				t.Errorf("taken URL = %q, want %q", taken.URL, tt.wantTaken)
+			}
+			if tt.opts.OnConflict == ConflictOverwrite && !tt.opts.DryRun && taken.Metadata != nil {
+				t.Errorf("taken metadata = %v, want it replaced by the row's none", taken.Metadata)
+			}
+
+			fresh, err := svc.GetStatistics(ctx, "fresh")
+			if tt.wantFresh != (err == nil) {
+				t.Fatalf("GetStatistics(fresh) error = %v, want exists = %v", err, tt.wantFresh)
+			}
+			// Overwriting fresh with line 7, which has no metadata, clears it.
+			wantLegacyID := "1"
+			if tt.opts.OnConflict == ConflictOverwrite {
+				wantLegacyID = ""
+			}
+			if err == nil && fresh.Metadata["legacyId"] != wantLegacyID {
+				t.Errorf("fresh metadata = %v, want legacyId %q", fresh.Metadata, wantLegacyID)
+			}
+
+			listed, err := svc.ListURLs(ctx, &dto.ListURLsRequest{})
+			if err != nil {
+				t.Fatalf("ListURLs() error = %v", err)
+			}
+			if listed.TotalItems != tt.wantStored {
+				t.Errorf("stored %d links, want %d", listed.TotalItems, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestExportURLs(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+	for _, code := range []string{"beta", "alpha", "gone"} {
+		if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/" + code, CustomCode: stringPtr(code)}); err != nil {
+			t.Fatalf("CreateShortURL() error = %v", err)
+		}
+	}
+	if _, err := svc.GetOriginalURL(ctx, "beta"); err != nil {
+		t.Fatalf("GetOriginalURL() error = %v", err)
+	}
+	if err := svc.DeleteShortURL(ctx, "gone"); err != nil {
+		t.Fatalf("DeleteShortURL() error = %v", err)
+	}
+
+	var codes []string
+	var betaCount int64
+	err := svc.ExportURLs(ctx, func(link *dto.GetStatsResponse) error {
+		codes = append(codes, link.ShortCode)
+		if link.ShortCode == "beta" {
+			betaCount = link.AccessCount
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportURLs() error = %v", err)
+	}
+	if !slices.Equal(codes, []string{"alpha", "beta"}) {
+		t.Errorf("ExportURLs() codes = %v, want [alpha beta] without trashed links", codes)
+	}
+	if betaCount != 1 {
+		t.Errorf("ExportURLs() beta accessCount = %d, want 1", betaCount)
+	}
+}
+
+// inFlightRepo records the most Create calls it saw running at once.
+type inFlightRepo struct {
+	repository.URLRepository
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (r *inFlightRepo) Create(ctx context.Context, shortURL *models.ShortURL) error {
+	r.mu.Lock()
+	r.current++
+	r.max = max(r.max, r.current)
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.current--
+		r.mu.Unlock()
+	}()
+	time.Sleep(5 * time.Millisecond)
+	return r.URLRepository.Create(ctx, shortURL)
+}
+
+func TestBatchCreateURLs(t *testing.T) {
+	ctx := context.Background()
+	repo := &inFlightRepo{URLRepository: repository.NewMemoryURLRepository()}
+	aggregator := counter.NewAggregator(repo, time.Hour)
+	svc := NewURLService(repo, repository.NewMemoryHistoryRepository(), directClicks{aggregator}, aggregator, Options{BatchMaxSize: 20, BatchConcurrency: 3})
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/taken", CustomCode: stringPtr("taken")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+
+	req := &dto.BatchCreateRequest{Items: []dto.CreateURLRequest{
+		{URL: "https://example.com/a", CustomCode: stringPtr("first")},
+		{URL: "https://example.com/b", CustomCode: stringPtr("taken")},
+		{URL: "ftp://example.com"},
+		{URL: "https://example.com/c", CustomCode: stringPtr("first")},
+		{URL: "https://example.com/d", CustomCode: stringPtr("bad!")},
+	}}
+	for range 10 {
+		req.Items = append(req.Items, dto.CreateURLRequest{URL: "https://example.com/generated"})
+	}
+
+	resp, err := svc.BatchCreateURLs(ctx, req)
+	if err != nil {
+		t.Fatalf("BatchCreateURLs() error = %v", err)
+	}
+	want := []string{BatchCreated, BatchDuplicate, BatchInvalid, BatchDuplicate, BatchInvalid}
+	for i, result := range resp.Items {
+		if result.Index != i {
+			t.Errorf("item %d has index %d", i, result.Index)
+		}
+		if i < len(want) && result.Status != want[i] {
+			t.Errorf("item %d status = %q (%s), want %q", i, result.Status, result.Error, want[i])
+		}
+		if i >= len(want) && (result.Status != BatchCreated || result.Link == nil || result.Link.ShortCode == "") {
+			t.Errorf("item %d = %+v, want a generated link", i, result)
+		}
+	}
+	if resp.Created != 11 || resp.Duplicates != 2 || resp.Invalid != 2 || resp.Failed != 0 {
+		t.Errorf("BatchCreateURLs() counts = %d created, %d duplicates, %d invalid, %d failed", resp.Created, resp.Duplicates, resp.Invalid, resp.Failed)
+	}
+	if repo.max > 3 {
+		t.Errorf("BatchCreateURLs() ran %d creates at once, want at most 3", repo.max)
+	}
+
+	if _, err := svc.BatchCreateURLs(ctx, &dto.BatchCreateRequest{}); errorCode(err) != serviceErrors.ErrorCodeValidation {
+		t.Errorf("empty batch error = %v, want a validation error", err)
+	}
+	tooMany := &dto.BatchCreateRequest{Items: make([]dto.CreateURLRequest, 21)}
+	if _, err := svc.BatchCreateURLs(ctx, tooMany); errorCode(err) != serviceErrors.ErrorCodeValidation {
+		t.Errorf("oversized batch error = %v, want a validation error", err)
+	}
+}
+
+func TestBatchDeleteURLs(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService()
+	for _, code := range []string{"alpha", "beta"} {
+		if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/" + code, CustomCode: stringPtr(code)}); err != nil {
+			t.Fatalf("CreateShortURL() error = %v", err)
+		}
+	}
+
+	resp, err := svc.BatchDeleteURLs(ctx, &dto.BatchDeleteRequest{ShortCodes: []string{"alpha", "missing", "x", "beta", "alpha"}})
+	if err != nil {
+		t.Fatalf("BatchDeleteURLs() error = %v", err)
+	}
+	var got []string
+	for _, result := range resp.Items {
+		got = append(got, result.Status)
+	}
+	if want := []string{BatchDeleted, BatchNotFound, BatchInvalid, BatchDeleted, BatchDuplicate}; !slices.Equal(got, want) {
+		t.Errorf("BatchDeleteURLs() statuses = %v, want %v", got, want)
+	}
+	if resp.Deleted != 2 || resp.NotFound != 1 || resp.Duplicates != 1 || resp.Invalid != 1 {
+		t.Errorf("BatchDeleteURLs() counts = %+v", resp)
+	}
+	if _, err := svc.GetOriginalURL(ctx, "beta"); errorCode(err) != serviceErrors.ErrorCodeNotFound {
+		t.Errorf("GetOriginalURL(beta) after batch delete error = %v, want not found", err)
+	}
+}
+
+func TestCreateShortURLUsesCodeGenerator(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository()
+	aggregator := counter.NewAggregator(repo, time.Hour)
+	codes := codegen.NewCounter(codegen.NewMemorySequence(1), 5)
+	svc := NewURLService(repo, repository.NewMemoryHistoryRepository(), directClicks{aggregator}, aggregator, Options{CodeGenerator: codes})
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/custom", CustomCode: stringPtr("00002")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	var got []string
+	for range 2 {
+		resp, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com"})
+		if err != nil {
+			t.Fatalf("CreateShortURL() error = %v", err)
+		}
+		got = append(got, resp.ShortCode)
+	}
+	if want := []string{"00001", "00003"}; !slices.Equal(got, want) {
+		t.Errorf("generated codes = %v, want %v skipping the taken 00002", got, want)
+	}
+}
+
+// queuedKeys hands out a fixed list of reserved codes.
+type queuedKeys struct {
+	mu    sync.Mutex
+	codes []string
+}
+
+func (k *queuedKeys) Take(ctx context.Context) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.codes) == 0 {
+		return "", errors.New("no keys left")
+	}
+	code := k.codes[0]
+	k.codes = k.codes[1:]
+	return code, nil
+}
+
+// existsCountingRepo counts existence checks.
+type existsCountingRepo struct {
+	repository.URLRepository
+	mu     sync.Mutex
+	checks int
+}
+
+func (r *existsCountingRepo) ExistsByShortCode(ctx context.Context, shortCode string) (bool, error) {
+	r.mu.Lock()
+	r.checks++
+	r.mu.Unlock()
+	return r.URLRepository.ExistsByShortCode(ctx, shortCode)
+}
+
+func TestCreateShortURLUsesKeySource(t *testing.T) {
+	ctx := context.Background()
+	repo := &existsCountingRepo{URLRepository: repository.NewMemoryURLRepository()}
+	aggregator := counter.NewAggregator(repo, time.Hour)
+	keys := &queuedKeys{codes: []string{"pool1", "pool2", "health", "pool3"}}
+	svc := NewURLService(repo, repository.NewMemoryHistoryRepository(), directClicks{aggregator}, aggregator, Options{Keys: keys})
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/custom", CustomCode: stringPtr("pool2")}); err != nil {
+		t.Fatalf("CreateShortURL() error = %v", err)
+	}
+	_, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com/again", CustomCode: stringPtr("pool2")})
+	if errorCode(err) != serviceErrors.ErrorCodeDuplicate {
+		t.Fatalf("CreateShortURL(taken custom code) error = %v, want duplicate", err)
+	}
+
+	var got []string
+	for range 2 {
+		resp, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com"})
+		if err != nil {
+			t.Fatalf("CreateShortURL() error = %v", err)
+		}
+		got = append(got, resp.ShortCode)
+	}
+	if want := []string{"pool1", "pool3"}; !slices.Equal(got, want) {
+		t.Errorf("pooled codes = %v, want %v skipping the taken pool2 and the reserved health", got, want)
+	}
+	if repo.checks != 0 {
+		t.Errorf("ExistsByShortCode called %d times, want none", repo.checks)
+	}
+
+	if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com"}); errorCode(err) != serviceErrors.ErrorCodeInternal {
+		t.Errorf("CreateShortURL(empty pool) error = %v, want internal", err)
+	}
+}
+
+// stalledKeyStore reserves every candidate, but refills after the first
+// block until release is closed, like a slow storage backend.
+type stalledKeyStore struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *stalledKeyStore) MaxReserve() int {
+	return 0
+}
+
+func (s *stalledKeyStore) Reserve(ctx context.Context, candidates []string) ([]string, error) {
+	if s.calls.Add(1) > 1 {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return candidates, nil
+}
+
+func TestCreateShortURLDoesNotWaitForSlowRefill(t *testing.T) {
+	store := &stalledKeyStore{release: make(chan struct{})}
+	pool := keypool.New(store, codegen.NewCounter(codegen.NewMemorySequence(1), 6), keypool.Options{Size: 10, LowWater: 8})
+	pool.Start()
+	defer pool.Stop(context.Background())
+	defer close(store.release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.Available() < 10 {
+		if time.Now().After(deadline) {
+			t.Fatal("key pool was not filled in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	repo := repository.NewMemoryURLRepository()
+	aggregator := counter.NewAggregator(repo, time.Hour)
+	svc := NewURLService(repo, repository.NewMemoryHistoryRepository(), directClicks{aggregator}, aggregator, Options{Keys: pool})
+
+	// The refill started by the third create is still stuck in storage.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	for i := 0; i < 8; i++ {
+		if _, err := svc.CreateShortURL(ctx, &dto.CreateURLRequest{URL: "https://example.com"}); err != nil {
+			t.Fatalf("CreateShortURL() %d error = %v", i+1, err)
+		}
+	}
+	for store.calls.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("no refill was started below the low-water mark")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
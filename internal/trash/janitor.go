@@ -0,0 +1,134 @@
+// Package trash purges deleted short URLs once their retention has passed.
+package trash
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rowjay/url-shortening-service/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// Store lists and deletes short URLs. repository.URLRepository satisfies it.
+type Store interface {
+	List(ctx context.Context, opts *models.ListOptions) (*models.ListResult, error)
+	Delete(ctx context.Context, shortCode string) error
+}
+
+type Options struct {
+	// Interval is the time between purges.
+	Interval time.Duration
+	// Retention is how long a deleted link stays restorable, and its short
+	// code reserved, before it is purged.
+	Retention time.Duration
+	// BatchSize caps how many links are listed and deleted at a time.
+	BatchSize int
+}
+
+// Janitor periodically hard-deletes short URLs that were moved to the trash
+// more than Options.Retention ago.
+type Janitor struct {
+	store Store
+	opts  Options
+	now   func() time.Time
+
+	purgeMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewJanitor(store Store, opts Options) *Janitor {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	return &Janitor{
+		store: store,
+		opts:  opts,
+		now:   time.Now,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Start runs the purge loop until Stop is called.
+func (j *Janitor) Start() {
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(j.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := j.Purge(context.Background()); err != nil {
+					log.Warn().Err(err).Msg("Failed to purge trashed short URLs, will retry")
+				}
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the purge loop, waiting for a purge in progress to finish.
+func (j *Janitor) Stop(ctx context.Context) error {
+	close(j.stop)
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Purge hard-deletes every trashed short URL past its retention and returns
+// how many were deleted.
+func (j *Janitor) Purge(ctx context.Context) (int, error) {
+	j.purgeMu.Lock()
+	defer j.purgeMu.Unlock()
+
+	opts := &models.ListOptions{
+		Filter:  models.ListFilter{Trashed: true, DeletedBefore: j.now().Add(-j.opts.Retention)},
+		SortBy:  models.SortByShortCode,
+		PerPage: j.opts.BatchSize,
+	}
+
+	purged := 0
+	var errs []error
+	for {
+		result, err := j.store.List(ctx, opts)
+		if err != nil {
+			return purged, errors.Join(append(errs, err)...)
+		}
+		if len(result.Items) == 0 {
+			break
+		}
+
+		for _, shortURL := range result.Items {
+			err := j.store.Delete(ctx, shortURL.ShortCode)
+			var serviceErr *serviceErrors.ServiceError
+			switch {
+			case err == nil:
+				purged++
+			case errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeNotFound:
+			default:
+				errs = append(errs, err)
+			}
+		}
+
+		if len(result.Items) < opts.PerPage {
+			break
+		}
+		// Page by cursor so links that failed to delete are not listed again.
+		opts.After = models.CursorFor(result.Items[len(result.Items)-1])
+	}
+
+	if purged > 0 {
+		log.Info().Int("purged", purged).Msg("Purged trashed short URLs")
+	}
+	return purged, errors.Join(errs...)
+}
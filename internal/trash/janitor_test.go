@@ -0,0 +1,55 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	serviceErrors "github.com/rowjay/url-shortening-service/internal/errors"
+	"github.com/rowjay/url-shortening-service/internal/models"
+	"github.com/rowjay/url-shortening-service/internal/repository"
+)
+
+func TestPurgeDeletesLinksPastRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	repo := repository.NewMemoryURLRepository()
+	for code, offset := range map[string]time.Duration{
+		"live":   0,
+		"recent": -time.Minute,
+		"old1":   -2 * time.Hour,
+		"old2":   -3 * time.Hour,
+		"old3":   -4 * time.Hour,
+	} {
+		if err := repo.Create(ctx, &models.ShortURL{URL: "https://example.com/" + code, ShortCode: code}); err != nil {
+			t.Fatalf("Create(%s) error = %v", code, err)
+		}
+		if offset != 0 {
+			deletedAt := now.Add(offset)
+			if _, err := repo.Update(ctx, code, &models.ShortURLUpdate{DeletedAt: &deletedAt}); err != nil {
+				t.Fatalf("Update(%s) error = %v", code, err)
+			}
+		}
+	}
+
+	janitor := NewJanitor(repo, Options{Retention: time.Hour, BatchSize: 2})
+	janitor.now = func() time.Time { return now }
+
+	purged, err := janitor.Purge(ctx)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if purged != 3 {
+		t.Errorf("Purge() purged %d, want 3", purged)
+	}
+
+	for code, wantPurged := range map[string]bool{"live": false, "recent": false, "old1": true, "old2": true, "old3": true} {
+		_, err := repo.GetByShortCode(ctx, code)
+		var serviceErr *serviceErrors.ServiceError
+		purged := errors.As(err, &serviceErr) && serviceErr.Code == serviceErrors.ErrorCodeNotFound
+		if purged != wantPurged {
+			t.Errorf("GetByShortCode(%s) after purge error = %v, want purged = %v", code, err, wantPurged)
+		}
+	}
+}
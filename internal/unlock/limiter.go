@@ -0,0 +1,87 @@
+package unlock
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts unlock attempts per key in fixed windows. Attempts are
+// taken before the password is checked and only kept when it was wrong, so a
+// key that reaches the limit is locked out until its window ends.
+type Limiter struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	failures  map[string]*failureWindow
+	nextSweep time.Time
+}
+
+type failureWindow struct {
+	count int
+	ends  time.Time
+}
+
+func NewLimiter(max int, window time.Duration) *Limiter {
+	return &Limiter{
+		max:      max,
+		window:   window,
+		now:      time.Now,
+		failures: make(map[string]*failureWindow),
+	}
+}
+
+// Take reserves an attempt by key. It fails, and returns how long until the
+// lockout ends, when key already used every attempt of its window. Checking
+// and counting under one lock keeps concurrent guesses from all passing the
+// check before any of them is counted.
+func (l *Limiter) Take(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	w, ok := l.failures[key]
+	if !ok || !now.Before(w.ends) {
+		w = &failureWindow{ends: now.Add(l.window)}
+		l.failures[key] = w
+	}
+	if w.count >= l.max {
+		return false, w.ends.Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// Refund returns an attempt taken by key that did not turn out to be a
+// failed guess.
+func (l *Limiter) Refund(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if w, ok := l.failures[key]; ok && w.count > 0 {
+		w.count--
+	}
+}
+
+// Reset forgets the attempts of key, after it unlocked successfully.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	delete(l.failures, key)
+	l.mu.Unlock()
+}
+
+// sweep drops finished windows at most once per window, so keys that stop
+// trying do not accumulate.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+	for key, w := range l.failures {
+		if !now.Before(w.ends) {
+			delete(l.failures, key)
+		}
+	}
+	l.nextSweep = now.Add(l.window)
+}
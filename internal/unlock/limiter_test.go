@@ -0,0 +1,79 @@
+package unlock
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterLocksOutAfterMaxFailures(t *testing.T) {
+	now := time.Now()
+	limiter := NewLimiter(3, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Take("docs|1.2.3.4"); !ok {
+			t.Fatalf("Take() for attempt %d = false, want true", i+1)
+		}
+	}
+
+	ok, retryAfter := limiter.Take("docs|1.2.3.4")
+	if ok || retryAfter != time.Minute {
+		t.Errorf("Take() after max failures = %v, %v, want false, %v", ok, retryAfter, time.Minute)
+	}
+	if ok, _ := limiter.Take("docs|5.6.7.8"); !ok {
+		t.Error("Take() for another client = false, want true")
+	}
+
+	now = now.Add(time.Minute)
+	if ok, _ := limiter.Take("docs|1.2.3.4"); !ok {
+		t.Error("Take() after the window = false, want true")
+	}
+}
+
+func TestLimiterTakeIsAtomic(t *testing.T) {
+	limiter := NewLimiter(5, time.Minute)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := limiter.Take("docs"); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 5 {
+		t.Errorf("concurrent Take() allowed %d attempts, want 5", allowed)
+	}
+}
+
+func TestLimiterRefund(t *testing.T) {
+	limiter := NewLimiter(1, time.Minute)
+	limiter.Take("docs")
+	limiter.Refund("docs")
+	if ok, _ := limiter.Take("docs"); !ok {
+		t.Error("Take() after Refund() = false, want true")
+	}
+}
+
+func TestLimiterReset(t *testing.T) {
+	limiter := NewLimiter(1, time.Minute)
+	limiter.Take("docs")
+	if ok, _ := limiter.Take("docs"); ok {
+		t.Fatal("Take() after max failures = true, want false")
+	}
+	limiter.Reset("docs")
+	if ok, _ := limiter.Take("docs"); !ok {
+		t.Error("Take() after Reset() = false, want true")
+	}
+}
@@ -0,0 +1,73 @@
+package unlock
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer issues and checks short-lived access tokens for password-protected
+// links. A token is bound to the short code and to the link's password hash,
+// so changing or removing the password revokes every token issued for it.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewSigner returns a Signer whose tokens live for ttl. An empty secret is
+// replaced by a random one, which means tokens do not survive a restart and
+// are not accepted by other replicas.
+func NewSigner(secret []byte, ttl time.Duration) *Signer {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic("unlock: failed to generate signing secret: " + err.Error())
+		}
+	}
+	return &Signer{secret: secret, ttl: ttl, now: time.Now}
+}
+
+// TTL is how long issued tokens stay valid.
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue returns a token for shortCode and the time it expires.
+func (s *Signer) Issue(shortCode, passwordHash string) (string, time.Time) {
+	expiresAt := s.now().Add(s.ttl).Truncate(time.Second)
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + base64.RawURLEncoding.EncodeToString(s.mac(shortCode, passwordHash, expiry)), expiresAt
+}
+
+// Verify reports whether token was issued for shortCode and passwordHash and
+// has not expired.
+func (s *Signer) Verify(token, shortCode, passwordHash string) bool {
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !s.now().Before(time.Unix(unix, 0)) {
+		return false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(mac, s.mac(shortCode, passwordHash, expiry))
+}
+
+func (s *Signer) mac(shortCode, passwordHash, expiry string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(shortCode))
+	h.Write([]byte{0})
+	h.Write([]byte(passwordHash))
+	h.Write([]byte{0})
+	h.Write([]byte(expiry))
+	return h.Sum(nil)
+}
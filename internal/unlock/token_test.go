@@ -0,0 +1,45 @@
+package unlock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignerVerify(t *testing.T) {
+	now := time.Now()
+	signer := NewSigner([]byte("secret"), time.Minute)
+	signer.now = func() time.Time { return now }
+
+	token, expiresAt := signer.Issue("docs", "hash")
+	if !expiresAt.After(now) {
+		t.Fatalf("Issue() expiresAt = %v, want after %v", expiresAt, now)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		shortCode string
+		hash      string
+		want      bool
+	}{
+		{"Valid", token, "docs", "hash", true},
+		{"Other code", token, "other", "hash", false},
+		{"Password changed", token, "docs", "new-hash", false},
+		{"Tampered expiry", "9" + token, "docs", "hash", false},
+		{"Malformed", "garbage", "docs", "hash", false},
+	}
+	for _, tt := range tests {
+		if got := signer.Verify(tt.token, tt.shortCode, tt.hash); got != tt.want {
+			t.Errorf("%s: Verify() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if NewSigner([]byte("other secret"), time.Minute).Verify(token, "docs", "hash") {
+		t.Error("Verify() accepted a token signed with another secret")
+	}
+
+	signer.now = func() time.Time { return now.Add(2 * time.Minute) }
+	if signer.Verify(token, "docs", "hash") {
+		t.Error("Verify() accepted an expired token")
+	}
+}
@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"fmt"
 	"github.com/rowjay/url-shortening-service/internal/constants"
 	"github.com/rowjay/url-shortening-service/internal/errors"
 	"net/url"
@@ -42,7 +43,7 @@ func (v *URLValidator) ValidateURL(rawURL string) error {
 }
 
 func (v *URLValidator) ValidateShortCode(code string) error {
-	if len(code) < 4 || len(code) > 20 {
+	if len(code) < constants.MinShortCodeLength || len(code) > constants.MaxShortCodeLength {
 		return errors.NewValidationError("validator.ValidateShortCode", "short code must be between 4 and 20 characters", nil)
 	}
 
@@ -51,7 +52,32 @@ func (v *URLValidator) ValidateShortCode(code string) error {
 			return errors.NewValidationError("validator.ValidateShortCode", "short code can only contain alphanumeric characters", nil)
 		}
 	}
+	if slices.Contains(constants.ReservedShortCodes, code) {
+		return errors.NewValidationError("validator.ValidateShortCode", fmt.Sprintf("short code %q is reserved", code), nil)
+	}
+
+	return nil
+}
+
+func (v *URLValidator) ValidateRedirectType(statusCode int) error {
+	if !slices.Contains(constants.RedirectStatusCodes, statusCode) {
+		return errors.NewValidationError("validator.ValidateRedirectType", "redirect type must be one of 301, 302, 307 or 308", nil)
+	}
+	return nil
+}
 
+func (v *URLValidator) ValidateMetadata(metadata map[string]string) error {
+	if len(metadata) > constants.MaxMetadataEntries {
+		return errors.NewValidationError("validator.ValidateMetadata", fmt.Sprintf("metadata can have at most %d entries", constants.MaxMetadataEntries), nil)
+	}
+	for key, value := range metadata {
+		if key == "" || len(key) > constants.MaxMetadataKeyLength {
+			return errors.NewValidationError("validator.ValidateMetadata", fmt.Sprintf("metadata keys must be between 1 and %d bytes", constants.MaxMetadataKeyLength), nil)
+		}
+		if len(value) > constants.MaxMetadataValueLength {
+			return errors.NewValidationError("validator.ValidateMetadata", fmt.Sprintf("metadata values must be at most %d bytes", constants.MaxMetadataValueLength), nil)
+		}
+	}
 	return nil
 }
 